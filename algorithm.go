@@ -0,0 +1,140 @@
+package terrapin
+
+import (
+	"crypto/sha1" // #nosec G505 -- SHA-1 gitoids are supported for interoperability with git
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+	"hash"
+	"strings"
+	"sync"
+)
+
+// Algorithm identifies the hash algorithm used for chunk and root gitoids
+// Its value is recorded in the header of attestations that use a non-default algorithm
+type Algorithm byte
+
+// Built-in algorithms
+const (
+	SHA1   Algorithm = 1 // SHA-1, as used by classic git object IDs
+	SHA256 Algorithm = 2 // SHA-256, the default
+	SHA512 Algorithm = 3 // SHA-512
+)
+
+// algorithmInfo describes how to compute digests for an Algorithm
+type algorithmInfo struct {
+	name    string           // Name used in gitoid URIs
+	newHash func() hash.Hash // Constructor for the hash
+	size    int              // Digest size in bytes
+}
+
+// algorithms is the registry of supported algorithms, with the built-in ones pre-registered
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = map[Algorithm]algorithmInfo{
+		SHA1:   {name: "sha1", newHash: sha1.New, size: sha1.Size},
+		SHA256: {name: "sha256", newHash: sha256.New, size: sha256.Size},
+		SHA512: {name: "sha512", newHash: sha512.New, size: sha512.Size},
+	}
+)
+
+// RegisterAlgorithm makes a hash algorithm available for attesting and for parsing attestation headers,
+// allowing third parties to add algorithms such as BLAKE3 without changes to this package
+// The id is recorded in attestation headers and name appears in gitoid URIs; both must be unique
+func RegisterAlgorithm(id byte, name string, newHash func() hash.Hash) (Algorithm, error) {
+	if id == 0 {
+		return 0, errors.New("algorithm id 0 is reserved")
+	}
+	if name == "" || strings.ContainsAny(name, ": \x00") {
+		return 0, fmt.Errorf("invalid algorithm name %q", name)
+	}
+	if newHash == nil {
+		return 0, errors.New("hash constructor must not be nil")
+	}
+
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+
+	alg := Algorithm(id)
+	if existing, ok := algorithms[alg]; ok {
+		return 0, fmt.Errorf("algorithm id %d is already registered as %s", id, existing.name)
+	}
+	for existingID, existing := range algorithms {
+		if existing.name == name {
+			return 0, fmt.Errorf("algorithm name %s is already registered with id %d", name, byte(existingID))
+		}
+	}
+	algorithms[alg] = algorithmInfo{name: name, newHash: newHash, size: newHash().Size()}
+	return alg, nil
+}
+
+// ParseAlgorithm returns the registered algorithm with the given name, as it appears in gitoid URIs
+func ParseAlgorithm(name string) (Algorithm, error) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+	for id, info := range algorithms {
+		if info.name == name {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported hash algorithm %s", name)
+}
+
+// info returns the description of the algorithm, if it is registered
+func (a Algorithm) info() (algorithmInfo, bool) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+	info, ok := algorithms[a]
+	return info, ok
+}
+
+// String returns the algorithm's name as used in gitoid URIs
+func (a Algorithm) String() string {
+	if info, ok := a.info(); ok {
+		return info.name
+	}
+	return fmt.Sprintf("unknown(%d)", byte(a))
+}
+
+// Size returns the algorithm's digest size in bytes, or 0 if the algorithm is not registered
+func (a Algorithm) Size() int {
+	info, _ := a.info()
+	return info.size
+}
+
+// hashGitoid computes the gitoid hash, as the given git object type, of the concatenation of parts, i.e. the
+// digest of the git object header followed by the data; it is a variable so tests can inject hashing failures
+var hashGitoid = func(objectType gitoid.GitObjectType, alg Algorithm, parts ...[]byte) ([]byte, error) {
+	info, ok := alg.info()
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm %s", alg)
+	}
+	length := 0
+	for _, part := range parts {
+		length += len(part)
+	}
+	h := info.newHash()
+	h.Write(gitoid.Header(objectType, int64(length)))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil), nil
+}
+
+// hashRaw computes the plain digest of data, without any git object header
+func hashRaw(alg Algorithm, data []byte) ([]byte, error) {
+	info, ok := alg.info()
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm %s", alg)
+	}
+	h := info.newHash()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// gitoidURI formats a gitoid hash as a gitoid URI
+func gitoidURI(objectType gitoid.GitObjectType, alg Algorithm, hash []byte) string {
+	return fmt.Sprintf("gitoid:%s:%s:%x", objectType, alg, hash)
+}
@@ -0,0 +1,91 @@
+package terrapin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"hash/fnv"
+	"strings"
+	"testing"
+)
+
+func TestRegisterAlgorithm(t *testing.T) {
+	alg, err := RegisterAlgorithm(200, "sha224", sha256.New224)
+	if err != nil {
+		t.Fatalf("RegisterAlgorithm returned an error: %v", err)
+	}
+	if alg.String() != "sha224" || alg.Size() != sha256.Size224 {
+		t.Fatalf("Expected sha224 with %d-byte digests, got %s with %d", sha256.Size224, alg, alg.Size())
+	}
+
+	data := make([]byte, 3*1024+5)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	attestor, err := NewTerrapinWithOptions(WithHashAlgorithm(alg), WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	rootURI, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if !strings.HasPrefix(rootURI, "gitoid:blob:sha224:") {
+		t.Errorf("Expected a sha224 root URI, got %s", rootURI)
+	}
+
+	// The header parser resolves the registered algorithm by id
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with attestations: %v", err)
+	}
+	if len(terrapin.attestations) != 4*sha256.Size224 {
+		t.Fatalf("Expected 4 chunk hashes of %d bytes, got %d bytes", sha256.Size224, len(terrapin.attestations))
+	}
+	match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !match {
+		t.Fatalf("VerifyBuffer expected to match, got %v, %v", match, err)
+	}
+	data[2000] ^= 0xff
+	match, err = terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || match {
+		t.Fatalf("VerifyBuffer expected to mismatch, got %v, %v", match, err)
+	}
+}
+
+func TestRegisterAlgorithmConflicts(t *testing.T) {
+	for name, register := range map[string]func() error{
+		"reserved id":    func() error { _, err := RegisterAlgorithm(0, "fnv", newFNV); return err },
+		"duplicate id":   func() error { _, err := RegisterAlgorithm(byte(SHA256), "fnv", newFNV); return err },
+		"duplicate name": func() error { _, err := RegisterAlgorithm(201, "sha512", newFNV); return err },
+		"invalid name":   func() error { _, err := RegisterAlgorithm(201, "fnv:64", newFNV); return err },
+		"nil hash":       func() error { _, err := RegisterAlgorithm(201, "fnv", nil); return err },
+	} {
+		if err := register(); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+// newFNV adapts fnv.New64 to the hash constructor signature
+func newFNV() hash.Hash {
+	return fnv.New64()
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	for _, alg := range []Algorithm{SHA1, SHA256, SHA512} {
+		parsed, err := ParseAlgorithm(alg.String())
+		if err != nil {
+			t.Fatalf("ParseAlgorithm returned an error for %s: %v", alg, err)
+		}
+		if parsed != alg {
+			t.Errorf("Expected %s, got %s", alg, parsed)
+		}
+	}
+	if _, err := ParseAlgorithm("md5"); err == nil {
+		t.Error("Expected an unregistered algorithm to be rejected")
+	}
+}
@@ -0,0 +1,97 @@
+package terrapin
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+)
+
+// asn1Attestations is the DER structure produced by MarshalASN1:
+//
+//	Attestations ::= SEQUENCE {
+//	    algorithm  OBJECT IDENTIFIER,
+//	    chunkSize  INTEGER,
+//	    digests    SEQUENCE OF OCTET STRING
+//	}
+type asn1Attestations struct {
+	Algorithm asn1.ObjectIdentifier
+	ChunkSize int
+	Digests   [][]byte
+}
+
+// algorithmOIDs maps the built-in algorithms to their NIST and OIW object identifiers
+var algorithmOIDs = map[Algorithm]asn1.ObjectIdentifier{
+	SHA1:   {1, 3, 14, 3, 2, 26},
+	SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+	SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+}
+
+// MarshalASN1 returns the chunk hashes of a finalized instance as a DER-encoded ASN.1 structure holding the
+// algorithm OID, the chunk size and the chunk digests, for embedding in X.509 extensions or CMS structures
+// Only the built-in algorithms, complete blob chunk gitoids over fixed-size chunks and a blob root type can be
+// represented, as the structure carries no object types; any Merkle tree is not carried, as it can be recomputed
+// from the chunk digests
+func (t *Terrapin) MarshalASN1() ([]byte, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	oid, ok := algorithmOIDs[t.algorithm]
+	if !ok {
+		return nil, fmt.Errorf("no ASN.1 object identifier for hash algorithm %s", t.algorithm)
+	}
+	if t.chunkType != gitoid.BLOB {
+		return nil, fmt.Errorf("chunk type %q cannot be represented in ASN.1", t.chunkType)
+	}
+	if t.rootType != gitoid.BLOB {
+		return nil, fmt.Errorf("root type %q cannot be represented in ASN.1", t.rootType)
+	}
+	if !t.hasChunkURIs() {
+		return nil, errors.New("raw or truncated chunk hashes cannot be represented in ASN.1")
+	}
+	if t.variable {
+		return nil, errVariableChunks
+	}
+
+	digests := make([][]byte, 0, len(t.attestations)/t.digestSize())
+	for i := 0; i < len(t.attestations); i += t.digestSize() {
+		digests = append(digests, t.attestations[i:i+t.digestSize()])
+	}
+	return asn1.Marshal(asn1Attestations{
+		Algorithm: oid,
+		ChunkSize: t.blockSize,
+		Digests:   digests,
+	})
+}
+
+// UnmarshalASN1 initializes a Terrapin instance from the DER-encoded attestations produced by MarshalASN1
+func UnmarshalASN1(der []byte) (*Terrapin, error) {
+	var parsed asn1Attestations
+	rest, err := asn1.Unmarshal(der, &parsed)
+	if err != nil {
+		return nil, &InvalidAttestationsError{Reason: err.Error()}
+	}
+	if len(rest) > 0 {
+		return nil, &InvalidAttestationsError{Reason: "trailing data after ASN.1 attestations"}
+	}
+
+	alg := Algorithm(0)
+	for candidate, oid := range algorithmOIDs {
+		if oid.Equal(parsed.Algorithm) {
+			alg = candidate
+		}
+	}
+	if alg == 0 {
+		return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("unsupported hash algorithm %s", parsed.Algorithm)}
+	}
+
+	attestations := make([]byte, 0, len(parsed.Digests)*alg.Size())
+	for _, digest := range parsed.Digests {
+		if len(digest) != alg.Size() {
+			return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("digest size %d does not match %s", len(digest), alg)}
+		}
+		attestations = append(attestations, digest...)
+	}
+	return NewTerrapinWithAttestations(attestations, WithBlockSize(parsed.ChunkSize), WithHashAlgorithm(alg), WithEmptyAttestations())
+}
@@ -0,0 +1,87 @@
+package terrapin
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"github.com/edwarnicke/gitoid"
+	"strings"
+	"testing"
+)
+
+func TestMarshalASN1(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024), WithHashAlgorithm(SHA512))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if _, err := attestor.MarshalASN1(); err == nil {
+		t.Error("Expected MarshalASN1 to require a finalized instance")
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uri, _, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	der, err := attestor.MarshalASN1()
+	if err != nil {
+		t.Fatalf("MarshalASN1 returned an error: %v", err)
+	}
+
+	// The structure decodes with encoding/asn1 alone
+	var decoded struct {
+		Algorithm asn1.ObjectIdentifier
+		ChunkSize int
+		Digests   [][]byte
+	}
+	if _, err := asn1.Unmarshal(der, &decoded); err != nil {
+		t.Fatalf("asn1.Unmarshal returned an error: %v", err)
+	}
+	if !decoded.Algorithm.Equal(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}) {
+		t.Errorf("Expected the SHA-512 OID, got %s", decoded.Algorithm)
+	}
+	if decoded.ChunkSize != 1024 || len(decoded.Digests) != 4 {
+		t.Errorf("Expected 4 digests of 1024-byte chunks, got %d of %d-byte chunks", len(decoded.Digests), decoded.ChunkSize)
+	}
+
+	terrapin, err := UnmarshalASN1(der)
+	if err != nil {
+		t.Fatalf("UnmarshalASN1 returned an error: %v", err)
+	}
+	if parsedURI, _, _ := terrapin.Finalize(); parsedURI != uri {
+		t.Errorf("Expected URI %s, got %s", uri, parsedURI)
+	}
+	match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+
+	if _, err := UnmarshalASN1(append(der, 0)); err == nil {
+		t.Error("Expected trailing data to be rejected")
+	}
+
+	// Object types other than blob would be lost, changing the root gitoid
+	for name, opt := range map[string]Option{"chunk": WithChunkType(gitoid.COMMIT), "root": WithRootType(gitoid.COMMIT)} {
+		typed, err := NewTerrapinWithOptions(WithBlockSize(1024), opt)
+		if err != nil {
+			t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+		}
+		if err := typed.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if _, _, err := typed.Finalize(); err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		if _, err := typed.MarshalASN1(); err == nil || !strings.Contains(err.Error(), name+" type") {
+			t.Errorf("Expected a non-blob %s type to be rejected, got %v", name, err)
+		}
+	}
+}
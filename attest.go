@@ -0,0 +1,261 @@
+package terrapin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// AttestReaderPipelined reads r to EOF and returns the gitoid URI and attestations of its content
+// Reading and hashing overlap: a read-ahead goroutine fills the next block while the current one is
+// hashed, which improves throughput for I/O-bound sources such as large files on disk
+// The result is identical to adding the same data to a Terrapin configured with opts
+func AttestReaderPipelined(r io.Reader, opts ...Option) (string, []byte, error) {
+	t, err := NewTerrapinWithOptions(opts...)
+	if err != nil {
+		return "", nil, err
+	}
+	return t.attestPipelined(r)
+}
+
+// AttestReaderN attests at most maxChunks chunks read from r, then stops reading
+// This gives a well-defined result for unbounded sources, such as live streams that never return EOF
+func AttestReaderN(r io.Reader, maxChunks int, opts ...Option) (string, []byte, error) {
+	if maxChunks < 0 {
+		return "", nil, errors.New("maxChunks must not be negative")
+	}
+	t, err := NewTerrapinWithOptions(opts...)
+	if err != nil {
+		return "", nil, err
+	}
+	return t.attestPipelined(io.LimitReader(r, int64(maxChunks)*int64(t.blockSize)))
+}
+
+// AttestFull reads r to EOF once and returns both the whole-file gitoid URI, as gitoid.New would compute it,
+// and the chunk attestations, so consumers get the classic single identifier and range-verifiable
+// attestations from a single read
+// When the reader's length cannot be determined up front (it is not a bytes.Reader, strings.Reader,
+// or seekable file) the data is buffered in memory, as required by the gitoid header
+func AttestFull(r io.Reader, opts ...Option) (string, []byte, error) {
+	length, ok := readerLength(r)
+	if !ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		r = bytes.NewReader(data)
+		length = int64(len(data))
+	}
+
+	t, err := NewTerrapinWithOptions(slices.Concat(opts, []Option{WithFileGitoid(length)})...)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, _, err := t.attestPipelined(r); err != nil {
+		return "", nil, err
+	}
+	fileURI, err := t.FileGitoid()
+	if err != nil {
+		return "", nil, err
+	}
+	return fileURI, t.blob(t.attestations), nil
+}
+
+// AttestSource reads src to EOF and returns the gitoid URI and attestations of its content, giving files,
+// network connections, and buffers a single typed entry point
+// Regular files are attested from their current offset through the AttestReaderAt fast path, and are left
+// positioned at their end as if read; any other source is attested with AttestReaderPipelined
+func AttestSource[T io.Reader](src T, opts ...Option) (string, []byte, error) {
+	if file, ok := any(src).(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			offset, err := file.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to read input: %w", err)
+			}
+			size := max(info.Size()-offset, 0)
+			uri, attestations, err := attestSourceAt(io.NewSectionReader(file, offset, size), size, opts...)
+			if err != nil {
+				return "", nil, err
+			}
+			if _, err := file.Seek(offset+size, io.SeekStart); err != nil {
+				return "", nil, fmt.Errorf("failed to read input: %w", err)
+			}
+			return uri, attestations, nil
+		}
+	}
+	return AttestReaderPipelined(src, opts...)
+}
+
+// attestSourceAt is the fast path AttestSource takes for regular files; it is a variable so tests can
+// observe which path was chosen
+var attestSourceAt = AttestReaderAt
+
+// AttestingReader forwards the bytes of a source while attesting them, so data can be attested as it is read
+// for an upload without a separate pass
+type AttestingReader struct {
+	src io.Reader // Source whose bytes are forwarded
+	t   *Terrapin // Instance attesting the bytes read
+	eof bool      // Whether src has returned io.EOF
+}
+
+// NewAttestingReader returns an AttestingReader over src attesting with the settings of opts
+func NewAttestingReader(src io.Reader, opts ...Option) (*AttestingReader, error) {
+	t, err := NewTerrapinWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestingReader{
+		src: src,
+		t:   t,
+	}, nil
+}
+
+// Read implements io.Reader, attesting every byte it returns
+func (r *AttestingReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	if n > 0 {
+		if addErr := r.t.Add(p[:n]); addErr != nil {
+			return n, addErr
+		}
+	}
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// Finalize returns the gitoid URI and attestations of everything read, once the source has been read to EOF
+func (r *AttestingReader) Finalize() (string, []byte, error) {
+	if !r.eof {
+		return "", nil, errors.New("source not read to EOF")
+	}
+	return r.t.Finalize()
+}
+
+// UnreadableSectorSize is the granularity at which WithZeroFillUnreadable zero-fills unreadable regions
+const UnreadableSectorSize = 512
+
+// AttestReaderAt attests the first size bytes of r, reading one block at a time at increasing offsets
+// Regions that cannot be read fail the attestation unless the WithZeroFillUnreadable policy is set
+func AttestReaderAt(r io.ReaderAt, size int64, opts ...Option) (string, []byte, error) {
+	if size < 0 {
+		return "", nil, errors.New("size must not be negative")
+	}
+	t, err := NewTerrapinWithOptions(opts...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	r = t.limitReaderAt(context.Background(), r)
+	buffer := make([]byte, t.blockSize)
+	for offset := int64(0); offset < size; offset += int64(t.blockSize) {
+		chunk := buffer[:min(int64(t.blockSize), size-offset)]
+		if err := t.readAtOrZeroFill(r, chunk, offset); err != nil {
+			return "", nil, err
+		}
+		if err := t.Add(chunk); err != nil {
+			return "", nil, err
+		}
+	}
+	return t.Finalize()
+}
+
+// readAtOrZeroFill fills p from r at offset, applying the zero-fill policy to regions that cannot be read
+func (t *Terrapin) readAtOrZeroFill(r io.ReaderAt, p []byte, offset int64) error {
+	n, err := r.ReadAt(p, offset)
+	if n == len(p) {
+		return nil
+	}
+	if err == io.EOF {
+		return fmt.Errorf("unexpected end of data at offset %d", offset+int64(n))
+	}
+	if !t.zeroFill {
+		return fmt.Errorf("failed to read at offset %d: %w", offset, err)
+	}
+
+	// Re-read sector by sector, zero-filling only the sectors that still fail
+	for start := 0; start < len(p); start += UnreadableSectorSize {
+		sector := p[start:min(start+UnreadableSectorSize, len(p))]
+		n, err := r.ReadAt(sector, offset+int64(start))
+		if n == len(sector) {
+			continue
+		}
+		if err == io.EOF {
+			return fmt.Errorf("unexpected end of data at offset %d", offset+int64(start+n))
+		}
+		clear(sector)
+		if t.onUnreadable != nil {
+			t.onUnreadable(offset+int64(start), int64(len(sector)), err)
+		}
+	}
+	return nil
+}
+
+// attestPipelined adds all data from r with read-ahead, then finalizes
+func (t *Terrapin) attestPipelined(r io.Reader) (string, []byte, error) {
+	// block carries one filled buffer, or the read error that ended the stream
+	type block struct {
+		data []byte
+		err  error
+	}
+
+	r = t.limitReader(context.Background(), r)
+
+	// One buffer more than the queue size circulates between the reader and the hasher, so the reader blocks
+	// once the queue is full and the hasher holds the remaining buffer
+	free := make(chan []byte, t.inputQueueSize+1)
+	for i := 0; i <= t.inputQueueSize; i++ {
+		free <- make([]byte, t.blockSize)
+	}
+	filled := make(chan block, t.inputQueueSize)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(filled)
+		for {
+			var buffer []byte
+			select {
+			case buffer = <-free:
+			case <-done:
+				return
+			}
+
+			n, err := io.ReadFull(r, buffer)
+			if n > 0 {
+				select {
+				case filled <- block{data: buffer[:n]}:
+				case <-done:
+					return
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				select {
+				case filled <- block{err: err}:
+				case <-done:
+				}
+				return
+			}
+		}
+	}()
+
+	// Hash each block as it arrives, then hand its buffer back to the reader
+	for b := range filled {
+		if b.err != nil {
+			return "", nil, fmt.Errorf("failed to read input: %w", b.err)
+		}
+		if err := t.Add(b.data); err != nil {
+			return "", nil, err
+		}
+		free <- b.data[:cap(b.data)]
+	}
+
+	return t.Finalize()
+}
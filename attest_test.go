@@ -0,0 +1,457 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+	"github.com/edwarnicke/gitoid"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestAttestReaderPipelined(t *testing.T) {
+	for _, size := range []int{0, 100, BufferCapacity, 3*BufferCapacity + 17} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i % 253)
+		}
+
+		expected := NewTerrapin()
+		if err := expected.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		expectedGid, expectedAttestations, err := expected.Finalize()
+		if err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+
+		// A reader returning short reads must produce the same chunk boundaries
+		gid, attestations, err := AttestReaderPipelined(iotest.HalfReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+		}
+		if gid != expectedGid {
+			t.Errorf("size %d: expected gid %s, got %s", size, expectedGid, gid)
+		}
+		if !bytes.Equal(attestations, expectedAttestations) {
+			t.Errorf("size %d: attestations differ from the serial path", size)
+		}
+	}
+}
+
+func TestAttestReaderPipelined_ReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	reader := io.MultiReader(bytes.NewReader(make([]byte, BufferCapacity+5)), iotest.ErrReader(readErr))
+	if _, _, err := AttestReaderPipelined(reader); !errors.Is(err, readErr) {
+		t.Fatalf("Expected read error, got %v", err)
+	}
+}
+
+// writeBenchmarkFile writes a 256MB file of patterned data for attestation benchmarks
+func writeBenchmarkFile(b *testing.B) string {
+	b.Helper()
+	data := make([]byte, 256*1024*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	path := filepath.Join(b.TempDir(), "large.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		b.Fatalf("Failed to write benchmark file: %v", err)
+	}
+	return path
+}
+
+func BenchmarkAttestSerial(b *testing.B) {
+	path := writeBenchmarkFile(b)
+	b.SetBytes(256 * 1024 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		terrapin := NewTerrapin()
+		if _, err := terrapin.AddReader(file); err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := terrapin.Finalize(); err != nil {
+			b.Fatal(err)
+		}
+		file.Close()
+	}
+}
+
+func BenchmarkAttestPipelined(b *testing.B) {
+	path := writeBenchmarkFile(b)
+	b.SetBytes(256 * 1024 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := AttestReaderPipelined(file); err != nil {
+			b.Fatal(err)
+		}
+		file.Close()
+	}
+}
+
+// blockingReader blocks every Read until release is closed, like a live stream that never ends
+type blockingReader struct {
+	release chan struct{}
+}
+
+func (b *blockingReader) Read(p []byte) (int, error) {
+	<-b.release
+	return 0, io.EOF
+}
+
+// runWithTimeout fails the test if fn does not return promptly
+func runWithTimeout(t *testing.T, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out, the unbounded reader was read past the limit")
+	}
+}
+
+func TestBoundedUnendingStream(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	blocker := &blockingReader{release: make(chan struct{})}
+	defer close(blocker.release)
+
+	expected, _ := setupTerrapinWithData(t, data[:2*BufferCapacity])
+	expectedGid, _, _ := expected.Finalize()
+
+	var gid string
+	var attestations []byte
+	var err error
+	runWithTimeout(t, func() {
+		gid, attestations, err = AttestReaderN(io.MultiReader(bytes.NewReader(data[:2*BufferCapacity]), blocker), 2)
+	})
+	if err != nil {
+		t.Fatalf("AttestReaderN returned an error: %v", err)
+	}
+	if gid != expectedGid {
+		t.Errorf("Expected gid %s, got %s", expectedGid, gid)
+	}
+
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with attestations: %v", err)
+	}
+	var match bool
+	runWithTimeout(t, func() {
+		match, err = terrapin.VerifyBufferN(io.MultiReader(bytes.NewReader(data[:2*BufferCapacity]), blocker), 1)
+	})
+	if err != nil || !match {
+		t.Fatalf("VerifyBufferN expected to match the leading chunk, got %v, %v", match, err)
+	}
+
+	// With room for every attested chunk, the data continuing past them is reported as VerifyBuffer does
+	runWithTimeout(t, func() {
+		match, err = terrapin.VerifyBufferN(io.MultiReader(bytes.NewReader(data), blocker), 5)
+	})
+	if err != nil || match {
+		t.Fatalf("VerifyBufferN expected trailing data to mismatch, got %v, %v", match, err)
+	}
+	if match, err := terrapin.VerifyBufferN(bytes.NewReader(data[:2*BufferCapacity]), 2); err != nil || !match {
+		t.Fatalf("VerifyBufferN expected to match, got %v, %v", match, err)
+	}
+
+	// The leading chunks must all be present
+	var truncated *TruncatedDataError
+	if _, err := terrapin.VerifyBufferN(bytes.NewReader(data[:BufferCapacity/2]), 1); !errors.As(err, &truncated) {
+		t.Fatalf("Expected a TruncatedDataError, got %v", err)
+	}
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	reader io.Reader
+	count  atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count.Add(int64(n))
+	return n, err
+}
+
+func TestWithInputQueueSize(t *testing.T) {
+	if _, err := NewTerrapinWithOptions(WithInputQueueSize(0)); err == nil {
+		t.Fatal("Expected an error for an empty input queue")
+	}
+
+	const blockSize, queueSize = 1024, 3
+	data := make([]byte, 10*blockSize)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	expected, err := NewTerrapinWithOptions(WithBlockSize(blockSize))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := expected.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	expectedGid, _, err := expected.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// Stall the hasher on the first chunk
+	release := make(chan struct{})
+	var once sync.Once
+	original := hashGitoid
+	defer func() { hashGitoid = original }()
+	hashGitoid = func(objectType gitoid.GitObjectType, alg Algorithm, parts ...[]byte) ([]byte, error) {
+		once.Do(func() { <-release })
+		return original(objectType, alg, parts...)
+	}
+
+	reader := &countingReader{reader: bytes.NewReader(data)}
+	var gid string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gid, _, err = AttestReaderPipelined(reader, WithBlockSize(blockSize), WithInputQueueSize(queueSize))
+	}()
+
+	// The reader fills the queue behind the stalled block, then blocks
+	limit := int64((queueSize + 1) * blockSize)
+	deadline := time.Now().Add(10 * time.Second)
+	for reader.count.Load() < limit && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if read := reader.count.Load(); read != limit {
+		t.Errorf("Expected the producer to block after %d bytes, read %d", limit, read)
+	}
+
+	// Draining the queue lets the producer finish
+	close(release)
+	<-done
+	if err != nil {
+		t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+	}
+	if gid != expectedGid {
+		t.Errorf("Expected gid %s, got %s", expectedGid, gid)
+	}
+}
+
+func TestAttestFull(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+17)
+	for i := range data {
+		data[i] = byte(i % 253)
+	}
+	expected, err := gitoid.New(bytes.NewReader(data), gitoid.WithSha256(), gitoid.WithContentLength(int64(len(data))))
+	if err != nil {
+		t.Fatalf("gitoid.New returned an error: %v", err)
+	}
+
+	// Readers of known and unknown length give the same result
+	for name, reader := range map[string]io.Reader{
+		"known length":   bytes.NewReader(data),
+		"unknown length": iotest.HalfReader(bytes.NewReader(data)),
+	} {
+		fileURI, attestations, err := AttestFull(reader)
+		if err != nil {
+			t.Fatalf("%s: AttestFull returned an error: %v", name, err)
+		}
+		if fileURI != expected.URI() {
+			t.Errorf("%s: Expected file URI %s, got %s", name, expected.URI(), fileURI)
+		}
+
+		terrapin, err := NewTerrapinWithAttestations(attestations)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithAttestations returned an error: %v", name, err)
+		}
+		match, err := terrapin.VerifyBufferRange(bytes.NewReader(data[BufferCapacity:3*BufferCapacity]), BufferCapacity, 3*BufferCapacity)
+		if err != nil {
+			t.Fatalf("%s: VerifyBufferRange returned an error: %v", name, err)
+		}
+		if !match {
+			t.Fatalf("%s: VerifyBufferRange expected to match, but it didn't", name)
+		}
+	}
+
+	// The file gitoid option is never appended into spare capacity of the caller's options
+	opts := make([]Option, 0, 1)
+	if _, _, err := AttestFull(bytes.NewReader(data), opts...); err != nil {
+		t.Fatalf("AttestFull returned an error: %v", err)
+	}
+	if opts[:1][0] != nil {
+		t.Error("Expected the caller's options to be left untouched")
+	}
+}
+
+// badSectorReaderAt fails every read that touches the bad region, like media with unreadable sectors
+type badSectorReaderAt struct {
+	data     []byte
+	badStart int64
+	badEnd   int64
+}
+
+func (b *badSectorReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < b.badEnd && off+int64(len(p)) > b.badStart {
+		return 0, errors.New("bad sector")
+	}
+	return bytes.NewReader(b.data).ReadAt(p, off)
+}
+
+func TestAttestReaderAt_ZeroFillUnreadable(t *testing.T) {
+	data := make([]byte, 4*1024+100)
+	for i := range data {
+		data[i] = byte(i%255 + 1)
+	}
+	reader := &badSectorReaderAt{data: data, badStart: 1500, badEnd: 2100}
+
+	// By default unreadable regions fail the attestation
+	if _, _, err := AttestReaderAt(reader, int64(len(data)), WithBlockSize(1024)); err == nil {
+		t.Fatal("Expected AttestReaderAt to fail on unreadable data")
+	}
+
+	var filled [][2]int64
+	report := func(offset, length int64, err error) {
+		filled = append(filled, [2]int64{offset, length})
+	}
+	gid, attestations, err := AttestReaderAt(reader, int64(len(data)), WithBlockSize(1024), WithZeroFillUnreadable(report))
+	if err != nil {
+		t.Fatalf("AttestReaderAt returned an error: %v", err)
+	}
+
+	// Only the sectors overlapping the bad region are zero-filled
+	expectedFilled := [][2]int64{{1024, 512}, {1536, 512}, {2048, 512}}
+	if len(filled) != len(expectedFilled) {
+		t.Fatalf("Expected zero-filled sectors %v, got %v", expectedFilled, filled)
+	}
+	for i := range expectedFilled {
+		if filled[i] != expectedFilled[i] {
+			t.Fatalf("Expected zero-filled sectors %v, got %v", expectedFilled, filled)
+		}
+	}
+
+	reference := append([]byte(nil), data...)
+	clear(reference[1024:2560])
+	expectedGid, expectedAttestations, err := AttestReaderAt(bytes.NewReader(reference), int64(len(reference)), WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("AttestReaderAt returned an error: %v", err)
+	}
+	if gid != expectedGid || !bytes.Equal(attestations, expectedAttestations) {
+		t.Errorf("Expected the zero-filled attestation to match the zero-filled reference")
+	}
+
+	// Data shorter than the declared size is an error, not a hole
+	if _, _, err := AttestReaderAt(bytes.NewReader(data), int64(len(data))+1, WithZeroFillUnreadable(nil)); err == nil {
+		t.Error("Expected AttestReaderAt to fail on truncated data")
+	}
+}
+
+func TestAttestSource(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	expected := NewTerrapin()
+	if err := expected.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	expectedURI, expectedAttestations, err := expected.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// Record whether the ReaderAt fast path was taken
+	fastPath := false
+	t.Cleanup(func() { attestSourceAt = AttestReaderAt })
+	attestSourceAt = func(r io.ReaderAt, size int64, opts ...Option) (string, []byte, error) {
+		fastPath = true
+		return AttestReaderAt(r, size, opts...)
+	}
+
+	uri, attestations, err := AttestSource(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("AttestSource returned an error: %v", err)
+	}
+	if uri != expectedURI || !bytes.Equal(attestations, expectedAttestations) {
+		t.Errorf("Expected buffer attestations to match attesting with Add")
+	}
+	if fastPath {
+		t.Error("Expected a bytes.Reader to be streamed rather than take the file fast path")
+	}
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open data: %v", err)
+	}
+	defer file.Close()
+	uri, attestations, err = AttestSource(file)
+	if err != nil {
+		t.Fatalf("AttestSource returned an error: %v", err)
+	}
+	if uri != expectedURI || !bytes.Equal(attestations, expectedAttestations) {
+		t.Errorf("Expected file attestations to match attesting with Add")
+	}
+	if !fastPath {
+		t.Error("Expected an *os.File to take the ReaderAt fast path")
+	}
+	if offset, err := file.Seek(0, io.SeekCurrent); err != nil || offset != int64(len(data)) {
+		t.Errorf("Expected the file to be positioned at its end, got %d, %v", offset, err)
+	}
+}
+
+func TestAttestingReader(t *testing.T) {
+	data := make([]byte, 3*1024+17)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	expectedURI, expectedAttestations, err := AttestReaderPipelined(bytes.NewReader(data), WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+	}
+
+	r, err := NewAttestingReader(iotest.HalfReader(bytes.NewReader(data)), WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewAttestingReader returned an error: %v", err)
+	}
+	if _, _, err := r.Finalize(); err == nil {
+		t.Error("Expected error finalizing before EOF, got nil")
+	}
+
+	// The bytes pass through unchanged, as an upload would send them
+	var uploaded bytes.Buffer
+	if _, err := io.Copy(&uploaded, r); err != nil {
+		t.Fatalf("Failed to read through AttestingReader: %v", err)
+	}
+	if !bytes.Equal(uploaded.Bytes(), data) {
+		t.Error("Expected AttestingReader to forward the source unchanged")
+	}
+
+	uri, attestations, err := r.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	if uri != expectedURI || !bytes.Equal(attestations, expectedAttestations) {
+		t.Error("Expected AttestingReader to match the direct attestation")
+	}
+}
@@ -0,0 +1,50 @@
+package terrapin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Checked attestations are the attestations blob followed by a trailer holding the hash of the root gitoid and
+// a single byte giving the length of that hash. The trailer lets NewTerrapinWithAttestationsChecked detect an
+// attestations file corrupted in transit, which would otherwise only show up as data failing verification.
+
+// FinalizeChecked finalizes the instance like Finalize, but returns the attestations with the root gitoid
+// trailer appended, for loading with NewTerrapinWithAttestationsChecked
+func (t *Terrapin) FinalizeChecked() (string, []byte, error) {
+	uri, attestations, err := t.Finalize()
+	if err != nil {
+		return "", nil, err
+	}
+	root, err := hex.DecodeString(uri[strings.LastIndex(uri, ":")+1:])
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode root gitoid: %w", err)
+	}
+	attestations = append(attestations, root...)
+	return uri, append(attestations, byte(len(root))), nil
+}
+
+// NewTerrapinWithAttestationsChecked initializes a Terrapin instance from checked attestations produced by
+// FinalizeChecked, returning an *InvalidAttestationsError if the root gitoid recomputed over the chunk hashes
+// does not match the one in the trailer. Options are applied as by NewTerrapinWithAttestations
+// The trailer vouches for the attestations, so those of an empty file are accepted without WithEmptyAttestations
+func NewTerrapinWithAttestationsChecked(checked []byte, opts ...Option) (*Terrapin, error) {
+	if len(checked) == 0 || int(checked[len(checked)-1]) >= len(checked) {
+		return nil, &InvalidAttestationsError{Reason: "missing root gitoid trailer"}
+	}
+	rootStart := len(checked) - 1 - int(checked[len(checked)-1])
+	attestations, root := checked[:rootStart], checked[rootStart:len(checked)-1]
+
+	res, err := NewTerrapinWithAttestations(attestations, slices.Concat(opts, []Option{WithEmptyAttestations()})...)
+	if err != nil {
+		return nil, err
+	}
+	if expected := gitoidURI(res.rootType, res.algorithm, root); res.rootURI != expected {
+		return nil, &InvalidAttestationsError{
+			Reason: fmt.Sprintf("root gitoid %s does not match the %s recorded in the trailer", res.rootURI, expected),
+		}
+	}
+	return res, nil
+}
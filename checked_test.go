@@ -0,0 +1,84 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNewTerrapinWithAttestationsChecked(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	for name, opts := range map[string][]Option{
+		"headerless": nil,
+		"sha512":     {WithBlockSize(1024), WithHashAlgorithm(SHA512)},
+		"merkle":     {WithBlockSize(1024), WithMerkle()},
+	} {
+		attestor, err := NewTerrapinWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithOptions returned an error: %v", name, err)
+		}
+		if err := attestor.Add(data); err != nil {
+			t.Fatalf("%s: Failed to add data: %v", name, err)
+		}
+		uri, checked, err := attestor.FinalizeChecked()
+		if err != nil {
+			t.Fatalf("%s: FinalizeChecked returned an error: %v", name, err)
+		}
+		_, attestations, _ := attestor.Finalize()
+		if !bytes.HasPrefix(checked, attestations) {
+			t.Errorf("%s: Expected checked attestations to start with the attestations", name)
+		}
+
+		terrapin, err := NewTerrapinWithAttestationsChecked(checked)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithAttestationsChecked returned an error: %v", name, err)
+		}
+		parsedURI, _, err := terrapin.Finalize()
+		if err != nil || parsedURI != uri {
+			t.Errorf("%s: Expected gitoid %s, got %s, %v", name, uri, parsedURI, err)
+		}
+		if valid, err := terrapin.VerifyBuffer(bytes.NewReader(data)); err != nil || !valid {
+			t.Errorf("%s: Expected data to verify, got %v, %v", name, valid, err)
+		}
+
+		// A corrupted chunk hash keeps the length valid but no longer matches the trailer
+		corrupted := bytes.Clone(checked)
+		corrupted[len(attestations)-1] ^= 0xff
+		var invalidErr *InvalidAttestationsError
+		if _, err := NewTerrapinWithAttestationsChecked(corrupted); !errors.As(err, &invalidErr) {
+			t.Errorf("%s: Expected InvalidAttestationsError for a corrupted chunk hash, got %v", name, err)
+		}
+	}
+
+	// The attestations of an empty file are vouched for by the trailer
+	_, checked, err := NewTerrapin().FinalizeChecked()
+	if err != nil {
+		t.Fatalf("FinalizeChecked returned an error: %v", err)
+	}
+	if _, err := NewTerrapinWithAttestationsChecked(checked); err != nil {
+		t.Errorf("Expected the checked attestations of an empty file to load, got %v", err)
+	}
+
+	// Options are never appended into spare capacity of the caller's slice
+	opts := make([]Option, 0, 1)
+	if _, err := NewTerrapinWithAttestationsChecked(checked, opts...); err != nil {
+		t.Fatalf("NewTerrapinWithAttestationsChecked returned an error: %v", err)
+	}
+	if opts[:1][0] != nil {
+		t.Error("Expected the caller's options to be left untouched")
+	}
+
+	for name, checked := range map[string][]byte{
+		"empty":           nil,
+		"no trailer":      bytes.Repeat([]byte{1}, 64),
+		"wrong length":    append(bytes.Repeat([]byte{1}, 64), 200),
+		"truncated trail": checked[1:],
+	} {
+		if _, err := NewTerrapinWithAttestationsChecked(checked); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package terrapin
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Chunker chooses chunk boundaries from the content of the data rather than at fixed offsets, so inserting or
+// removing bytes only moves the boundaries near the edit and the hashes of the remaining chunks are unchanged
+type Chunker interface {
+	// MaxSize returns the largest chunk the chunker produces
+	MaxSize() int
+	// Boundary returns the length of the chunk at the start of data, from 1 to the smaller of len(data) and
+	// MaxSize. data holds at least MaxSize bytes unless it is the end of the stream
+	Boundary(data []byte) int
+}
+
+// WithChunker splits the data added with Add into variable-size chunks at the boundaries chosen by chunker,
+// recording the length of every chunk in the header as WithVariableChunks does. Verification reads the
+// boundaries from the header, so it does not need the chunker
+func WithChunker(chunker Chunker) Option {
+	return func(t *Terrapin) error {
+		if chunker == nil {
+			return errors.New("chunker must not be nil")
+		}
+		if size := chunker.MaxSize(); size < 1 || size > MaxBlockSize {
+			return fmt.Errorf("chunker maximum size %d is outside the range 1 to %d bytes", size, MaxBlockSize)
+		}
+		t.variable = true
+		t.chunker = chunker
+		return nil
+	}
+}
+
+// splitChunks attests the chunks the chunker finds in the buffer, leaving any data that may still be extended
+// by later calls to Add; atEOF attests the whole buffer
+func (t *Terrapin) splitChunks(atEOF bool) error {
+	offset := 0
+	defer func() {
+		// Keep the unattested remainder, even if hashing failed, so the attested chunks are never repeated
+		t.buffer = append(t.buffer[:0], t.buffer[offset:]...)
+	}()
+
+	for len(t.buffer) > offset && (atEOF || len(t.buffer)-offset >= t.chunker.MaxSize()) {
+		rest := t.buffer[offset:]
+		n := t.chunker.Boundary(rest)
+		if n < 1 || n > min(len(rest), t.chunker.MaxSize()) {
+			return fmt.Errorf("chunker returned invalid chunk length %d", n)
+		}
+
+		hash, err := t.hashChunk(rest[:n])
+		if err != nil {
+			return err
+		}
+		t.attestations = append(t.attestations, hash...)
+		t.chunkLengths = append(t.chunkLengths, n)
+		t.reportProgress(n)
+		offset += n
+	}
+	return nil
+}
+
+// rabinWindow is the number of trailing bytes the RabinChunker rolling hash covers
+const rabinWindow = 48
+
+// rabinMultiplier is the base of the RabinChunker polynomial rolling hash
+const rabinMultiplier = 0x100000001b3
+
+// RabinChunker is a content-defined Chunker using a Rabin-Karp rolling hash over the last rabinWindow bytes
+// A chunk ends after the first byte, at least minSize bytes in, where the low bits of the hash are all set, so
+// chunks average about minSize plus avgSize bytes and never exceed maxSize
+type RabinChunker struct {
+	minSize int    // Smallest chunk, except for the final one
+	maxSize int    // Largest chunk
+	mask    uint64 // Low bits of the hash that must all be set at a boundary
+	pow     uint64 // rabinMultiplier to the power of rabinWindow, removing the byte leaving the window
+}
+
+// NewRabinChunker returns a RabinChunker producing chunks of minSize to maxSize bytes
+// avgSize must be a power of two and sets the expected distance to a boundary once past minSize
+func NewRabinChunker(minSize, avgSize, maxSize int) (*RabinChunker, error) {
+	if avgSize < 1 || avgSize&(avgSize-1) != 0 {
+		return nil, fmt.Errorf("average chunk size %d is not a power of two", avgSize)
+	}
+	if minSize < 1 || minSize > maxSize || maxSize > MaxBlockSize {
+		return nil, fmt.Errorf("chunk sizes must satisfy 1 <= %d <= %d <= %d", minSize, maxSize, MaxBlockSize)
+	}
+
+	pow := uint64(1)
+	for i := 0; i < rabinWindow; i++ {
+		pow *= rabinMultiplier
+	}
+	return &RabinChunker{minSize: minSize, maxSize: maxSize, mask: uint64(avgSize - 1), pow: pow}, nil
+}
+
+// MaxSize returns the largest chunk the chunker produces
+func (c *RabinChunker) MaxSize() int {
+	return c.maxSize
+}
+
+// Boundary returns the length of the chunk at the start of data
+func (c *RabinChunker) Boundary(data []byte) int {
+	limit := min(len(data), c.maxSize)
+	if limit <= c.minSize {
+		return limit
+	}
+
+	// Only the window ending at each candidate boundary affects the hash there
+	var hash uint64
+	start := max(c.minSize-rabinWindow, 0)
+	for i := start; i < limit; i++ {
+		hash = hash*rabinMultiplier + uint64(data[i])
+		if i-rabinWindow >= start {
+			hash -= c.pow * uint64(data[i-rabinWindow])
+		}
+		if i+1 >= c.minSize && hash&c.mask == c.mask {
+			return i + 1
+		}
+	}
+	return limit
+}
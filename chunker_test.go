@@ -0,0 +1,165 @@
+package terrapin
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// attestChunked attests data with the chunker, adding it in pieces of the given size
+func attestChunked(t *testing.T, chunker Chunker, data []byte, piece int) (*Terrapin, []byte) {
+	t.Helper()
+	attestor, err := NewTerrapinWithOptions(WithChunker(chunker))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	for offset := 0; offset < len(data); offset += piece {
+		if err := attestor.Add(data[offset:min(offset+piece, len(data))]); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	return attestor, attestations
+}
+
+func TestRabinChunker(t *testing.T) {
+	chunker, err := NewRabinChunker(1024, 4096, 16384)
+	if err != nil {
+		t.Fatalf("NewRabinChunker returned an error: %v", err)
+	}
+	data := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	original, attestations := attestChunked(t, chunker, data, 1000)
+	if original.NumChunks() < 100 {
+		t.Fatalf("Expected content-defined chunks averaging about 5 KiB, got %d chunks", original.NumChunks())
+	}
+
+	// The boundaries do not depend on how the data was added
+	if _, other := attestChunked(t, chunker, data, len(data)); !bytes.Equal(other, attestations) {
+		t.Error("Expected the same attestations regardless of how the data was added")
+	}
+
+	// Verification reads the boundaries from the header
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	valid, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !valid {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+
+	// Inserting bytes only changes the chunks around the insertion
+	edited := append(append(append([]byte(nil), data[:500000]...), "inserted bytes"...), data[500000:]...)
+	changed, _ := attestChunked(t, chunker, edited, 1000)
+	known := map[string]bool{}
+	for _, hash := range original.UniqueChunkHashes() {
+		known[string(hash)] = true
+	}
+	added := 0
+	for _, hash := range changed.UniqueChunkHashes() {
+		if !known[string(hash)] {
+			added++
+		}
+	}
+	if added < 1 || added > 3 {
+		t.Errorf("Expected 1 to 3 chunk hashes to change, got %d of %d", added, changed.NumChunks())
+	}
+}
+
+func TestNewRabinChunkerValidation(t *testing.T) {
+	for name, sizes := range map[string][3]int{
+		"average not a power of two": {1024, 3000, 16384},
+		"minimum above maximum":      {4096, 4096, 1024},
+		"zero minimum":               {0, 4096, 16384},
+		"maximum too large":          {1024, 4096, MaxBlockSize + 1},
+	} {
+		if _, err := NewRabinChunker(sizes[0], sizes[1], sizes[2]); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+// sharedChunkFraction attests both versions of the data with opts and returns the fraction of the chunk hashes
+// of the second version that also occur in the first
+func sharedChunkFraction(tb testing.TB, opts []Option, before, after []byte) float64 {
+	tb.Helper()
+	unique := func(data []byte) [][]byte {
+		_, attestations, err := AttestReaderPipelined(bytes.NewReader(data), opts...)
+		if err != nil {
+			tb.Fatalf("AttestReaderPipelined returned an error: %v", err)
+		}
+		terrapin, err := NewTerrapinWithAttestations(attestations)
+		if err != nil {
+			tb.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+		}
+		return terrapin.UniqueChunkHashes()
+	}
+
+	known := map[string]bool{}
+	for _, hash := range unique(before) {
+		known[string(hash)] = true
+	}
+	hashes := unique(after)
+	shared := 0
+	for _, hash := range hashes {
+		if known[string(hash)] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(hashes))
+}
+
+// dedupVersions returns random data and a copy with bytes inserted near the start
+func dedupVersions() ([]byte, []byte) {
+	before := make([]byte, 2*1024*1024)
+	rand.New(rand.NewSource(2)).Read(before)
+	after := append(append(append([]byte(nil), before[:100]...), bytes.Repeat([]byte{0xaa}, 37)...), before[100:]...)
+	return before, after
+}
+
+func TestContentDefinedChunkingDedup(t *testing.T) {
+	chunker, err := NewRabinChunker(1024, 4096, 16384)
+	if err != nil {
+		t.Fatalf("NewRabinChunker returned an error: %v", err)
+	}
+	before, after := dedupVersions()
+
+	// The insertion shifts every fixed-size chunk, but only disturbs the first content-defined chunks
+	fixed := sharedChunkFraction(t, []Option{WithBlockSize(4096)}, before, after)
+	cdc := sharedChunkFraction(t, []Option{WithChunker(chunker)}, before, after)
+	if fixed > 0.01 {
+		t.Errorf("Expected fixed-size chunks to share almost nothing after the insertion, got %.2f", fixed)
+	}
+	if cdc < 0.95 {
+		t.Errorf("Expected content-defined chunks to share at least 95%% after the insertion, got %.2f", cdc)
+	}
+}
+
+func BenchmarkChunkingDedup(b *testing.B) {
+	chunker, err := NewRabinChunker(1024, 4096, 16384)
+	if err != nil {
+		b.Fatal(err)
+	}
+	before, after := dedupVersions()
+	for name, opts := range map[string][]Option{
+		"fixed": {WithBlockSize(4096)},
+		"rabin": {WithChunker(chunker)},
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(before) + len(after)))
+			var shared float64
+			for i := 0; i < b.N; i++ {
+				shared = sharedChunkFraction(b, opts, before, after)
+			}
+			b.ReportMetric(100*shared, "%shared")
+		})
+	}
+}
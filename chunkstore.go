@@ -0,0 +1,175 @@
+package terrapin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+	"io"
+)
+
+// ErrChunkNotFound is returned by a ChunkStore when no hash is stored for the requested chunk
+var ErrChunkNotFound = errors.New("chunk hash not found")
+
+// ChunkStore provides the attested chunk hashes of a single file without requiring the
+// whole attestation blob to be loaded into memory
+type ChunkStore interface {
+	// NumChunks returns the number of chunk hashes held for the file
+	NumChunks() (int, error)
+	// ChunkHash returns the attested hash of the chunk at index, or ErrChunkNotFound
+	ChunkHash(index int) ([]byte, error)
+}
+
+// KV is a minimal key-value store, small enough to be implemented on top of bbolt, SQLite or similar databases
+type KV interface {
+	// Get returns the value stored under key, or nil if the key does not exist
+	Get(key []byte) ([]byte, error)
+	// Put stores value under key, replacing any existing value
+	Put(key, value []byte) error
+}
+
+// KVChunkStore is a ChunkStore that looks chunk hashes up in a KV by (fileID, chunkIndex)
+type KVChunkStore struct {
+	kv     KV     // Backing key-value store
+	fileID string // Identifier of the attested file within the store
+}
+
+// Key tags distinguishing the records stored for a file
+const (
+	kvTagCount = 'c' // Number of chunks
+	kvTagHash  = 'h' // Hash of a single chunk
+)
+
+// NewKVChunkStore returns a ChunkStore for fileID backed by kv
+func NewKVChunkStore(kv KV, fileID string) *KVChunkStore {
+	return &KVChunkStore{
+		kv:     kv,
+		fileID: fileID,
+	}
+}
+
+// key builds a store key; the file ID is length-prefixed so IDs of different files can never collide
+func (s *KVChunkStore) key(tag byte, index int) []byte {
+	key := binary.AppendUvarint(nil, uint64(len(s.fileID)))
+	key = append(key, s.fileID...)
+	key = append(key, tag)
+	if tag == kvTagHash {
+		key = binary.BigEndian.AppendUint64(key, uint64(index))
+	}
+	return key
+}
+
+// PutAttestations stores every chunk hash of the attestations blob along with the chunk count
+// Only attestations with the default settings are accepted, as VerifyChunkStore assumes them
+func (s *KVChunkStore) PutAttestations(attestations []byte) error {
+	t := newDefaultTerrapin()
+	attestations, err := t.parseHeader(attestations)
+	if err != nil {
+		return err
+	}
+	if t.needsHeader() {
+		return errors.New("chunk stores only support attestations with the default settings")
+	}
+
+	// Ensure the attestations length is a multiple of the digest size
+	if len(attestations)%t.digestSize() != 0 {
+		return &InvalidAttestationsError{Reason: "length is not a multiple of the digest size"}
+	}
+
+	count := len(attestations) / t.digestSize()
+	for i := 0; i < count; i++ {
+		hash := attestations[i*t.digestSize() : (i+1)*t.digestSize()]
+		if err := s.kv.Put(s.key(kvTagHash, i), hash); err != nil {
+			return fmt.Errorf("failed to store chunk %d: %w", i, err)
+		}
+	}
+
+	// Store the count last so a partially written file is never reported as complete
+	if err := s.kv.Put(s.key(kvTagCount, 0), binary.BigEndian.AppendUint64(nil, uint64(count))); err != nil {
+		return fmt.Errorf("failed to store chunk count: %w", err)
+	}
+	return nil
+}
+
+// NumChunks implements ChunkStore
+func (s *KVChunkStore) NumChunks() (int, error) {
+	value, err := s.kv.Get(s.key(kvTagCount, 0))
+	if err != nil {
+		return 0, err
+	}
+	if value == nil {
+		return 0, ErrChunkNotFound
+	}
+	if len(value) != 8 {
+		return 0, errors.New("invalid chunk count record")
+	}
+	return int(binary.BigEndian.Uint64(value)), nil
+}
+
+// ChunkHash implements ChunkStore
+func (s *KVChunkStore) ChunkHash(index int) ([]byte, error) {
+	if index < 0 {
+		return nil, ErrChunkNotFound
+	}
+	value, err := s.kv.Get(s.key(kvTagHash, index))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, ErrChunkNotFound
+	}
+	return value, nil
+}
+
+// VerifyChunkStore verifies the entire data stream from the reader against the chunk hashes in store
+// Hashes are fetched one chunk at a time, so memory use does not grow with the size of the attestations
+// The data is split into chunks of the default BufferCapacity size
+// Returns true if verification succeeds, false otherwise; data ending before the last stored chunk is reported
+// with a *TruncatedDataError
+func VerifyChunkStore(store ChunkStore, reader io.Reader) (bool, error) {
+	count, err := store.NumChunks()
+	if err != nil {
+		return false, err
+	}
+
+	// Buffer to read data in chunks
+	buffer := make([]byte, BufferCapacity)
+
+	for index := 0; ; index++ {
+		n, err := io.ReadFull(reader, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return false, err
+		}
+		if n == 0 {
+			if index < count {
+				return false, &TruncatedDataError{Chunk: index} // The data must cover every stored chunk
+			}
+			return true, nil
+		}
+		if index >= count {
+			return false, nil // More data than attested
+		}
+		if n < BufferCapacity && index < count-1 {
+			return false, &TruncatedDataError{Chunk: index, Length: n} // Only the last chunk may be short
+		}
+
+		computedHash, err := chunkHash(buffer[:n], gitoid.BLOB, SHA256)
+		if err != nil {
+			return false, err
+		}
+		expectedHash, err := store.ChunkHash(index)
+		if err != nil {
+			return false, err
+		}
+
+		// Compare the computed hash with the expected hash
+		if !bytes.Equal(computedHash, expectedHash) {
+			return false, nil // Hash mismatch
+		}
+
+		if n < BufferCapacity {
+			return true, nil // Only the last chunk may be short
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// mapKV is an in-memory KV used to exercise KVChunkStore
+type mapKV map[string][]byte
+
+func (m mapKV) Get(key []byte) ([]byte, error) {
+	return m[string(key)], nil
+}
+
+func (m mapKV) Put(key, value []byte) error {
+	m[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+func TestVerifyChunkStore_KV(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	other := make([]byte, BufferCapacity)
+
+	kv := mapKV{}
+	for fileID, content := range map[string][]byte{"data": data, "other": other} {
+		terrapin := NewTerrapin()
+		if err := terrapin.Add(content); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		_, attestations, err := terrapin.Finalize()
+		if err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		if err := NewKVChunkStore(kv, fileID).PutAttestations(attestations); err != nil {
+			t.Fatalf("Failed to store attestations: %v", err)
+		}
+	}
+
+	store := NewKVChunkStore(kv, "data")
+	count, err := store.NumChunks()
+	if err != nil {
+		t.Fatalf("NumChunks returned an error: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 chunks, got %d", count)
+	}
+
+	match, err := VerifyChunkStore(store, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyChunkStore returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyChunkStore expected to match, but it didn't")
+	}
+
+	// Each file is keyed independently
+	match, err = VerifyChunkStore(NewKVChunkStore(kv, "other"), bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyChunkStore returned an error: %v", err)
+	}
+	if match {
+		t.Fatalf("VerifyChunkStore expected to mismatch another file's hashes, but it matched")
+	}
+
+	// Corrupted data must not match
+	corrupt := append([]byte(nil), data...)
+	corrupt[2*BufferCapacity+7] ^= 0xff
+	match, err = VerifyChunkStore(store, bytes.NewReader(corrupt))
+	if err != nil {
+		t.Fatalf("VerifyChunkStore returned an error: %v", err)
+	}
+	if match {
+		t.Fatalf("VerifyChunkStore expected to mismatch, but it matched")
+	}
+
+	// Truncated data, ending on a chunk boundary or within a chunk, is reported as such
+	var truncated *TruncatedDataError
+	for length, chunk := range map[int]int{2 * BufferCapacity: 2, BufferCapacity + 7: 1} {
+		match, err = VerifyChunkStore(store, bytes.NewReader(data[:length]))
+		if match || !errors.As(err, &truncated) || truncated.Chunk != chunk {
+			t.Errorf("%d bytes: expected truncation at chunk %d, got %v, %v", length, chunk, match, err)
+		}
+	}
+}
+
+func TestKVChunkStore_Missing(t *testing.T) {
+	store := NewKVChunkStore(mapKV{}, "missing")
+	if _, err := store.NumChunks(); !errors.Is(err, ErrChunkNotFound) {
+		t.Errorf("Expected ErrChunkNotFound, got %v", err)
+	}
+	if _, err := store.ChunkHash(0); !errors.Is(err, ErrChunkNotFound) {
+		t.Errorf("Expected ErrChunkNotFound, got %v", err)
+	}
+}
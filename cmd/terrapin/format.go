@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Encodings of attestations files, chosen with the -format flags; the terrapin package itself only handles
+// the binary attestations, which the other encodings wrap for embedding in text
+const (
+	formatAuto   = "auto"   // Detect the encoding when reading
+	formatBinary = "binary" // Raw attestations bytes
+	formatHex    = "hex"    // Lowercase hex on a single line
+	formatBase64 = "base64" // Standard base64 on a single line
+)
+
+// encodeAttestations encodes the attestations in format, which must not be formatAuto
+// Text encodings end with a newline, as is usual for text files
+func encodeAttestations(attestations []byte, format string) ([]byte, error) {
+	switch format {
+	case formatBinary:
+		return attestations, nil
+	case formatHex:
+		return []byte(hex.EncodeToString(attestations) + "\n"), nil
+	case formatBase64:
+		return []byte(base64.StdEncoding.EncodeToString(attestations) + "\n"), nil
+	default:
+		return nil, fmt.Errorf("unknown attestations format %q, expected binary, hex or base64", format)
+	}
+}
+
+// decodeAttestations decodes attestations encoded in format, detecting the encoding for formatAuto
+// Surrounding whitespace is ignored in text encodings
+func decodeAttestations(data []byte, format string) ([]byte, error) {
+	if format == formatAuto {
+		format = detectFormat(data)
+	}
+	text := string(bytes.TrimSpace(data))
+	switch format {
+	case formatBinary:
+		return data, nil
+	case formatHex:
+		attestations, err := hex.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex attestations: %w", err)
+		}
+		return attestations, nil
+	case formatBase64:
+		attestations, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 attestations: %w", err)
+		}
+		return attestations, nil
+	default:
+		return nil, fmt.Errorf("unknown attestations format %q, expected auto, binary, hex or base64", format)
+	}
+}
+
+// detectFormat returns the encoding of an attestations file: text that decodes as hex or base64 is taken to be
+// encoded, as binary attestations are chunk hashes, optionally after a header, and all but never consist of
+// such text alone. A file holding only whitespace is the text encoding of the empty attestations of an empty file
+func detectFormat(data []byte) string {
+	text := string(bytes.TrimSpace(data))
+	if len(data) == 0 || bytes.HasPrefix(data, []byte("TRPN")) {
+		return formatBinary
+	}
+	if _, err := hex.DecodeString(text); err == nil {
+		return formatHex
+	}
+	if _, err := base64.StdEncoding.DecodeString(text); err == nil {
+		return formatBase64
+	}
+	return formatBinary
+}
+
+// readAttestations reads and decodes the attestations file at path, encoded in format
+func readAttestations(path, format string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAttestations(data, format)
+}
+
+// writeAttestations encodes the attestations in format and writes them to the file at path
+func writeAttestations(path string, attestations []byte, format string) error {
+	data, err := encodeAttestations(attestations, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -1,161 +1,511 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/fkautz/terrapin-go"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 )
 
-// blockSize is set to the buffer capacity defined in the terrapin package
+// blockSize is the default block size of the attest subcommand, the buffer capacity defined in the terrapin package
 const blockSize = terrapin.BufferCapacity
 
+// attestationsSuffix is appended to a file's path to name its attestations file by default
+const attestationsSuffix = ".terrapin"
+
+// Exit codes returned by the command-line tool
+const (
+	exitOK       = 0 // Success
+	exitFailure  = 1 // Usage, I/O or other operational error
+	exitMismatch = 2 // The data does not match its attestations
+)
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// stdioPath is the path naming standard input for -input and standard output for -output
+const stdioPath = "-"
+
+// run executes the subcommand named by args[0] and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 	// Ensure there is at least one argument provided (the subcommand)
-	if len(os.Args) < 2 {
-		fmt.Println("Expected 'attest', 'validate', or 'cat' subcommands")
-		os.Exit(1)
+	if len(args) < 1 {
+		fmt.Fprintln(stdout, "Expected 'attest', 'id', 'validate', 'cat', 'scrub', 'dump', or 'repair' subcommands")
+		return exitFailure
 	}
 
 	// Switch based on the first argument to determine which subcommand to execute
-	switch os.Args[1] {
+	switch args[0] {
 	case "attest":
 		// Setup and parse flags for the "attest" subcommand
-		attestCmd := flag.NewFlagSet("attest", flag.ExitOnError)
-		inputFile := attestCmd.String("input", "", "Input file path")
-		outputFile := attestCmd.String("output", "", "Output file path for terrapin attestations")
-		attestCmd.Parse(os.Args[2:])
+		attestCmd := flag.NewFlagSet("attest", flag.ContinueOnError)
+		attestCmd.SetOutput(stderr)
+		inputFile := attestCmd.String("input", "", "Input file path, or - for standard input")
+		inputList := attestCmd.String("input-list", "", "File listing input file paths, one per line")
+		outputFile := attestCmd.String("output", "", "Output file path for terrapin attestations, - for standard output, or for the manifest with -input-list")
+		format := attestCmd.String("format", formatBinary, "Encoding of the attestations written: binary, hex or base64")
+		settings := addAttestFlags(attestCmd)
+		if err := attestCmd.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
+		opts, err := settings.options()
+		if err != nil {
+			fmt.Fprintln(stdout, err)
+			attestCmd.Usage()
+			return exitFailure
+		}
+		if _, err := encodeAttestations(nil, *format); err != nil {
+			fmt.Fprintln(stdout, err)
+			attestCmd.Usage()
+			return exitFailure
+		}
+
+		// Attest every listed file if requested
+		if *inputList != "" {
+			if *inputFile != "" {
+				fmt.Fprintln(stdout, "The -input and -input-list flags cannot be combined")
+				attestCmd.Usage()
+				return exitFailure
+			}
+			if *outputFile == stdioPath {
+				fmt.Fprintln(stdout, "The manifest of -input-list cannot be written to standard output")
+				attestCmd.Usage()
+				return exitFailure
+			}
+			return processInputList(*inputList, *outputFile, *format, *settings.threads, opts, stdout, stderr)
+		}
 
 		// Ensure the input file path is provided
 		if *inputFile == "" {
-			fmt.Println("Input file path is required")
+			fmt.Fprintln(stdout, "Input file path or input list is required")
 			attestCmd.Usage()
-			os.Exit(1)
+			return exitFailure
 		}
 
 		// Process the input file and generate attestations
-		processInputFile(*inputFile, *outputFile)
+		return processInputFile(*inputFile, *outputFile, *format, *settings.threads, opts, stdin, stdout, stderr)
+
+	case "id":
+		// Setup and parse flags for the "id" subcommand
+		idCmd := flag.NewFlagSet("id", flag.ContinueOnError)
+		idCmd.SetOutput(stderr)
+		inputFile := idCmd.String("input", "", "Input file path, or - for standard input")
+		settings := addAttestFlags(idCmd)
+		if err := idCmd.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
+		opts, err := settings.options()
+		if err != nil {
+			fmt.Fprintln(stdout, err)
+			idCmd.Usage()
+			return exitFailure
+		}
+
+		// Ensure the input file path is provided
+		if *inputFile == "" {
+			fmt.Fprintln(stdout, "Input file path is required")
+			idCmd.Usage()
+			return exitFailure
+		}
+
+		// Print only the gitoid URI, without writing attestations
+		return printGitoid(*inputFile, *settings.threads, opts, stdin, stdout, stderr)
 
 	case "validate":
 		// Setup and parse flags for the "validate" subcommand
-		validateCmd := flag.NewFlagSet("validate", flag.ExitOnError)
+		validateCmd := flag.NewFlagSet("validate", flag.ContinueOnError)
+		validateCmd.SetOutput(stderr)
 		inputFile := validateCmd.String("input", "", "Input file path")
 		attestationsFile := validateCmd.String("attestations", "", "Attestations file path for verification")
 		start := validateCmd.Int64("start", 0, "Start byte for range")
 		end := validateCmd.Int64("end", -1, "End byte for range")
-		validateCmd.Parse(os.Args[2:])
+		all := validateCmd.Bool("all", false, "Report every mismatched chunk instead of stopping at the first")
+		format := validateCmd.String("format", formatAuto, "Encoding of the attestations file: auto, binary, hex or base64")
+		threads := validateCmd.Int("threads", runtime.NumCPU(), "Number of chunks hashed concurrently, 1 for the serial path")
+		if err := validateCmd.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
+		if *threads < 1 {
+			fmt.Fprintln(stdout, "Threads must be at least 1")
+			validateCmd.Usage()
+			return exitFailure
+		}
 
 		// Ensure both the input file path and attestations file path are provided
 		if *inputFile == "" || *attestationsFile == "" {
-			fmt.Println("Input file path and attestations file path are required")
+			fmt.Fprintln(stdout, "Input file path and attestations file path are required")
 			validateCmd.Usage()
-			os.Exit(1)
+			return exitFailure
+		}
+
+		// Report all mismatches over the whole file if requested
+		if *all {
+			if *start > 0 || *end > 0 {
+				fmt.Fprintln(stdout, "The -all flag cannot be combined with -start or -end")
+				validateCmd.Usage()
+				return exitFailure
+			}
+			return validateAll(*inputFile, *attestationsFile, *format, stdout, stderr)
 		}
 
 		// Validate the input file against the provided attestations
-		validate(*inputFile, *attestationsFile, *start, *end)
+		return validate(*inputFile, *attestationsFile, *format, *start, *end, *threads, stdout, stderr)
 
 	case "cat":
 		// Setup and parse flags for the "cat" subcommand
-		catCmd := flag.NewFlagSet("cat", flag.ExitOnError)
+		catCmd := flag.NewFlagSet("cat", flag.ContinueOnError)
+		catCmd.SetOutput(stderr)
 		inputFile := catCmd.String("input", "", "Input file path")
 		attestationsFile := catCmd.String("attestations", "", "Attestations file path for verification")
 		start := catCmd.Int64("start", 0, "Start byte for range")
 		end := catCmd.Int64("end", -1, "End byte for range")
-		catCmd.Parse(os.Args[2:])
+		format := catCmd.String("format", formatAuto, "Encoding of the attestations file: auto, binary, hex or base64")
+		if err := catCmd.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
 
 		// Ensure both the input file path and attestations file path are provided
 		if *inputFile == "" || *attestationsFile == "" {
-			fmt.Println("Input file path and attestations file path are required")
+			fmt.Fprintln(stdout, "Input file path and attestations file path are required")
 			catCmd.Usage()
-			os.Exit(1)
+			return exitFailure
 		}
 
 		// Verify the input file and echo its content if verification succeeds
-		cat(*inputFile, *attestationsFile, *start, *end)
+		return cat(*inputFile, *attestationsFile, *format, *start, *end, stdout, stderr)
+
+	case "scrub":
+		// Setup and parse flags for the "scrub" subcommand
+		scrubCmd := flag.NewFlagSet("scrub", flag.ContinueOnError)
+		scrubCmd.SetOutput(stderr)
+		dir := scrubCmd.String("dir", "", "Directory to scrub")
+		suffix := scrubCmd.String("suffix", attestationsSuffix, "Suffix appended to a file's path to find its attestations")
+		interval := scrubCmd.Duration("interval", 24*time.Hour, "Time between scrub passes")
+		once := scrubCmd.Bool("once", false, "Run a single scrub pass and exit")
+		if err := scrubCmd.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
+
+		// Ensure the directory is provided and the schedule is valid
+		if *dir == "" || *suffix == "" {
+			fmt.Fprintln(stdout, "Directory and attestations suffix are required")
+			scrubCmd.Usage()
+			return exitFailure
+		}
+		if *interval <= 0 {
+			fmt.Fprintln(stdout, "Interval must be positive")
+			scrubCmd.Usage()
+			return exitFailure
+		}
+
+		// Verify every attested file in the directory, once or on each tick
+		return scrub(*dir, *suffix, *interval, *once, stdout, stderr)
+
+	case "dump":
+		// Setup and parse flags for the "dump" subcommand
+		dumpCmd := flag.NewFlagSet("dump", flag.ContinueOnError)
+		dumpCmd.SetOutput(stderr)
+		attestationsFile := dumpCmd.String("attestations", "", "Attestations file path")
+		start := dumpCmd.Int("start", 0, "First chunk to print")
+		end := dumpCmd.Int("end", -1, "Chunk to stop before, defaults to the last chunk")
+		if err := dumpCmd.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
+
+		// Ensure the attestations file path is provided
+		if *attestationsFile == "" {
+			fmt.Fprintln(stdout, "Attestations file path is required")
+			dumpCmd.Usage()
+			return exitFailure
+		}
+
+		// Print the chunk table of the attestations
+		return dump(*attestationsFile, *start, *end, stdout, stderr)
+
+	case "repair":
+		// Setup and parse flags for the "repair" subcommand
+		repairCmd := flag.NewFlagSet("repair", flag.ContinueOnError)
+		repairCmd.SetOutput(stderr)
+		inputFile := repairCmd.String("input", "", "Input file path")
+		attestationsFile := repairCmd.String("attestations", "", "Attestations file path for verification")
+		mirror := repairCmd.String("mirror", "", "URL of a mirror of the file supporting range requests")
+		timeout := repairCmd.Duration("timeout", 30*time.Second, "Time limit for fetching each chunk from the mirror")
+		if err := repairCmd.Parse(args[1:]); err != nil {
+			return exitFailure
+		}
+
+		// Ensure the input file path, attestations file path and mirror are provided
+		if *inputFile == "" || *attestationsFile == "" || *mirror == "" {
+			fmt.Fprintln(stdout, "Input file path, attestations file path and mirror URL are required")
+			repairCmd.Usage()
+			return exitFailure
+		}
+		if *timeout <= 0 {
+			fmt.Fprintln(stdout, "Timeout must be positive")
+			repairCmd.Usage()
+			return exitFailure
+		}
+
+		// Replace mismatched chunks with verified chunks from the mirror
+		return repair(*inputFile, *attestationsFile, *mirror, *timeout, stdout, stderr)
+
+	case "test-vectors":
+		// Undocumented: emit the canonical conformance test vectors as JSON
+		vectors, err := terrapin.GenerateTestVectors()
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to generate test vectors: %v\n", err)
+			return exitFailure
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(vectors); err != nil {
+			fmt.Fprintf(stderr, "Failed to write test vectors: %v\n", err)
+			return exitFailure
+		}
+		return exitOK
 
 	default:
 		// Print an error message if the provided subcommand is not recognized
-		fmt.Println("Expected 'attest', 'validate', or 'cat' subcommands")
-		os.Exit(1)
+		fmt.Fprintln(stdout, "Expected 'attest', 'id', 'validate', 'cat', 'scrub', 'dump', or 'repair' subcommands")
+		return exitFailure
+	}
+}
+
+// attestSettings holds the flags choosing how the attest and id subcommands attest data
+type attestSettings struct {
+	threads   *int    // Number of chunks hashed concurrently
+	size      *int    // Size of each attested chunk in bytes
+	algorithm *string // Name of the hash algorithm
+}
+
+// addAttestFlags registers the flags choosing how data is attested on fs
+func addAttestFlags(fs *flag.FlagSet) attestSettings {
+	return attestSettings{
+		threads:   fs.Int("threads", runtime.NumCPU(), "Number of chunks hashed concurrently, 1 for the serial path"),
+		size:      fs.Int("block-size", blockSize, "Size of each attested chunk in bytes"),
+		algorithm: fs.String("algorithm", terrapin.SHA256.String(), "Hash algorithm of the gitoids: sha1, sha256 or sha512"),
+	}
+}
+
+// options validates the parsed flags and returns the attestation options they select
+func (s attestSettings) options() ([]terrapin.Option, error) {
+	if *s.threads < 1 {
+		return nil, errors.New("Threads must be at least 1")
+	}
+	if *s.size < terrapin.MinBlockSize || *s.size > terrapin.MaxBlockSize {
+		return nil, fmt.Errorf("Block size must be between %d and %d bytes", terrapin.MinBlockSize, terrapin.MaxBlockSize)
 	}
+	alg, err := terrapin.ParseAlgorithm(*s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return []terrapin.Option{terrapin.WithBlockSize(*s.size), terrapin.WithHashAlgorithm(alg)}, nil
+}
+
+// printGitoid attests the input file as processInputFile does, but only prints its gitoid URI
+func printGitoid(inputFile string, threads int, opts []terrapin.Option, stdin io.Reader, stdout, stderr io.Writer) int {
+	input, err := openInput(inputFile, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open input file: %v\n", err)
+		return exitFailure
+	}
+	defer input.Close()
+
+	gid, _, err := attestReader(input, threads, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to attest input file: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintln(stdout, gid)
+	return exitOK
 }
 
-// processInputFile reads the input file, processes it with Terrapin, and writes the attestations
-func processInputFile(inputFile, outputFile string) {
+// openInput opens the input file, or returns stdin when the path is stdioPath
+func openInput(path string, stdin io.Reader) (io.ReadCloser, error) {
+	if path == stdioPath {
+		return io.NopCloser(stdin), nil
+	}
+	return os.Open(path)
+}
+
+// processInputFile reads the input file, processes it with Terrapin, and writes the attestations encoded in format
+// With an output path of stdioPath the attestations are written to stdout, so the gitoid URI is printed to
+// stderr instead to keep stdout holding the attestations alone
+func processInputFile(inputFile, outputFile, format string, threads int, opts []terrapin.Option, stdin io.Reader, stdout, stderr io.Writer) int {
 	// Open the input file
-	file, err := os.Open(inputFile)
+	input, err := openInput(inputFile, stdin)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open input file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to open input file: %v\n", err)
+		return exitFailure
 	}
-	defer file.Close()
+	defer input.Close()
 
-	// Create a new Terrapin instance
-	terrapinInstance := terrapin.NewTerrapin()
-	buffer := make([]byte, blockSize)
+	// Attest the input file, reading ahead while blocks are hashed
+	gid, attestations, err := attestReader(input, threads, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to attest input file: %v\n", err)
+		return exitFailure
+	}
 
-	// Read the input file in chunks and add to the Terrapin instance
-	for {
-		n, err := file.Read(buffer)
-		if err != nil && err != io.EOF {
-			fmt.Fprintf(os.Stderr, "Failed to read input file: %v\n", err)
-			os.Exit(1)
+	// Write the attestations to standard output, leaving it to them alone
+	if outputFile == stdioPath {
+		encoded, err := encodeAttestations(attestations, format)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to encode attestations: %v\n", err)
+			return exitFailure
 		}
-		if n == 0 {
-			break
+		if _, err := stdout.Write(encoded); err != nil {
+			fmt.Fprintf(stderr, "Failed to write attestations to standard output: %v\n", err)
+			return exitFailure
 		}
+		fmt.Fprintln(stderr, "Gitoid URI:", gid)
+		return exitOK
+	}
 
-		err = terrapinInstance.Add(buffer[:n])
+	// Write the attestations to the output file if specified
+	if outputFile != "" {
+		err = writeAttestations(outputFile, attestations, format)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to add data to terrapin: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(stderr, "Failed to write attestations to output file: %v\n", err)
+			return exitFailure
 		}
 	}
 
-	// Finalize the Terrapin instance to generate the gitoid URI and attestations
-	gid, attestations, err := terrapinInstance.Finalize()
+	// Print the gitoid URI
+	fmt.Fprintln(stdout, "Gitoid URI:", gid)
+	return exitOK
+}
+
+// processInputList attests every file named in listFile, writing each file's attestations encoded in format
+// alongside it with the attestationsSuffix and, if manifestFile is set, a manifest of the gitoid URI and path of each file
+// Blank lines and lines starting with '#' are ignored; a file that fails is reported and the rest are still attested
+// Manifest paths use forward slashes and are sorted, so the same files produce the same manifest on any platform
+func processInputList(listFile, manifestFile, format string, threads int, opts []terrapin.Option, stdout, stderr io.Writer) int {
+	list, err := os.ReadFile(listFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to finalize terrapin: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to read input list: %v\n", err)
+		return exitFailure
 	}
 
-	// Write the attestations to the output file if specified
-	if outputFile != "" {
-		err = os.WriteFile(outputFile, attestations, 0644)
+	var entries []manifestEntry
+	attested, failed := 0, 0
+	for _, line := range strings.Split(string(list), "\n") {
+		path := strings.TrimSpace(line)
+		if path == "" || strings.HasPrefix(path, "#") {
+			continue
+		}
+
+		gid, err := attestFile(path, path+attestationsSuffix, format, threads, opts...)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to write attestations to output file: %v\n", err)
-			os.Exit(1)
+			failed++
+			fmt.Fprintf(stderr, "Failed to attest %s: %v\n", path, err)
+			continue
 		}
+		attested++
+		fmt.Fprintf(stdout, "%s: %s\n", path, gid)
+		entries = append(entries, manifestEntry{path: normalizePath(path, filepath.Separator), gid: gid})
 	}
 
-	// Print the gitoid URI
-	fmt.Println("Gitoid URI:", gid)
+	// Write the manifest of the successfully attested files if specified
+	if manifestFile != "" {
+		if err := os.WriteFile(manifestFile, formatManifest(entries), 0644); err != nil {
+			fmt.Fprintf(stderr, "Failed to write manifest: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	fmt.Fprintf(stdout, "Attested %d files: %d failed\n", attested, failed)
+	if failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// manifestEntry is the gitoid URI of a file listed in a manifest
+type manifestEntry struct {
+	path string // Path of the file, with forward slashes
+	gid  string // Gitoid URI of the file's attestations
+}
+
+// formatManifest returns the manifest listing entries sorted by path, one "gid  path" line each
+func formatManifest(entries []manifestEntry) []byte {
+	slices.SortStableFunc(entries, func(a, b manifestEntry) int {
+		return strings.Compare(a.path, b.path)
+	})
+	var manifest strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&manifest, "%s  %s\n", entry.gid, entry.path)
+	}
+	return []byte(manifest.String())
+}
+
+// normalizePath rewrites path, using the given separator, with forward slashes as on Unix
+func normalizePath(path string, separator byte) string {
+	if separator == '/' {
+		return path
+	}
+	return strings.ReplaceAll(path, string(separator), "/")
+}
+
+// attestFile attests the file at inputFile, writes its attestations encoded in format to outputFile, and returns
+// its gitoid URI
+func attestFile(inputFile, outputFile, format string, threads int, opts ...terrapin.Option) (string, error) {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	gid, attestations, err := attestReader(file, threads, opts...)
+	if err != nil {
+		return "", err
+	}
+	if err := writeAttestations(outputFile, attestations, format); err != nil {
+		return "", err
+	}
+	return gid, nil
+}
+
+// attestReader attests r with opts using the serial path for a single thread and the parallel path otherwise
+// Both produce identical attestations, so the thread count only affects speed
+func attestReader(r io.Reader, threads int, opts ...terrapin.Option) (string, []byte, error) {
+	if threads == 1 {
+		return terrapin.AttestReaderPipelined(r, opts...)
+	}
+	return terrapin.AttestReaderParallel(r, threads, opts...)
 }
 
 // validate verifies the file against the provided attestations
-func validate(filePath, attestationsPath string, start, end int64) {
+func validate(filePath, attestationsPath, format string, start, end int64, threads int, stdout, stderr io.Writer) int {
 	// Read the attestations file
-	attestations, err := os.ReadFile(attestationsPath)
+	attestations, err := readAttestations(attestationsPath, format)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to read attestations file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to read attestations file: %v\n", err)
+		return exitFailure
 	}
 
 	// Open the input file
 	file, err := os.Open(filePath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to open file: %v\n", err)
+		return exitFailure
 	}
 	defer file.Close()
 
 	// Create a new Terrapin instance with the provided attestations
-	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations)
+	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations, terrapin.WithEmptyAttestations())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create terrapin instance with attestations: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to create terrapin instance with attestations: %v\n", err)
+		return exitFailure
 	}
 
 	// Verify a specific range if start and/or end is specified
@@ -163,71 +513,144 @@ func validate(filePath, attestationsPath string, start, end int64) {
 		if end == -1 {
 			fi, err := file.Stat()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to stat file: %v\n", err)
-				os.Exit(1)
+				fmt.Fprintf(stderr, "Failed to stat file: %v\n", err)
+				return exitFailure
 			}
 			end = fi.Size()
 		}
 
-		// Align the start and end offsets to buffer boundaries
-		alignedStart := (start / blockSize) * blockSize
-		_, err = file.Seek(alignedStart, io.SeekStart)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to seek start position: %v\n", err)
-			os.Exit(1)
+		// Verify the chunks covering the specified range
+		if !attestedRange(terrapinInstance, end, stderr) {
+			return exitMismatch
 		}
-
-		// Verify the specified range
-		valid, err := terrapinInstance.VerifyBufferRange(file, int(alignedStart), int(end))
+		valid, err := terrapinInstance.VerifyRange(file, start, end)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to verify file: %v\n", err)
-			os.Exit(1)
+			return verifyError(err, stderr)
 		}
 		if !valid {
-			fmt.Fprintf(os.Stderr, "File verification failed\n")
-			os.Exit(1)
+			fmt.Fprintf(stderr, "File verification failed\n")
+			return exitMismatch
 		}
 
-		fmt.Println("File verification succeeded")
-		return
+		fmt.Fprintln(stdout, "File verification succeeded")
+		return exitOK
 	}
 
-	// Verify the entire file
-	valid, err := terrapinInstance.VerifyBuffer(file)
+	// Verify the entire file, using the serial path for a single thread
+	var valid bool
+	if threads == 1 {
+		valid, err = terrapinInstance.VerifyBuffer(file)
+	} else {
+		valid, err = terrapinInstance.VerifyBufferParallel(file, threads)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to verify file: %v\n", err)
-		os.Exit(1)
+		return verifyError(err, stderr)
 	}
 	if !valid {
-		fmt.Fprintf(os.Stderr, "File verification failed\n")
-		os.Exit(1)
+		fmt.Fprintf(stderr, "File verification failed\n")
+		return exitMismatch
+	}
+
+	fmt.Fprintln(stdout, "File verification succeeded")
+	return exitOK
+}
+
+// attestedRange reports whether a range ending at end lies within the attested data, reporting the failure if not
+func attestedRange(t *terrapin.Terrapin, end int64, stderr io.Writer) bool {
+	attested := t.VerifiablePrefix()
+	if end > attested {
+		fmt.Fprintf(stderr, "File verification failed: range ends beyond the %d attested bytes\n", attested)
+		return false
+	}
+	return true
+}
+
+// verifyError reports an error returned by verification and returns the exit code for it: truncated data and
+// mismatched chunks do not match their attestations, while any other error is operational
+func verifyError(err error, stderr io.Writer) int {
+	var truncated *terrapin.TruncatedDataError
+	var mismatch *terrapin.ChunkMismatchError
+	if errors.As(err, &truncated) || errors.As(err, &mismatch) {
+		fmt.Fprintf(stderr, "File verification failed: %v\n", err)
+		return exitMismatch
+	}
+	fmt.Fprintf(stderr, "Failed to verify file: %v\n", err)
+	return exitFailure
+}
+
+// validateAll verifies the whole file against the provided attestations and reports every mismatched chunk
+func validateAll(filePath, attestationsPath, format string, stdout, stderr io.Writer) int {
+	// Read the attestations file
+	attestations, err := readAttestations(attestationsPath, format)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read attestations file: %v\n", err)
+		return exitFailure
+	}
+
+	// Open the input file
+	file, err := os.Open(filePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open file: %v\n", err)
+		return exitFailure
+	}
+	defer file.Close()
+
+	// Create a new Terrapin instance with the provided attestations
+	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations, terrapin.WithEmptyAttestations())
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create terrapin instance with attestations: %v\n", err)
+		return exitFailure
+	}
+
+	// Verify the entire file, collecting every mismatched chunk
+	mismatches, err := terrapinInstance.VerifyAllMismatches(file)
+	if err != nil {
+		return verifyError(err, stderr)
+	}
+	if len(mismatches) > 0 {
+		fi, err := file.Stat()
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to stat file: %v\n", err)
+			return exitFailure
+		}
+		for _, index := range mismatches {
+			chunkStart, chunkEnd := terrapinInstance.ChunkByteRange(index)
+			if index >= terrapinInstance.NumChunks() {
+				chunkEnd = fi.Size() // Data beyond the attested chunks
+			}
+			chunkEnd = min(chunkEnd, fi.Size())
+			fmt.Fprintf(stdout, "Chunk %d mismatched: bytes %d-%d\n", index, chunkStart, chunkEnd-1)
+		}
+		fmt.Fprintf(stderr, "File verification failed: %d chunks mismatched\n", len(mismatches))
+		return exitMismatch
 	}
 
-	fmt.Println("File verification succeeded")
+	fmt.Fprintln(stdout, "File verification succeeded")
+	return exitOK
 }
 
-// cat reads the file and attestations, verifies the file, and echoes it if validation succeeds
-func cat(filePath, attestationsPath string, start, end int64) {
+// cat reads the file and attestations and echoes the file, or the requested range of it, once it verifies
+func cat(filePath, attestationsPath, format string, start, end int64, stdout, stderr io.Writer) int {
 	// Read the attestations file
-	attestations, err := os.ReadFile(attestationsPath)
+	attestations, err := readAttestations(attestationsPath, format)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to read attestations file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to read attestations file: %v\n", err)
+		return exitFailure
 	}
 
 	// Open the input file
 	file, err := os.Open(filePath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to open file: %v\n", err)
+		return exitFailure
 	}
 	defer file.Close()
 
 	// Create a new Terrapin instance with the provided attestations
-	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations)
+	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations, terrapin.WithEmptyAttestations())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create terrapin instance with attestations: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to create terrapin instance with attestations: %v\n", err)
+		return exitFailure
 	}
 
 	// Verify a specific range if start and/or end is specified
@@ -235,67 +658,69 @@ func cat(filePath, attestationsPath string, start, end int64) {
 		if end == -1 {
 			fi, err := file.Stat()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to stat file: %v\n", err)
-				os.Exit(1)
+				fmt.Fprintf(stderr, "Failed to stat file: %v\n", err)
+				return exitFailure
 			}
 			end = fi.Size()
 		}
 
-		// Align the start and end offsets to buffer boundaries
-		alignedStart := (start / blockSize) * blockSize
-		alignedEnd := ((end + blockSize - 1) / blockSize) * blockSize
-		_, err = file.Seek(alignedStart, io.SeekStart)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to seek start position: %v\n", err)
-			os.Exit(1)
+		// Bytes past the attested chunks cannot be verified, so they must not be echoed either
+		if !attestedRange(terrapinInstance, end, stderr) {
+			return exitMismatch
 		}
 
-		// Verify the specified range
-		valid, err := terrapinInstance.VerifyBufferRange(file, int(alignedStart), int(alignedEnd))
+		// Verify the chunks covering the specified range
+		valid, err := terrapinInstance.VerifyRange(file, start, end)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to verify file: %v\n", err)
-			os.Exit(1)
+			return verifyError(err, stderr)
 		}
 		if !valid {
-			fmt.Fprintf(os.Stderr, "File verification failed\n")
-			os.Exit(1)
+			fmt.Fprintf(stderr, "File verification failed\n")
+			return exitMismatch
 		}
 
 		// Seek to the start position and echo the file content
 		_, err = file.Seek(start, io.SeekStart)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to reset file reader: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(stderr, "Failed to reset file reader: %v\n", err)
+			return exitFailure
 		}
 
-		if _, err := io.CopyN(os.Stdout, file, end-start); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to echo file contents: %v\n", err)
-			os.Exit(1)
+		if _, err := io.CopyN(stdout, file, end-start); err != nil {
+			fmt.Fprintf(stderr, "Failed to echo file contents: %v\n", err)
+			return exitFailure
 		}
 
-		return
+		return exitOK
 	}
 
-	// Verify the entire file
-	valid, err := terrapinInstance.VerifyBuffer(file)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to verify file: %v\n", err)
-		os.Exit(1)
+	// Verify the entire file while echoing it, so it is only read once; each chunk is echoed once verified
+	if _, err := io.Copy(stdout, terrapinInstance.VerifyingReader(file)); err != nil {
+		return verifyError(err, stderr)
 	}
-	if !valid {
-		fmt.Fprintf(os.Stderr, "File verification failed\n")
-		os.Exit(1)
+
+	return exitOK
+}
+
+// dump prints the index, byte range and digest of each chunk in the requested range of the attestations
+func dump(attestationsPath string, start, end int, stdout, stderr io.Writer) int {
+	// Read the attestations file
+	attestations, err := readAttestations(attestationsPath, formatAuto)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read attestations file: %v\n", err)
+		return exitFailure
 	}
 
-	// Reset file reader and echo the file content
-	_, err = file.Seek(0, io.SeekStart)
+	// Create a new Terrapin instance with the provided attestations
+	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations, terrapin.WithEmptyAttestations())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to reset file reader: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Failed to create terrapin instance with attestations: %v\n", err)
+		return exitFailure
 	}
 
-	if _, err := io.Copy(os.Stdout, file); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to echo file contents: %v\n", err)
-		os.Exit(1)
+	if err := terrapinInstance.DumpAttestations(stdout, start, end); err != nil {
+		fmt.Fprintf(stderr, "Failed to dump attestations: %v\n", err)
+		return exitFailure
 	}
+	return exitOK
 }
@@ -0,0 +1,738 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes patterned data of the given size into dir and returns its path and content
+func writeTestFile(t *testing.T, dir, name string, size int) (string, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	return path, data
+}
+
+// runCLI runs the command-line tool with args and returns its exit code and output
+func runCLI(args ...string) (int, string, string) {
+	return runCLIWithInput(nil, args...)
+}
+
+// runCLIWithInput runs the CLI like runCLI, with stdin as its standard input
+func runCLIWithInput(stdin []byte, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, bytes.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestValidateAllReportsEveryMismatch(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 4*blockSize+10)
+	attestations := filepath.Join(dir, "input.attestations")
+
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+	if code, _, stderr := runCLI("validate", "-all", "-input", input, "-attestations", attestations); code != exitOK {
+		t.Fatalf("validate -all exited with %d: %s", code, stderr)
+	}
+
+	// Corrupt the first, third and final (short) chunks
+	data[1] ^= 0xff
+	data[2*blockSize+1] ^= 0xff
+	data[4*blockSize+1] ^= 0xff
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+
+	// Without -all verification stops at the first failure
+	if code, _, _ := runCLI("validate", "-input", input, "-attestations", attestations); code != exitMismatch {
+		t.Fatalf("Expected validate to exit with %d, got %d", exitMismatch, code)
+	}
+
+	code, stdout, _ := runCLI("validate", "-all", "-input", input, "-attestations", attestations)
+	if code != exitMismatch {
+		t.Fatalf("Expected validate -all to exit with %d, got %d", exitMismatch, code)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	expected := []string{
+		"Chunk 0 mismatched: bytes 0-2097151",
+		"Chunk 2 mismatched: bytes 4194304-6291455",
+		"Chunk 4 mismatched: bytes 8388608-8388617",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d reported chunks, got %q", len(expected), stdout)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], lines[i])
+		}
+	}
+}
+
+func TestScrubOnceReportsCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	good, _ := writeTestFile(t, dir, "good.bin", 2*blockSize+10)
+	bad, data := writeTestFile(t, filepath.Join(dir, "nested"), "bad.bin", blockSize+10)
+	writeTestFile(t, dir, "unattested.bin", 10)
+
+	for _, input := range []string{good, bad} {
+		if code, _, stderr := runCLI("attest", "-input", input, "-output", input+".terrapin"); code != exitOK {
+			t.Fatalf("attest exited with %d: %s", code, stderr)
+		}
+	}
+	if code, stdout, stderr := runCLI("scrub", "-dir", dir, "-once"); code != exitOK {
+		t.Fatalf("scrub exited with %d: %s%s", code, stdout, stderr)
+	}
+
+	data[blockSize+1] ^= 0xff
+	if err := os.WriteFile(bad, data, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+
+	code, stdout, stderr := runCLI("scrub", "-dir", dir, "-once")
+	if code != exitMismatch {
+		t.Fatalf("Expected scrub to exit with %d, got %d", exitMismatch, code)
+	}
+	if strings.TrimSpace(stdout) != "Scrubbed 2 files: 1 failed" {
+		t.Errorf("Unexpected scrub summary %q", stdout)
+	}
+	if !strings.Contains(stderr, bad) || strings.Contains(stderr, good) {
+		t.Errorf("Expected only %s to be reported, got %q", bad, stderr)
+	}
+}
+
+func TestAttestInputList(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := writeTestFile(t, dir, "first.bin", blockSize+10)
+	second, _ := writeTestFile(t, dir, "second.bin", 10)
+	missing := filepath.Join(dir, "missing.bin")
+	list := filepath.Join(dir, "inputs.txt")
+	content := "# artifacts\n" + first + "\n\n  " + missing + "\n" + second + "\n"
+	if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write input list: %v", err)
+	}
+	manifest := filepath.Join(dir, "manifest.txt")
+
+	code, stdout, stderr := runCLI("attest", "-input-list", list, "-output", manifest)
+	if code != exitFailure {
+		t.Fatalf("Expected attest to exit with %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(stderr, missing) {
+		t.Errorf("Expected %s to be reported, got %q", missing, stderr)
+	}
+	if !strings.Contains(stdout, "Attested 2 files: 1 failed") {
+		t.Errorf("Unexpected summary %q", stdout)
+	}
+
+	// Each attested file verifies against its own attestations and appears in the manifest
+	written, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(written)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 manifest entries, got %q", written)
+	}
+	for i, input := range []string{first, second} {
+		if !strings.HasSuffix(lines[i], "  "+input) || !strings.HasPrefix(lines[i], "gitoid:blob:sha256:") {
+			t.Errorf("Unexpected manifest entry %q", lines[i])
+		}
+		if code, _, stderr := runCLI("validate", "-input", input, "-attestations", input+attestationsSuffix); code != exitOK {
+			t.Errorf("validate %s exited with %d: %s", input, code, stderr)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	dir := t.TempDir()
+	input, _ := writeTestFile(t, dir, "input.bin", 4*blockSize+10)
+	attestations := filepath.Join(dir, "input.attestations")
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		rows int
+	}{
+		{nil, 5},
+		{[]string{"-start", "1", "-end", "3"}, 2},
+	} {
+		code, stdout, stderr := runCLI(append([]string{"dump", "-attestations", attestations}, tc.args...)...)
+		if code != exitOK {
+			t.Fatalf("dump %v exited with %d: %s", tc.args, code, stderr)
+		}
+		if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != tc.rows+1 {
+			t.Errorf("dump %v: expected %d rows, got %q", tc.args, tc.rows, stdout)
+		}
+	}
+}
+
+func TestThreadsProduceIdenticalResults(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 5*blockSize+10)
+
+	var outputs []string
+	var blobs [][]byte
+	for _, threads := range []string{"1", "4"} {
+		attestations := filepath.Join(dir, "input.attestations."+threads)
+		code, stdout, stderr := runCLI("attest", "-threads", threads, "-input", input, "-output", attestations)
+		if code != exitOK {
+			t.Fatalf("attest -threads %s exited with %d: %s", threads, code, stderr)
+		}
+		blob, err := os.ReadFile(attestations)
+		if err != nil {
+			t.Fatalf("Failed to read attestations: %v", err)
+		}
+		outputs = append(outputs, stdout)
+		blobs = append(blobs, blob)
+
+		if code, _, stderr := runCLI("validate", "-threads", threads, "-input", input, "-attestations", attestations); code != exitOK {
+			t.Fatalf("validate -threads %s exited with %d: %s", threads, code, stderr)
+		}
+	}
+	if outputs[0] != outputs[1] || !bytes.Equal(blobs[0], blobs[1]) {
+		t.Errorf("Expected identical attestations for -threads 1 and 4, got %q and %q", outputs[0], outputs[1])
+	}
+
+	// Both paths detect corruption
+	data[3*blockSize+1] ^= 0xff
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+	for _, threads := range []string{"1", "4"} {
+		attestations := filepath.Join(dir, "input.attestations."+threads)
+		if code, _, _ := runCLI("validate", "-threads", threads, "-input", input, "-attestations", attestations); code != exitMismatch {
+			t.Errorf("Expected validate -threads %s to exit with %d, got %d", threads, exitMismatch, code)
+		}
+	}
+}
+
+func TestRepairFetchesBadChunksFromMirror(t *testing.T) {
+	dir := t.TempDir()
+	input, original := writeTestFile(t, dir, "input.bin", 3*blockSize+10)
+	attestations := input + ".terrapin"
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "input.bin", time.Time{}, bytes.NewReader(original))
+	}))
+	defer mirror.Close()
+
+	// Corrupt the first chunk and the short last chunk, and append data beyond the end
+	corrupted := append([]byte(nil), original...)
+	corrupted[1] ^= 0xff
+	corrupted[3*blockSize+5] ^= 0xff
+	corrupted = append(corrupted, "trailing"...)
+	if err := os.WriteFile(input, corrupted, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+
+	code, stdout, stderr := runCLI("repair", "-input", input, "-attestations", attestations, "-mirror", mirror.URL)
+	if code != exitOK {
+		t.Fatalf("repair exited with %d: %s%s", code, stdout, stderr)
+	}
+	if strings.TrimSpace(stdout) != "Repaired 2 chunks" {
+		t.Errorf("Unexpected repair summary %q", stdout)
+	}
+	repaired, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("Failed to read repaired input: %v", err)
+	}
+	if !bytes.Equal(repaired, original) {
+		t.Errorf("Repaired file does not match the original")
+	}
+	if code, _, stderr := runCLI("validate", "-input", input, "-attestations", attestations); code != exitOK {
+		t.Errorf("validate exited with %d after repair: %s", code, stderr)
+	}
+}
+
+func TestRepairTruncatesTrailingData(t *testing.T) {
+	for name, size := range map[string]int{"short last chunk": 2*blockSize + 100, "full last chunk": 2 * blockSize} {
+		dir := t.TempDir()
+		input, original := writeTestFile(t, dir, "input.bin", size)
+		attestations := input + ".terrapin"
+		if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+			t.Fatalf("%s: attest exited with %d: %s", name, code, stderr)
+		}
+		mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "input.bin", time.Time{}, bytes.NewReader(original))
+		}))
+		defer mirror.Close()
+
+		// Append more than a block of junk, so the data beyond the attested chunks spans a whole chunk
+		corrupted := append([]byte(nil), original...)
+		corrupted = append(corrupted, bytes.Repeat([]byte{0xaa}, blockSize+5)...)
+		if err := os.WriteFile(input, corrupted, 0644); err != nil {
+			t.Fatalf("%s: Failed to corrupt input: %v", name, err)
+		}
+
+		code, stdout, stderr := runCLI("repair", "-input", input, "-attestations", attestations, "-mirror", mirror.URL)
+		if code != exitOK {
+			t.Fatalf("%s: repair exited with %d: %s%s", name, code, stdout, stderr)
+		}
+		repaired, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatalf("%s: Failed to read repaired input: %v", name, err)
+		}
+		if !bytes.Equal(repaired, original) {
+			t.Errorf("%s: Repaired file of %d bytes does not match the original of %d", name, len(repaired), len(original))
+		}
+		if code, _, stderr := runCLI("validate", "-input", input, "-attestations", attestations); code != exitOK {
+			t.Errorf("%s: validate exited with %d after repair: %s", name, code, stderr)
+		}
+	}
+}
+
+func TestRepairRestoresTruncatedFile(t *testing.T) {
+	dir := t.TempDir()
+	input, original := writeTestFile(t, dir, "input.bin", 3*blockSize+10)
+	attestations := input + ".terrapin"
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "input.bin", time.Time{}, bytes.NewReader(original))
+	}))
+	defer mirror.Close()
+
+	// The file ends partway through the second chunk, so it and every chunk after it are fetched
+	if err := os.WriteFile(input, original[:blockSize+7], 0644); err != nil {
+		t.Fatalf("Failed to truncate input: %v", err)
+	}
+	code, stdout, stderr := runCLI("repair", "-input", input, "-attestations", attestations, "-mirror", mirror.URL)
+	if code != exitOK {
+		t.Fatalf("repair exited with %d: %s%s", code, stdout, stderr)
+	}
+	if strings.TrimSpace(stdout) != "Repaired 3 chunks" {
+		t.Errorf("Unexpected repair summary %q", stdout)
+	}
+	repaired, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("Failed to read repaired input: %v", err)
+	}
+	if !bytes.Equal(repaired, original) {
+		t.Errorf("Repaired file of %d bytes does not match the original of %d", len(repaired), len(original))
+	}
+}
+
+func TestRepairTimesOutStalledMirror(t *testing.T) {
+	dir := t.TempDir()
+	input, original := writeTestFile(t, dir, "input.bin", 2*blockSize)
+	attestations := input + ".terrapin"
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	corrupted := append([]byte(nil), original...)
+	corrupted[blockSize] ^= 0xff
+	if err := os.WriteFile(input, corrupted, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+
+	// The mirror never responds, until the client gives up on the request
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer mirror.Close()
+
+	code, stdout, stderr := runCLI("repair", "-input", input, "-attestations", attestations, "-mirror", mirror.URL, "-timeout", "100ms")
+	if code != exitMismatch {
+		t.Fatalf("Expected repair to exit with %d, got %d: %s%s", exitMismatch, code, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "Failed to fetch chunk 1") {
+		t.Errorf("Expected the stalled fetch to be reported, got %q", stderr)
+	}
+	if code, _, _ := runCLI("repair", "-input", input, "-attestations", attestations, "-mirror", mirror.URL, "-timeout", "0s"); code != exitFailure {
+		t.Errorf("Expected repair to reject a zero timeout, got %d", code)
+	}
+}
+
+func TestRepairRejectsBadMirrorChunks(t *testing.T) {
+	dir := t.TempDir()
+	input, original := writeTestFile(t, dir, "input.bin", 2*blockSize)
+	attestations := input + ".terrapin"
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	corrupted := append([]byte(nil), original...)
+	corrupted[blockSize] ^= 0xff
+	if err := os.WriteFile(input, corrupted, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+
+	// The mirror serves the same corrupted data
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "input.bin", time.Time{}, bytes.NewReader(corrupted))
+	}))
+	defer mirror.Close()
+
+	code, stdout, stderr := runCLI("repair", "-input", input, "-attestations", attestations, "-mirror", mirror.URL)
+	if code != exitMismatch {
+		t.Fatalf("Expected repair to exit with %d, got %d: %s%s", exitMismatch, code, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "Chunk 1 from the mirror does not match") {
+		t.Errorf("Expected the bad mirror chunk to be reported, got %q", stderr)
+	}
+	unchanged, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("Failed to read input: %v", err)
+	}
+	if !bytes.Equal(unchanged, corrupted) {
+		t.Errorf("Expected the file to be left unchanged")
+	}
+}
+
+func TestAttestBlockSize(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 4*1024+10)
+	attestations := input + attestationsSuffix
+
+	if code, _, stderr := runCLI("attest", "-block-size", "1024", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+	if code, _, _ := runCLI("attest", "-block-size", "0", "-input", input); code != exitFailure {
+		t.Errorf("Expected attest to reject a zero block size, got %d", code)
+	}
+
+	// Range verification and mismatch reports use the block size recorded in the attestations
+	data[2*1024+1] ^= 0xff
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+	if code, _, stderr := runCLI("validate", "-input", input, "-attestations", attestations, "-start", "3000", "-end", "3072"); code != exitMismatch {
+		t.Errorf("Expected validate of the corrupt chunk to exit with %d, got %d: %s", exitMismatch, code, stderr)
+	}
+	if code, _, stderr := runCLI("validate", "-input", input, "-attestations", attestations, "-start", "3072", "-end", "4096"); code != exitOK {
+		t.Errorf("Expected validate of an intact chunk to exit with %d, got %d: %s", exitOK, code, stderr)
+	}
+	code, stdout, _ := runCLI("validate", "-all", "-input", input, "-attestations", attestations)
+	if code != exitMismatch {
+		t.Fatalf("Expected validate -all to exit with %d, got %d", exitMismatch, code)
+	}
+	if strings.TrimSpace(stdout) != "Chunk 2 mismatched: bytes 2048-3071" {
+		t.Errorf("Unexpected mismatch report %q", stdout)
+	}
+}
+
+func TestValidateTruncatedFile(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 2*blockSize+10)
+	attestations := input + attestationsSuffix
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	if err := os.WriteFile(input, data[:blockSize+10], 0644); err != nil {
+		t.Fatalf("Failed to truncate input: %v", err)
+	}
+	code, _, stderr := runCLI("validate", "-input", input, "-attestations", attestations)
+	if code != exitMismatch {
+		t.Fatalf("Expected validate to exit with %d, got %d: %s", exitMismatch, code, stderr)
+	}
+	if !strings.Contains(stderr, "truncated") {
+		t.Errorf("Expected the truncation to be reported, got %q", stderr)
+	}
+
+	// Data ending on a chunk boundary is truncated too, whichever verification path is used
+	for _, length := range []int{0, blockSize} {
+		if err := os.WriteFile(input, data[:length], 0644); err != nil {
+			t.Fatalf("Failed to truncate input: %v", err)
+		}
+		for _, threads := range []string{"1", "4"} {
+			code, _, stderr := runCLI("validate", "-threads", threads, "-input", input, "-attestations", attestations)
+			if code != exitMismatch || !strings.Contains(stderr, "truncated") {
+				t.Errorf("%d bytes, %s threads: expected a truncation and exit code %d, got %d: %s", length, threads, exitMismatch, code, stderr)
+			}
+		}
+		code, _, stderr := runCLI("validate", "-all", "-input", input, "-attestations", attestations)
+		if code != exitMismatch || !strings.Contains(stderr, "truncated") {
+			t.Errorf("%d bytes, -all: expected a truncation and exit code %d, got %d: %s", length, exitMismatch, code, stderr)
+		}
+	}
+}
+
+func TestAttestAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	input, _ := writeTestFile(t, dir, "input.bin", blockSize+10)
+	attestations := input + attestationsSuffix
+
+	code, stdout, stderr := runCLI("attest", "-algorithm", "sha1", "-input", input, "-output", attestations)
+	if code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(stdout), "Gitoid URI: gitoid:blob:sha1:") {
+		t.Errorf("Expected a SHA-1 gitoid URI, got %q", stdout)
+	}
+	if code, _, stderr := runCLI("validate", "-input", input, "-attestations", attestations); code != exitOK {
+		t.Errorf("validate exited with %d: %s", code, stderr)
+	}
+	if code, _, _ := runCLI("attest", "-algorithm", "md5", "-input", input); code != exitFailure {
+		t.Errorf("Expected attest to reject an unknown algorithm, got %d", code)
+	}
+}
+
+func TestEmptyFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	input, _ := writeTestFile(t, dir, "empty.bin", 0)
+	attestations := input + attestationsSuffix
+	if code, _, stderr := runCLI("attest", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	for _, args := range [][]string{{"validate"}, {"validate", "-all"}, {"validate", "-threads", "1"}} {
+		if code, _, stderr := runCLI(append(args, "-input", input, "-attestations", attestations)...); code != exitOK {
+			t.Errorf("%v: expected an empty file to verify, got %d: %s", args, code, stderr)
+		}
+	}
+	if code, stdout, stderr := runCLI("cat", "-input", input, "-attestations", attestations); code != exitOK || stdout != "" {
+		t.Errorf("Expected cat of an empty file to print nothing, got %d: %q %s", code, stdout, stderr)
+	}
+
+	// Text encodings of the empty attestations are a bare newline
+	for _, format := range []string{formatHex, formatBase64} {
+		encoded := input + "." + format
+		if code, _, stderr := runCLI("attest", "-format", format, "-input", input, "-output", encoded); code != exitOK {
+			t.Fatalf("%s: attest exited with %d: %s", format, code, stderr)
+		}
+		if code, _, stderr := runCLI("validate", "-input", input, "-attestations", encoded); code != exitOK {
+			t.Errorf("%s: expected an empty file to verify, got %d: %s", format, code, stderr)
+		}
+	}
+
+	// The attestations of an empty file verify nothing else
+	other, _ := writeTestFile(t, dir, "other.bin", 10)
+	if code, _, stderr := runCLI("validate", "-input", other, "-attestations", attestations); code != exitMismatch {
+		t.Errorf("Expected validate of a non-empty file to exit with %d, got %d: %s", exitMismatch, code, stderr)
+	}
+}
+
+func TestID(t *testing.T) {
+	dir := t.TempDir()
+	input, _ := writeTestFile(t, dir, "input.bin", 3*1024+100)
+
+	code, stdout, stderr := runCLI("attest", "-block-size", "1024", "-input", input)
+	if code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+	gid := strings.TrimPrefix(strings.TrimSpace(stdout), "Gitoid URI: ")
+
+	// Only the gitoid URI is printed, and no attestations are written
+	code, stdout, stderr = runCLI("id", "-block-size", "1024", "-input", input)
+	if code != exitOK {
+		t.Fatalf("id exited with %d: %s", code, stderr)
+	}
+	if stdout != gid+"\n" {
+		t.Errorf("Expected id to print %q, got %q", gid+"\n", stdout)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected no files to be written, got %v, %v", entries, err)
+	}
+
+	if code, _, _ := runCLI("id"); code != exitFailure {
+		t.Errorf("Expected id without an input to fail, got %d", code)
+	}
+	if code, _, _ := runCLI("id", "-input", filepath.Join(dir, "missing")); code != exitFailure {
+		t.Errorf("Expected id of a missing file to fail, got %d", code)
+	}
+}
+
+func TestAttestStdio(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 3*1024+100)
+	attestations := input + attestationsSuffix
+	code, stdout, stderr := runCLI("attest", "-block-size", "1024", "-input", input, "-output", attestations)
+	if code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+	expected, err := os.ReadFile(attestations)
+	if err != nil {
+		t.Fatalf("Failed to read attestations: %v", err)
+	}
+
+	// Standard input is attested like the file, with the gitoid URI printed to stdout
+	code, stdinStdout, stderr := runCLIWithInput(data, "attest", "-block-size", "1024", "-input", "-")
+	if code != exitOK {
+		t.Fatalf("attest from stdin exited with %d: %s", code, stderr)
+	}
+	if stdinStdout != stdout {
+		t.Errorf("Expected %q, got %q", stdout, stdinStdout)
+	}
+
+	// With -output -, stdout holds only the attestations and the gitoid URI moves to stderr
+	code, blob, stderr := runCLIWithInput(data, "attest", "-block-size", "1024", "-input", "-", "-output", "-")
+	if code != exitOK {
+		t.Fatalf("attest to stdout exited with %d: %s", code, stderr)
+	}
+	if blob != string(expected) {
+		t.Errorf("Expected stdout to hold the %d bytes of attestations, got %d", len(expected), len(blob))
+	}
+	if stderr != stdout {
+		t.Errorf("Expected the gitoid URI %q on stderr, got %q", stdout, stderr)
+	}
+
+	if code, stdout, _ := runCLIWithInput(data, "id", "-block-size", "1024", "-input", "-"); code != exitOK ||
+		"Gitoid URI: "+stdout != stderr {
+		t.Errorf("Expected id to read stdin, got %d with %q", code, stdout)
+	}
+	if code, _, _ := runCLI("attest", "-input-list", input, "-output", "-"); code != exitFailure {
+		t.Errorf("Expected a manifest on stdout to be rejected, got %d", code)
+	}
+}
+
+func TestAttestationsFormat(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 3*1024+100)
+
+	for _, format := range []string{formatBinary, formatHex, formatBase64} {
+		attestations := filepath.Join(dir, "input."+format)
+		if code, _, stderr := runCLI("attest", "-block-size", "1024", "-format", format, "-input", input, "-output", attestations); code != exitOK {
+			t.Fatalf("%s: attest exited with %d: %s", format, code, stderr)
+		}
+
+		// The encoding is detected, or may be given explicitly
+		for _, readFormat := range []string{formatAuto, format} {
+			if code, _, stderr := runCLI("validate", "-format", readFormat, "-input", input, "-attestations", attestations); code != exitOK {
+				t.Errorf("%s: validate with -format %s exited with %d: %s", format, readFormat, code, stderr)
+			}
+		}
+		code, stdout, stderr := runCLI("cat", "-input", input, "-attestations", attestations)
+		if code != exitOK || stdout != string(data) {
+			t.Errorf("%s: cat exited with %d: %s", format, code, stderr)
+		}
+	}
+
+	// Text encodings hold a single line, so they can be embedded in text configs
+	encoded, err := os.ReadFile(filepath.Join(dir, "input.base64"))
+	if err != nil {
+		t.Fatalf("Failed to read attestations: %v", err)
+	}
+	if strings.Count(string(encoded), "\n") != 1 || !strings.HasSuffix(string(encoded), "\n") {
+		t.Errorf("Expected a single line of base64, got %q", encoded)
+	}
+
+	if code, _, _ := runCLI("validate", "-format", formatHex, "-input", input, "-attestations", filepath.Join(dir, "input.base64")); code != exitFailure {
+		t.Errorf("Expected validate with the wrong -format to fail, got %d", code)
+	}
+	if code, _, _ := runCLI("attest", "-format", "yaml", "-input", input); code != exitFailure {
+		t.Errorf("Expected attest to reject an unknown format, got %d", code)
+	}
+}
+
+func TestCatFinalRange(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 3*1024+100)
+	attestations := input + attestationsSuffix
+	if code, _, stderr := runCLI("attest", "-block-size", "1024", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	// The final range ends inside the short last chunk
+	code, stdout, stderr := runCLI("cat", "-input", input, "-attestations", attestations, "-start", "3000")
+	if code != exitOK {
+		t.Fatalf("cat exited with %d: %s", code, stderr)
+	}
+	if stdout != string(data[3000:]) {
+		t.Errorf("Expected cat to echo the final %d bytes, got %d", len(data)-3000, len(stdout))
+	}
+
+	// Bytes appended after attestation are not covered by it
+	if err := os.WriteFile(input, append(data, make([]byte, 2048)...), 0644); err != nil {
+		t.Fatalf("Failed to extend input: %v", err)
+	}
+	code, stdout, _ = runCLI("cat", "-input", input, "-attestations", attestations, "-start", "3000")
+	if code != exitMismatch || stdout != "" {
+		t.Errorf("Expected cat of unattested bytes to exit with %d and no output, got %d with %d bytes", exitMismatch, code, len(stdout))
+	}
+}
+
+func TestCatMismatch(t *testing.T) {
+	dir := t.TempDir()
+	input, data := writeTestFile(t, dir, "input.bin", 3*1024+100)
+	attestations := input + attestationsSuffix
+	if code, _, stderr := runCLI("attest", "-block-size", "1024", "-input", input, "-output", attestations); code != exitOK {
+		t.Fatalf("attest exited with %d: %s", code, stderr)
+	}
+
+	// The chunks before the corrupted one are echoed, and nothing after it
+	data[2*1024+5] ^= 0xff
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatalf("Failed to corrupt input: %v", err)
+	}
+	code, stdout, stderr := runCLI("cat", "-input", input, "-attestations", attestations)
+	if code != exitMismatch || !strings.Contains(stderr, "File verification failed") {
+		t.Errorf("Expected cat to exit with %d reporting the mismatch, got %d: %s", exitMismatch, code, stderr)
+	}
+	if stdout != string(data[:2*1024]) {
+		t.Errorf("Expected cat to echo the 2048 verified bytes, got %d", len(stdout))
+	}
+}
+
+func TestManifestIsPlatformIndependent(t *testing.T) {
+	// The same tree as listed on Windows and on Linux, in different orders
+	windows := []manifestEntry{
+		{path: normalizePath(`tree\sub\b.bin`, '\\'), gid: "gitoid:blob:sha256:02"},
+		{path: normalizePath(`tree\a.bin`, '\\'), gid: "gitoid:blob:sha256:01"},
+		{path: normalizePath(`tree\sub-c.bin`, '\\'), gid: "gitoid:blob:sha256:03"},
+	}
+	linux := []manifestEntry{
+		{path: normalizePath("tree/sub-c.bin", '/'), gid: "gitoid:blob:sha256:03"},
+		{path: normalizePath("tree/a.bin", '/'), gid: "gitoid:blob:sha256:01"},
+		{path: normalizePath("tree/sub/b.bin", '/'), gid: "gitoid:blob:sha256:02"},
+	}
+	expected := "gitoid:blob:sha256:01  tree/a.bin\n" +
+		"gitoid:blob:sha256:03  tree/sub-c.bin\n" +
+		"gitoid:blob:sha256:02  tree/sub/b.bin\n"
+	if manifest := string(formatManifest(windows)); manifest != expected {
+		t.Errorf("Unexpected manifest from Windows paths %q", manifest)
+	}
+	if manifest := string(formatManifest(linux)); manifest != expected {
+		t.Errorf("Unexpected manifest from Linux paths %q", manifest)
+	}
+
+	// Input lists with CRLF line endings list the same files
+	dir := t.TempDir()
+	second, _ := writeTestFile(t, dir, "second.bin", 10)
+	first, _ := writeTestFile(t, dir, "first.bin", 20)
+	for i, content := range []string{second + "\n" + first + "\n", first + "\r\n" + second + "\r\n"} {
+		list := filepath.Join(dir, fmt.Sprintf("inputs%d.txt", i))
+		if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write input list: %v", err)
+		}
+		if code, _, stderr := runCLI("attest", "-input-list", list, "-output", list+".manifest"); code != exitOK {
+			t.Fatalf("attest exited with %d: %s", code, stderr)
+		}
+	}
+	lf, err := os.ReadFile(filepath.Join(dir, "inputs0.txt.manifest"))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	crlf, err := os.ReadFile(filepath.Join(dir, "inputs1.txt.manifest"))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if !bytes.Equal(lf, crlf) {
+		t.Errorf("Expected identical manifests, got %q and %q", lf, crlf)
+	}
+}
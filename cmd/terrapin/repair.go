@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fkautz/terrapin-go"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+)
+
+// repair verifies the file against its attestations and replaces each mismatched chunk with the chunk fetched
+// from mirrorURL, which must support HTTP range requests, once the fetched chunk verifies
+// Each fetch is abandoned after timeout, so a stalled mirror fails the chunk rather than hanging the repair
+func repair(filePath, attestationsPath, mirrorURL string, timeout time.Duration, stdout, stderr io.Writer) int {
+	// Read the attestations file
+	attestations, err := readAttestations(attestationsPath, formatAuto)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read attestations file: %v\n", err)
+		return exitFailure
+	}
+
+	// Open the input file for repairing in place
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open file: %v\n", err)
+		return exitFailure
+	}
+	defer file.Close()
+
+	// Create a new Terrapin instance with the provided attestations
+	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations, terrapin.WithEmptyAttestations())
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create terrapin instance with attestations: %v\n", err)
+		return exitFailure
+	}
+
+	// Find every mismatched chunk, including chunks missing from a truncated file and chunks that cannot be read
+	report, err := terrapinInstance.VerifyAllMismatchesAt(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to verify file: %v\n", err)
+		return exitFailure
+	}
+	mismatches := slices.Concat(report.Mismatched, report.Unreadable)
+	slices.Sort(mismatches)
+
+	// The data ends with the last attested chunk, whose length is known once it verifies or is repaired
+	count := terrapinInstance.NumChunks()
+	fi, err := file.Stat()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to stat file: %v\n", err)
+		return exitFailure
+	}
+	finalLength := int64(0)
+	if count > 0 {
+		_, end := terrapinInstance.ChunkByteRange(count - 1)
+		finalLength = min(end, fi.Size())
+	}
+
+	client := &http.Client{Timeout: timeout}
+	repaired, failed := 0, 0
+	for _, index := range mismatches {
+		if index >= count {
+			continue // Data beyond the attested chunks is truncated below
+		}
+		if index == count-1 {
+			finalLength = -1 // Unknown unless the last chunk is repaired
+		}
+
+		// Fetch the chunk from the mirror and only write it once it verifies
+		offset, end := terrapinInstance.ChunkByteRange(index)
+		chunk, err := fetchRange(client, mirrorURL, offset, end-offset)
+		if err != nil {
+			failed++
+			fmt.Fprintf(stderr, "Failed to fetch chunk %d: %v\n", index, err)
+			continue
+		}
+		valid, err := terrapinInstance.VerifyChunk(index, chunk)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to verify chunk %d: %v\n", index, err)
+			return exitFailure
+		}
+		if !valid {
+			failed++
+			fmt.Fprintf(stderr, "Chunk %d from the mirror does not match its attestation\n", index)
+			continue
+		}
+		if _, err := file.WriteAt(chunk, offset); err != nil {
+			fmt.Fprintf(stderr, "Failed to write chunk %d: %v\n", index, err)
+			return exitFailure
+		}
+		repaired++
+		if index == count-1 {
+			finalLength = offset + int64(len(chunk))
+		}
+	}
+
+	// Drop any data beyond the last chunk, unless its length is unknown because it could not be repaired
+	if finalLength >= 0 {
+		if err := file.Truncate(finalLength); err != nil {
+			fmt.Fprintf(stderr, "Failed to truncate file: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	fmt.Fprintf(stdout, "Repaired %d chunks\n", repaired)
+	if failed > 0 {
+		fmt.Fprintf(stderr, "File repair failed: %d chunks could not be repaired\n", failed)
+		return exitMismatch
+	}
+
+	// Verify the repaired file as a whole before reporting success
+	report, err = terrapinInstance.VerifyAllMismatchesAt(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to verify repaired file: %v\n", err)
+		return exitFailure
+	}
+	if remaining := len(report.Mismatched) + len(report.Unreadable); remaining > 0 {
+		fmt.Fprintf(stderr, "File repair failed: %d chunks still mismatched after repair\n", remaining)
+		return exitMismatch
+	}
+	return exitOK
+}
+
+// fetchRange fetches up to length bytes at offset from url with client, using an HTTP range request
+func fetchRange(client *http.Client, url string, offset, length int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("mirror returned %s instead of partial content", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, length))
+}
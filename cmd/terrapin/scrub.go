@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fkautz/terrapin-go"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// scrub periodically re-verifies every file under dir that has an attestations file alongside it, named
+// after the file with suffix appended, logging each file that fails verification
+// With once set a single pass is made and its outcome determines the exit code; otherwise it never returns
+func scrub(dir, suffix string, interval time.Duration, once bool, stdout, stderr io.Writer) int {
+	logger := log.New(stderr, "", log.LstdFlags)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checked, failed, err := scrubPass(dir, suffix, logger)
+		if err != nil {
+			logger.Printf("Scrub of %s failed: %v", dir, err)
+		} else {
+			fmt.Fprintf(stdout, "Scrubbed %d files: %d failed\n", checked, failed)
+		}
+
+		if once {
+			switch {
+			case err != nil:
+				return exitFailure
+			case failed > 0:
+				return exitMismatch
+			default:
+				return exitOK
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// scrubPass walks dir once, verifying each attested file, and returns the number of files checked and failed
+// Files that cannot be verified are logged and counted as failed without stopping the pass, and files whose
+// attestations carry an epoch are logged with the epoch they were verified against
+func scrubPass(dir, suffix string, logger *log.Logger) (int, int, error) {
+	checked, failed := 0, 0
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || strings.HasSuffix(path, suffix) {
+			return nil
+		}
+
+		// Only files with attestations are scrubbed
+		attestationsPath := path + suffix
+		if _, err := os.Stat(attestationsPath); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		checked++
+		valid, epoch, err := verifyFile(path, attestationsPath)
+		if err != nil {
+			failed++
+			logger.Printf("Failed to verify %s: %v", path, err)
+			return nil
+		}
+		if !valid {
+			failed++
+			logger.Printf("Corruption detected in %s", path)
+		} else if epoch != 0 {
+			logger.Printf("Verified %s against epoch %d", path, epoch)
+		}
+		return nil
+	})
+	return checked, failed, err
+}
+
+// verifyFile verifies the whole file at filePath against the attestations at attestationsPath, returning the
+// epoch of the attestations along with the outcome
+func verifyFile(filePath, attestationsPath string) (bool, uint64, error) {
+	// Read the attestations file
+	attestations, err := readAttestations(attestationsPath, formatAuto)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to read attestations file: %w", err)
+	}
+
+	// Open the input file
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	// Create a new Terrapin instance with the provided attestations
+	terrapinInstance, err := terrapin.NewTerrapinWithAttestations(attestations, terrapin.WithEmptyAttestations())
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to create terrapin instance with attestations: %w", err)
+	}
+
+	valid, err := terrapinInstance.VerifyBuffer(file)
+	return valid, terrapinInstance.Epoch(), err
+}
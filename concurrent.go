@@ -0,0 +1,62 @@
+package terrapin
+
+import (
+	"io"
+	"sync"
+)
+
+// ConcurrentTerrapin wraps a Terrapin instance so it can be shared by multiple goroutines: Add and Finalize
+// are serialized by a mutex, and the Verify methods share a read lock, so they run concurrently with each
+// other but never with a call that modifies the instance
+// Data passed to concurrent calls of Add is attested in whatever order the calls acquire the lock, so callers
+// that need a particular order must impose it themselves
+type ConcurrentTerrapin struct {
+	mu sync.RWMutex
+	t  *Terrapin
+}
+
+// NewConcurrentTerrapin initializes and returns a new ConcurrentTerrapin configured by the given options
+func NewConcurrentTerrapin(opts ...Option) (*ConcurrentTerrapin, error) {
+	t, err := NewTerrapinWithOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ConcurrentTerrapin{t: t}, nil
+}
+
+// Add adds data to the attestation, as Terrapin.Add does
+func (c *ConcurrentTerrapin) Add(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.Add(data)
+}
+
+// Finalize finalizes the attestation, as Terrapin.Finalize does
+func (c *ConcurrentTerrapin) Finalize() (string, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t.Finalize()
+}
+
+// NumChunks returns the number of chunks attested so far
+func (c *ConcurrentTerrapin) NumChunks() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.NumChunks()
+}
+
+// VerifyBuffer verifies the entire data stream from the reader against the attestations, as
+// Terrapin.VerifyBuffer does
+func (c *ConcurrentTerrapin) VerifyBuffer(reader io.Reader) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.VerifyBuffer(reader)
+}
+
+// VerifyBufferRange verifies a specific range of data from the reader against the attestations, as
+// Terrapin.VerifyBufferRange does
+func (c *ConcurrentTerrapin) VerifyBufferRange(reader io.Reader, startOffset, endOffset int) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.t.VerifyBufferRange(reader, startOffset, endOffset)
+}
@@ -0,0 +1,64 @@
+package terrapin
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentTerrapin exercises parallel calls on the guarded variant and is meant to be run with -race
+func TestConcurrentTerrapin(t *testing.T) {
+	const goroutines, blocks = 8, 4
+	block := make([]byte, 1024)
+	for i := range block {
+		block[i] = byte(i % 251)
+	}
+	terrapin, err := NewConcurrentTerrapin(WithBlockSize(len(block)))
+	if err != nil {
+		t.Fatalf("NewConcurrentTerrapin returned an error: %v", err)
+	}
+
+	// Every goroutine adds whole identical blocks, so the attestations do not depend on the order of the calls
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range blocks {
+				if err := terrapin.Add(block); err != nil {
+					t.Errorf("Add returned an error: %v", err)
+				}
+				terrapin.NumChunks()
+			}
+		}()
+	}
+	wg.Wait()
+	uri, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	data := bytes.Repeat(block, goroutines*blocks)
+	expectedURI, expectedAttestations, err := AttestReaderPipelined(bytes.NewReader(data), WithBlockSize(len(block)))
+	if err != nil {
+		t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+	}
+	if uri != expectedURI || !bytes.Equal(attestations, expectedAttestations) {
+		t.Error("Expected parallel Adds to attest every block")
+	}
+
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if valid, err := terrapin.VerifyBuffer(bytes.NewReader(data)); err != nil || !valid {
+				t.Errorf("Expected data to verify, got %v, %v", valid, err)
+			}
+			if valid, err := terrapin.VerifyBufferRange(bytes.NewReader(data[1024:]), 1024, 2048); err != nil || !valid {
+				t.Errorf("Expected range to verify, got %v, %v", valid, err)
+			}
+			terrapin.Finalize()
+		}()
+	}
+	wg.Wait()
+}
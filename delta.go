@@ -0,0 +1,83 @@
+package terrapin
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AttestationsSince returns an attestations delta holding the header followed by only the chunk hashes from
+// chunkIndex onward, so a remote holding the attestations of an earlier version of append-only data can bring
+// them up to date with ApplyAttestationsDelta instead of receiving the whole blob again
+// The header records chunkIndex, so the delta is always headered unless chunkIndex is 0, in which case the
+// delta is the complete attestations. As the last chunk of the earlier version may have been partial, deltas
+// should start at or before that chunk
+func (t *Terrapin) AttestationsSince(chunkIndex int) ([]byte, error) {
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	if t.merkle {
+		return nil, errors.New("attestations deltas are not supported in Merkle mode")
+	}
+	if chunkIndex < 0 || chunkIndex > t.NumChunks() {
+		return nil, fmt.Errorf("chunk index %d out of range [0, %d]", chunkIndex, t.NumChunks())
+	}
+
+	// Describe the delta with a copy of the instance starting at chunkIndex
+	delta := *t
+	delta.firstChunk = chunkIndex
+	if t.variable {
+		delta.chunkLengths = t.chunkLengths[chunkIndex:]
+	}
+	return delta.blob(t.attestations[chunkIndex*t.digestSize():]), nil
+}
+
+// ApplyAttestationsDelta applies a delta produced by AttestationsSince to the attestations it was computed
+// against, replacing their chunk hashes from the delta's first chunk onward, and returns the updated attestations
+// The delta must use the same settings as the attestations and must not start beyond their last chunk; its
+// epoch, if any, replaces theirs
+func ApplyAttestationsDelta(attestations, delta []byte) ([]byte, error) {
+	base, err := NewTerrapinWithAttestations(attestations, WithEmptyAttestations())
+	if err != nil {
+		return nil, err
+	}
+	if base.merkle {
+		return nil, errors.New("attestations deltas are not supported in Merkle mode")
+	}
+
+	// Parse the delta with the attestations' settings, which apply if the delta is headerless
+	update := newDefaultTerrapin()
+	update.blockSize, update.chunkType, update.rootType = base.blockSize, base.chunkType, base.rootType
+	update.algorithm, update.rawChunks, update.digestLen = base.algorithm, base.rawChunks, base.digestLen
+	update.variable = base.variable
+	body, err := update.parseHeader(delta)
+	if err != nil {
+		return nil, err
+	}
+	if len(body)%update.digestSize() != 0 {
+		return nil, &InvalidAttestationsError{Reason: "delta length is not a multiple of the digest size"}
+	}
+	if update.variable && len(update.chunkLengths) != len(body)/update.digestSize() {
+		return nil, &InvalidAttestationsError{Reason: "delta chunk lengths do not match the number of chunk hashes"}
+	}
+
+	// The delta's chunk hashes are only meaningful when computed the same way
+	if base.blockSize != update.blockSize || base.chunkType != update.chunkType || base.rootType != update.rootType ||
+		base.algorithm != update.algorithm || base.variable != update.variable || base.rawChunks != update.rawChunks ||
+		base.merkle != update.merkle || base.digestSize() != update.digestSize() {
+		return nil, errors.New("delta uses different settings than the attestations")
+	}
+	if update.firstChunk > base.NumChunks() {
+		return nil, fmt.Errorf("delta starts at chunk %d beyond the %d attested chunks", update.firstChunk, base.NumChunks())
+	}
+
+	// Replace the chunk hashes from the delta's first chunk onward, copying so the input is left untouched
+	keep := update.firstChunk * base.digestSize()
+	base.attestations = append(base.attestations[:keep:keep], body...)
+	if base.variable {
+		base.chunkLengths = append(base.chunkLengths[:update.firstChunk:update.firstChunk], update.chunkLengths...)
+	}
+	if update.epoch != 0 {
+		base.epoch = update.epoch
+	}
+	return base.blob(base.attestations), nil
+}
@@ -0,0 +1,101 @@
+package terrapin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+// attestBlocks attests data in 1024-byte blocks with the given extra options
+func attestBlocks(t *testing.T, data []byte, opts ...Option) (*Terrapin, []byte) {
+	t.Helper()
+	attestor, err := NewTerrapinWithOptions(append([]Option{WithBlockSize(1024)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	return attestor, attestations
+}
+
+func TestAttestationsDelta(t *testing.T) {
+	data := make([]byte, 6*1024+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	// The remote holds attestations of an earlier version ending in a partial chunk
+	_, earlier := attestBlocks(t, data[:3*1024+10])
+	current, full := attestBlocks(t, data, WithEpoch(2))
+
+	// Only the hashes from the previously partial chunk onward are transmitted
+	delta, err := current.AttestationsSince(3)
+	if err != nil {
+		t.Fatalf("AttestationsSince returned an error: %v", err)
+	}
+	if hashes := 4 * sha256.Size; len(delta) >= len(full) || !bytes.HasSuffix(delta, full[len(full)-hashes:]) {
+		t.Errorf("Expected the delta to hold only the last 4 chunk hashes, got %d bytes", len(delta))
+	}
+	if _, err := NewTerrapinWithAttestations(delta); err == nil {
+		t.Error("Expected a delta to be rejected as complete attestations")
+	}
+
+	updated, err := ApplyAttestationsDelta(earlier, delta)
+	if err != nil {
+		t.Fatalf("ApplyAttestationsDelta returned an error: %v", err)
+	}
+	if !bytes.Equal(updated, full) {
+		t.Errorf("Expected the updated attestations to match the full attestations")
+	}
+	terrapin, err := NewTerrapinWithAttestations(updated)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	if terrapin.Epoch() != 2 {
+		t.Errorf("Expected the delta's epoch 2, got %d", terrapin.Epoch())
+	}
+	valid, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !valid {
+		t.Errorf("Expected data to verify, got %v, %v", valid, err)
+	}
+
+	// A delta starting at chunk 0 is the complete attestations
+	whole, err := current.AttestationsSince(0)
+	if err != nil {
+		t.Fatalf("AttestationsSince returned an error: %v", err)
+	}
+	if !bytes.Equal(whole, full) {
+		t.Errorf("Expected a delta from chunk 0 to match the full attestations")
+	}
+}
+
+func TestApplyAttestationsDeltaRejectsMismatches(t *testing.T) {
+	data := make([]byte, 4*1024)
+	_, short := attestBlocks(t, data[:1024])
+	current, _ := attestBlocks(t, data)
+	gap, err := current.AttestationsSince(3)
+	if err != nil {
+		t.Fatalf("AttestationsSince returned an error: %v", err)
+	}
+	if _, err := ApplyAttestationsDelta(short, gap); err == nil {
+		t.Error("Expected a delta starting beyond the attested chunks to be rejected")
+	}
+
+	other, _ := attestBlocks(t, data, WithHashAlgorithm(SHA512))
+	otherDelta, err := other.AttestationsSince(1)
+	if err != nil {
+		t.Fatalf("AttestationsSince returned an error: %v", err)
+	}
+	if _, err := ApplyAttestationsDelta(short, otherDelta); err == nil {
+		t.Error("Expected a delta with different settings to be rejected")
+	}
+
+	if _, err := current.AttestationsSince(5); err == nil {
+		t.Error("Expected an out of range chunk index to be rejected")
+	}
+}
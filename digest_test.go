@@ -0,0 +1,84 @@
+package terrapin
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"testing"
+)
+
+func TestWithDigestSize(t *testing.T) {
+	data := make([]byte, 5*1024+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	full, err := chunkHash(data[2*1024:3*1024], "blob", SHA512)
+	if err != nil {
+		t.Fatalf("chunkHash returned an error: %v", err)
+	}
+
+	for _, size := range []int{16, 32, sha512.Size} {
+		for _, merkle := range []bool{false, true} {
+			opts := []Option{WithBlockSize(1024), WithHashAlgorithm(SHA512), WithDigestSize(size)}
+			if merkle {
+				opts = append(opts, WithMerkle())
+			}
+			attestor, err := NewTerrapinWithOptions(opts...)
+			if err != nil {
+				t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+			}
+			if err := attestor.Add(data); err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+			uri, attestations, err := attestor.Finalize()
+			if err != nil {
+				t.Fatalf("Failed to finalize terrapin: %v", err)
+			}
+
+			terrapin, err := NewTerrapinWithAttestations(attestations)
+			if err != nil {
+				t.Fatalf("%d bytes: NewTerrapinWithAttestations returned an error: %v", size, err)
+			}
+			if terrapin.digestSize() != size || terrapin.NumChunks() != 6 {
+				t.Fatalf("%d bytes: expected 6 chunks of %d-byte digests, got %d of %d", size, size, terrapin.NumChunks(), terrapin.digestSize())
+			}
+			if parsedURI, _, _ := terrapin.Finalize(); parsedURI != uri {
+				t.Errorf("%d bytes: expected URI %s, got %s", size, uri, parsedURI)
+			}
+
+			// Each chunk hash is the prefix of the full digest
+			if !bytes.Equal(terrapin.attestations[2*size:3*size], full[:size]) {
+				t.Errorf("%d bytes: expected chunk 2 to hold the truncated digest", size)
+			}
+
+			match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+			if err != nil || !match {
+				t.Fatalf("%d bytes: expected VerifyBuffer to match, got %v, %v", size, match, err)
+			}
+			for i := 0; i < terrapin.NumChunks(); i++ {
+				match, err := terrapin.VerifyReaderAt(bytes.NewReader(data), i)
+				if err != nil || !match {
+					t.Fatalf("%d bytes: expected chunk %d to match, got %v, %v", size, i, match, err)
+				}
+			}
+			corrupt := append([]byte(nil), data...)
+			corrupt[4*1024+1] ^= 0xff
+			mismatches, err := terrapin.VerifyAllMismatches(bytes.NewReader(corrupt))
+			if err != nil {
+				t.Fatalf("%d bytes: VerifyAllMismatches returned an error: %v", size, err)
+			}
+			if len(mismatches) != 1 || mismatches[0] != 4 {
+				t.Errorf("%d bytes: expected chunk 4 to mismatch, got %v", size, mismatches)
+			}
+		}
+	}
+
+	for _, opts := range [][]Option{
+		{WithDigestSize(8)},
+		{WithDigestSize(48)},
+		{WithHashAlgorithm(SHA1), WithDigestSize(32)},
+	} {
+		if _, err := NewTerrapinWithOptions(opts...); err == nil {
+			t.Error("Expected an invalid digest size to be rejected")
+		}
+	}
+}
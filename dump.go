@@ -0,0 +1,38 @@
+package terrapin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// DumpAttestations writes a table of the chunks with indexes from start up to but excluding end, listing each
+// chunk's index, byte range and hex digest, for manual inspection of attestations
+// A negative end dumps through the last chunk
+// With fixed-size chunks the byte range of the final chunk is shown at full block size, as the data length
+// is not recorded in the attestations
+func (t *Terrapin) DumpAttestations(w io.Writer, start, end int) error {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return errors.New("terrapin not finalized")
+	}
+
+	// Validate the range, defaulting to the end of the attestations
+	count := len(t.attestations) / t.digestSize()
+	if end < 0 {
+		end = count
+	}
+	if start < 0 || end < start || end > count {
+		return fmt.Errorf("invalid chunk range %d-%d for %d chunks", start, end, count)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHUNK\tBYTES\tDIGEST")
+	for index := start; index < end; index++ {
+		offset := t.chunkOffset(index)
+		digest := t.attestations[index*t.digestSize() : (index+1)*t.digestSize()]
+		fmt.Fprintf(tw, "%d\t%d-%d\t%x\n", index, offset, offset+int64(t.chunkLength(index))-1, digest)
+	}
+	return tw.Flush()
+}
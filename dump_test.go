@@ -0,0 +1,56 @@
+package terrapin
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDumpAttestations(t *testing.T) {
+	data := make([]byte, 5*1024+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := terrapin.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if _, _, err := terrapin.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if terrapin.NumChunks() != 6 {
+		t.Fatalf("Expected 6 chunks, got %d", terrapin.NumChunks())
+	}
+
+	var out bytes.Buffer
+	if err := terrapin.DumpAttestations(&out, 2, 5); err != nil {
+		t.Fatalf("DumpAttestations returned an error: %v", err)
+	}
+	rows := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(rows) != 4 {
+		t.Fatalf("Expected a header and 3 rows, got %q", out.String())
+	}
+	fields := strings.Fields(rows[1])
+	expected := []string{"2", "2048-3071", fmt.Sprintf("%x", terrapin.attestations[2*32:3*32])}
+	if strings.Join(fields, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected row %v, got %v", expected, fields)
+	}
+
+	out.Reset()
+	if err := terrapin.DumpAttestations(&out, 4, -1); err != nil {
+		t.Fatalf("DumpAttestations returned an error: %v", err)
+	}
+	if rows := strings.Split(strings.TrimSpace(out.String()), "\n"); len(rows) != 3 {
+		t.Errorf("Expected a header and the last 2 rows, got %q", out.String())
+	}
+
+	for _, r := range [][2]int{{-1, 2}, {3, 2}, {0, 7}} {
+		if err := terrapin.DumpAttestations(&out, r[0], r[1]); err == nil {
+			t.Errorf("Expected range %d-%d to be rejected", r[0], r[1])
+		}
+	}
+}
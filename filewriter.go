@@ -0,0 +1,129 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// verifyingFileWriter writes data to a temporary file while verifying each completed chunk against the attestations
+type verifyingFileWriter struct {
+	t      *Terrapin // Finalized instance holding the attestations
+	file   *os.File  // Temporary file in the destination's directory
+	path   string    // Destination the temporary file is renamed to on success
+	buffer []byte    // Data of the chunk being written, not yet verified
+	index  int       // Index of the chunk being written
+	err    error     // First write or verification error, returned by every later call
+	closed bool      // Whether Close has been called
+}
+
+// VerifyingFileWriter returns an io.WriteCloser that writes data to a temporary file next to path while verifying
+// it against the attestations of t, so a download is written and verified in a single pass
+// Write fails as soon as a chunk does not match. Close verifies the final chunk and renames the temporary file to
+// path only if the data matched the attestations in full; otherwise it removes the temporary file and returns an
+// error, so path never holds unverified data. As with os.CreateTemp, the file is created with mode 0600
+func VerifyingFileWriter(path string, t *Terrapin) (io.WriteCloser, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+
+	// Create the temporary file in the destination's directory so the final rename is atomic
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	return &verifyingFileWriter{
+		t:    t,
+		file: file,
+		path: path,
+	}, nil
+}
+
+// Write writes p to the temporary file and verifies every chunk it completes
+func (w *verifyingFileWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("writer already closed")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if w.index >= w.t.NumChunks() {
+			w.err = errors.New("data exceeds the attested chunks")
+			return written, w.err
+		}
+
+		// Take as much of p as the current chunk still needs
+		n := min(len(p), w.t.chunkLength(w.index)-len(w.buffer))
+		if _, err := w.file.Write(p[:n]); err != nil {
+			w.err = fmt.Errorf("failed to write temporary file: %w", err)
+			return written, w.err
+		}
+		w.buffer = append(w.buffer, p[:n]...)
+		written += n
+		p = p[n:]
+
+		if len(w.buffer) == w.t.chunkLength(w.index) {
+			if err := w.verifyChunk(); err != nil {
+				w.err = err
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// verifyChunk verifies the buffered data as the chunk at the current index, then moves on to the next chunk
+func (w *verifyingFileWriter) verifyChunk() error {
+	computedHash, err := w.t.hashChunk(w.buffer)
+	if err != nil {
+		return err
+	}
+	expectedHash := w.t.attestations[w.index*w.t.digestSize() : (w.index+1)*w.t.digestSize()]
+	if !bytes.Equal(computedHash, expectedHash) {
+		return fmt.Errorf("chunk %d does not match its attestation", w.index)
+	}
+	w.buffer = w.buffer[:0]
+	w.index++
+	return nil
+}
+
+// Close verifies the final chunk, then renames the temporary file to the destination path if all data matched,
+// or removes it and returns the error otherwise
+func (w *verifyingFileWriter) Close() error {
+	if w.closed {
+		return errors.New("writer already closed")
+	}
+	w.closed = true
+
+	// Only the last chunk may be short, and no attested chunk may be missing
+	if w.err == nil && len(w.buffer) > 0 {
+		if w.index == w.t.NumChunks()-1 && !w.t.variable {
+			w.err = w.verifyChunk()
+		} else {
+			w.err = fmt.Errorf("data ends within chunk %d after %d bytes", w.index, len(w.buffer))
+		}
+	}
+	if w.err == nil && w.index < w.t.NumChunks() {
+		w.err = fmt.Errorf("data ends after %d of %d attested chunks", w.index, w.t.NumChunks())
+	}
+
+	if err := w.file.Close(); err != nil && w.err == nil {
+		w.err = fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if w.err != nil {
+		os.Remove(w.file.Name())
+		return w.err
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+	return nil
+}
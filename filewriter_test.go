@@ -0,0 +1,71 @@
+package terrapin
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyingFileWriter(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if _, _, err := attestor.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// download writes stream to path through a VerifyingFileWriter in small writes
+	download := func(path string, stream []byte) error {
+		w, err := VerifyingFileWriter(path, attestor)
+		if err != nil {
+			t.Fatalf("VerifyingFileWriter returned an error: %v", err)
+		}
+		_, copyErr := io.CopyBuffer(w, bytes.NewReader(stream), make([]byte, 700))
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return copyErr
+	}
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.bin")
+	if err := download(good, data); err != nil {
+		t.Fatalf("Expected matching data to be written, got %v", err)
+	}
+	if written, err := os.ReadFile(good); err != nil || !bytes.Equal(written, data) {
+		t.Errorf("Expected the destination to hold the data, got %d bytes, %v", len(written), err)
+	}
+
+	corrupt := bytes.Clone(data)
+	corrupt[2*1024+5] ^= 0xff
+	truncated := data[:2*1024+10]
+	extended := append(bytes.Clone(data), 0)
+	for name, stream := range map[string][]byte{"corrupt": corrupt, "truncated": truncated, "extended": extended} {
+		path := filepath.Join(dir, name+".bin")
+		if err := download(path, stream); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no destination file, got %v", name, err)
+		}
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the verified file in the directory, got %d entries", len(entries))
+	}
+}
@@ -2,4 +2,7 @@ module github.com/fkautz/terrapin-go
 
 go 1.22
 
-require github.com/edwarnicke/gitoid v0.0.0-20220710194850-1be5bfda1f9d
+require (
+	github.com/edwarnicke/gitoid v0.0.0-20220710194850-1be5bfda1f9d
+	golang.org/x/time v0.10.0
+)
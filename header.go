@@ -0,0 +1,307 @@
+package terrapin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+	"math"
+)
+
+// Attestations produced with the default settings (BufferCapacity blocks, SHA-256, blob chunk and root types)
+// are a bare concatenation of chunk hashes, exactly as in earlier releases. Any other configuration
+// is recorded in a header preceding the chunk hashes, so the attestations are self-describing:
+//
+//	magic    4 bytes  "TRPN"
+//	version  1 byte   headerVersion
+//	length   uvarint  number of bytes of the fields that follow
+//	fields   repeated (tag uvarint, value length uvarint, value bytes)
+//
+// Every uvarint, including the integers within field values, is an unsigned LEB128 integer: seven bits per
+// byte, least significant group first, with the high bit of each byte set on all but the last byte. Only the
+// shortest encoding of a value is accepted, so 1024 is always 0x80 0x08 and never 0x80 0x88 0x00. LEB128
+// fixes its own byte order, so headers are identical regardless of host endianness. Strings are raw bytes.
+// Each tag appears at most once; this package writes them in ascending order.
+// The root gitoid returned by Finalize is computed over the header and the chunk hashes together, except that
+// metadata fields such as the epoch are left out of the hashed header, and attestations whose header only
+// carries metadata are hashed as if headerless.
+//
+// For example, 1024-byte blocks with the default types and SHA-256 produce the header
+//
+//	54 52 50 4e                    "TRPN"
+//	01                             version 1
+//	16                             22 bytes of fields
+//	01 02 80 08                    block size 1024
+//	02 04 62 6c 6f 62              chunk type "blob"
+//	03 04 62 6c 6f 62              root type "blob"
+//	04 01 02                       algorithm SHA256
+//	05 01 20                       digest size 32
+
+// attestationMagic identifies attestations that begin with a header
+var attestationMagic = []byte("TRPN")
+
+// headerVersion is the version of the header layout written by this package
+const headerVersion = 1
+
+// Header field tags
+const (
+	headerTagBlockSize  = 1  // Block size in bytes, uvarint
+	headerTagChunkType  = 2  // Git object type of chunk gitoids, string
+	headerTagRootType   = 3  // Git object type of the root gitoid, string
+	headerTagAlgorithm  = 4  // Hash algorithm identifier, uvarint
+	headerTagDigestSize = 5  // Size of each chunk hash in bytes, uvarint
+	headerTagMerkle     = 6  // Merkle mode flag, uvarint 1 when the body holds a Merkle tree
+	headerTagChunkCount = 7  // Number of chunk hashes, uvarint; written in Merkle mode
+	headerTagChunkSizes = 8  // Length of each chunk in bytes, one uvarint per chunk; written for variable-size chunks
+	headerTagRawChunks  = 9  // Raw chunk hash flag, uvarint 1 when chunks are hashed without a git object header
+	headerTagEpoch      = 10 // Attestation generation, uvarint; metadata excluded from the root gitoid
+	headerTagFirstChunk = 11 // Index of the first chunk hash, uvarint; written in attestation deltas
+)
+
+// needsHeader reports whether the instance's settings differ from the headerless defaults
+func (t *Terrapin) needsHeader() bool {
+	return t.blockSize != BufferCapacity || t.algorithm != SHA256 || t.chunkType != gitoid.BLOB || t.rootType != gitoid.BLOB ||
+		t.merkle || t.variable || t.rawChunks || t.digestLen != 0 || t.firstChunk != 0
+}
+
+// marshalHeader returns the header describing the instance's settings and chunk count, or nil if none is needed
+// Metadata fields are only included when metadata is set, so they can be left out of the hashed header
+func (t *Terrapin) marshalHeader(chunks int, metadata bool) []byte {
+	if !t.needsHeader() && !(metadata && t.epoch != 0) {
+		return nil
+	}
+
+	var fields []byte
+	fields = appendHeaderField(fields, headerTagBlockSize, binary.AppendUvarint(nil, uint64(t.blockSize)))
+	fields = appendHeaderField(fields, headerTagChunkType, []byte(t.chunkType))
+	fields = appendHeaderField(fields, headerTagRootType, []byte(t.rootType))
+	fields = appendHeaderField(fields, headerTagAlgorithm, binary.AppendUvarint(nil, uint64(t.algorithm)))
+	fields = appendHeaderField(fields, headerTagDigestSize, binary.AppendUvarint(nil, uint64(t.digestSize())))
+	if t.merkle {
+		fields = appendHeaderField(fields, headerTagMerkle, binary.AppendUvarint(nil, 1))
+		fields = appendHeaderField(fields, headerTagChunkCount, binary.AppendUvarint(nil, uint64(chunks)))
+	}
+	if t.rawChunks {
+		fields = appendHeaderField(fields, headerTagRawChunks, binary.AppendUvarint(nil, 1))
+	}
+	if t.variable {
+		var lengths []byte
+		for _, length := range t.chunkLengths[:chunks] {
+			lengths = binary.AppendUvarint(lengths, uint64(length))
+		}
+		fields = appendHeaderField(fields, headerTagChunkSizes, lengths)
+	}
+	if t.firstChunk != 0 {
+		fields = appendHeaderField(fields, headerTagFirstChunk, binary.AppendUvarint(nil, uint64(t.firstChunk)))
+	}
+	if metadata && t.epoch != 0 {
+		fields = appendHeaderField(fields, headerTagEpoch, binary.AppendUvarint(nil, t.epoch))
+	}
+
+	header := append([]byte(nil), attestationMagic...)
+	header = append(header, headerVersion)
+	header = binary.AppendUvarint(header, uint64(len(fields)))
+	return append(header, fields...)
+}
+
+// appendHeaderField appends a single tagged field to dst
+func appendHeaderField(dst []byte, tag uint64, value []byte) []byte {
+	dst = binary.AppendUvarint(dst, tag)
+	dst = binary.AppendUvarint(dst, uint64(len(value)))
+	return append(dst, value...)
+}
+
+// blob returns a new slice holding the header, if any, followed by the given chunk hashes
+// In Merkle mode the levels of the tree follow the chunk hashes
+func (t *Terrapin) blob(attestations []byte) []byte {
+	return bytes.Join(t.blobParts(attestations, true), nil)
+}
+
+// blobParts returns the pieces of the blob without copying the chunk hashes
+// Without metadata the pieces are those covered by the root gitoid
+func (t *Terrapin) blobParts(attestations []byte, metadata bool) [][]byte {
+	parts := [][]byte{t.marshalHeader(len(attestations)/t.digestSize(), metadata), attestations}
+	if t.merkle {
+		for _, level := range t.merkleLevels(attestations) {
+			parts = append(parts, level...)
+		}
+	}
+	return parts
+}
+
+// parseHeader applies the settings recorded in the header of blob, if it has one, and returns the chunk hashes
+func (t *Terrapin) parseHeader(blob []byte) ([]byte, error) {
+	if !bytes.HasPrefix(blob, attestationMagic) {
+		return blob, nil // Headerless attestations use the configured settings
+	}
+
+	rest := blob[len(attestationMagic):]
+	if len(rest) == 0 || rest[0] != headerVersion {
+		return nil, &InvalidAttestationsError{Reason: "unsupported header version"}
+	}
+	rest = rest[1:]
+
+	length, n := uvarint(rest)
+	if n <= 0 || length > uint64(len(rest)-n) {
+		return nil, &InvalidAttestationsError{Reason: "truncated header"}
+	}
+	fields, body := rest[n:n+int(length)], rest[n+int(length):]
+
+	digestSize := 0
+	chunks := -1
+	seen := map[uint64]bool{}
+	for len(fields) > 0 {
+		tag, value, remaining, err := readHeaderField(fields)
+		if err != nil {
+			return nil, err
+		}
+		fields = remaining
+		if seen[tag] {
+			return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("duplicate header field %d", tag)}
+		}
+		seen[tag] = true
+
+		switch tag {
+		case headerTagBlockSize:
+			size, err := headerUvarint(value, MaxBlockSize, "block size")
+			if err != nil {
+				return nil, err
+			}
+			if err := WithBlockSize(int(size))(t); err != nil {
+				return nil, &InvalidAttestationsError{Reason: err.Error()}
+			}
+		case headerTagChunkType:
+			if err := WithChunkType(gitoid.GitObjectType(value))(t); err != nil {
+				return nil, &InvalidAttestationsError{Reason: err.Error()}
+			}
+		case headerTagRootType:
+			if err := WithRootType(gitoid.GitObjectType(value))(t); err != nil {
+				return nil, &InvalidAttestationsError{Reason: err.Error()}
+			}
+		case headerTagAlgorithm:
+			id, err := headerUvarint(value, 255, "hash algorithm")
+			if err != nil {
+				return nil, err
+			}
+			if err := WithHashAlgorithm(Algorithm(id))(t); err != nil {
+				return nil, &InvalidAttestationsError{Reason: err.Error()}
+			}
+		case headerTagDigestSize:
+			size, err := headerUvarint(value, 1024, "digest size")
+			if err != nil {
+				return nil, err
+			}
+			digestSize = int(size)
+		case headerTagMerkle:
+			flag, err := headerUvarint(value, 1, "Merkle flag")
+			if err != nil {
+				return nil, err
+			}
+			t.merkle = flag == 1
+		case headerTagChunkCount:
+			count, err := headerUvarint(value, uint64(len(body)), "chunk count")
+			if err != nil {
+				return nil, err
+			}
+			chunks = int(count)
+		case headerTagRawChunks:
+			flag, err := headerUvarint(value, 1, "raw chunk flag")
+			if err != nil {
+				return nil, err
+			}
+			t.rawChunks = flag == 1
+		case headerTagChunkSizes:
+			lengths, err := parseChunkSizes(value)
+			if err != nil {
+				return nil, err
+			}
+			t.variable = true
+			t.chunkLengths = lengths
+		case headerTagEpoch:
+			epoch, err := headerUvarint(value, math.MaxUint64, "epoch")
+			if err != nil {
+				return nil, err
+			}
+			t.epoch = epoch
+		case headerTagFirstChunk:
+			first, err := headerUvarint(value, math.MaxInt32, "first chunk")
+			if err != nil {
+				return nil, err
+			}
+			t.firstChunk = int(first)
+		default:
+			return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("unknown header field %d", tag)}
+		}
+	}
+
+	// The declared digest size may truncate the declared algorithm's digests, but never exceed them
+	if digestSize != 0 {
+		if digestSize > t.algorithm.Size() {
+			return nil, &InvalidAttestationsError{
+				Reason: fmt.Sprintf("digest size %d exceeds %s digest size %d", digestSize, t.algorithm, t.algorithm.Size()),
+			}
+		}
+		t.digestLen = digestSize
+		if err := t.validateDigestSize(); err != nil {
+			return nil, &InvalidAttestationsError{Reason: err.Error()}
+		}
+	}
+
+	// Split the Merkle tree's levels from the chunk hashes
+	if t.merkle {
+		if chunks < 0 {
+			return nil, &InvalidAttestationsError{Reason: "Merkle attestations without a chunk count"}
+		}
+		return t.splitMerkle(body, chunks)
+	}
+
+	return body, nil
+}
+
+// headerUvarint decodes a header field value holding a single uvarint no greater than limit
+func headerUvarint(value []byte, limit uint64, name string) (uint64, error) {
+	v, n := uvarint(value)
+	if n <= 0 || n != len(value) || v > limit {
+		return 0, &InvalidAttestationsError{Reason: "malformed " + name}
+	}
+	return v, nil
+}
+
+// parseChunkSizes decodes the sequence of chunk lengths recorded for variable-size chunks
+func parseChunkSizes(value []byte) ([]int, error) {
+	var lengths []int
+	for len(value) > 0 {
+		length, n := uvarint(value)
+		if n <= 0 || length == 0 || length > MaxBlockSize {
+			return nil, &InvalidAttestationsError{Reason: "malformed chunk sizes"}
+		}
+		lengths = append(lengths, int(length))
+		value = value[n:]
+	}
+	return lengths, nil
+}
+
+// readHeaderField splits the first tagged field off fields
+func readHeaderField(fields []byte) (uint64, []byte, []byte, error) {
+	tag, n := uvarint(fields)
+	if n <= 0 {
+		return 0, nil, nil, &InvalidAttestationsError{Reason: "malformed header field"}
+	}
+	fields = fields[n:]
+	length, n := uvarint(fields)
+	if n <= 0 || length > uint64(len(fields)-n) {
+		return 0, nil, nil, &InvalidAttestationsError{Reason: "malformed header field"}
+	}
+	fields = fields[n:]
+	return tag, fields[:length], fields[length:], nil
+}
+
+// uvarint decodes an unsigned LEB128 integer from buf like binary.Uvarint, but also rejects encodings longer
+// than necessary so every value has exactly one valid encoding
+func uvarint(buf []byte) (uint64, int) {
+	v, n := binary.Uvarint(buf)
+	if n > 0 && n != len(binary.AppendUvarint(nil, v)) {
+		return 0, -n
+	}
+	return v, n
+}
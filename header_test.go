@@ -0,0 +1,405 @@
+package terrapin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"github.com/edwarnicke/gitoid"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChunkAndRootTypes(t *testing.T) {
+	data := make([]byte, BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+
+	attestor, err := NewTerrapinWithOptions(WithChunkType(gitoid.BLOB), WithRootType("terrapin"))
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	rootURI, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if !strings.HasPrefix(rootURI, "gitoid:terrapin:sha256:") {
+		t.Errorf("Expected root URI with terrapin type, got %s", rootURI)
+	}
+
+	// The root covers the header, which records both types
+	expectedRoot, err := gitoid.New(bytes.NewReader(attestations), gitoid.WithSha256(), gitoid.WithGitObjectType("terrapin"))
+	if err != nil {
+		t.Fatalf("Failed to compute gitoid: %v", err)
+	}
+	if rootURI != expectedRoot.URI() {
+		t.Errorf("Expected root URI %s, got %s", expectedRoot.URI(), rootURI)
+	}
+
+	// Round trip the attestations without passing any options
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with attestations: %v", err)
+	}
+	if terrapin.rootType != "terrapin" || terrapin.chunkType != gitoid.BLOB {
+		t.Errorf("Expected types blob/terrapin from header, got %s/%s", terrapin.chunkType, terrapin.rootType)
+	}
+	roundTripURI, _, _ := terrapin.Finalize()
+	if roundTripURI != rootURI {
+		t.Errorf("Expected round-tripped root URI %s, got %s", rootURI, roundTripURI)
+	}
+	match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !match {
+		t.Fatalf("VerifyBuffer expected to match, got %v, %v", match, err)
+	}
+}
+
+func TestChunkType(t *testing.T) {
+	data := []byte("chunk data")
+	attestor, err := NewTerrapinWithOptions(WithChunkType(gitoid.TREE))
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	rootURI, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if !strings.HasPrefix(rootURI, "gitoid:blob:sha256:") {
+		t.Errorf("Expected root URI with blob type, got %s", rootURI)
+	}
+
+	expectedChunk, err := gitoid.New(bytes.NewReader(data), gitoid.WithSha256(), gitoid.WithGitObjectType(gitoid.TREE))
+	if err != nil {
+		t.Fatalf("Failed to compute gitoid: %v", err)
+	}
+	if !bytes.Equal(attestations[len(attestations)-sha256.Size:], expectedChunk.Bytes()) {
+		t.Errorf("Expected chunk hash of a tree object")
+	}
+}
+
+func TestDefaultAttestationsAreHeaderless(t *testing.T) {
+	attestor := NewTerrapin()
+	if err := attestor.Add([]byte{1, 2, 3}); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if len(attestations) != sha256.Size {
+		t.Errorf("Expected a single bare chunk hash, got %d bytes", len(attestations))
+	}
+}
+
+func TestInvalidHeaders(t *testing.T) {
+	for name, blob := range map[string][]byte{
+		"bad version":         []byte("TRPN\x02\x00"),
+		"truncated":           []byte("TRPN\x01\x05\x01"),
+		"unknown field":       []byte("TRPN\x01\x03\x7f\x01\x00"),
+		"tiny block":          []byte("TRPN\x01\x03\x01\x01\x01"),
+		"bad root type":       []byte("TRPN\x01\x03\x03\x01:"),
+		"body not a multiple": append([]byte("TRPN\x01\x00"), make([]byte, 31)...),
+		"overlong varint":     []byte("TRPN\x01\x04\x01\x02\x80\x88\x00"),
+		"overlong length":     []byte("TRPN\x01\x80\x00"),
+		"duplicate field":     []byte("TRPN\x01\x06\x06\x01\x00\x06\x01\x00"),
+	} {
+		if _, err := NewTerrapinWithAttestations(blob); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+	if _, err := NewTerrapinWithOptions(WithRootType("bad type")); err == nil {
+		t.Error("Expected invalid root type to be rejected")
+	}
+}
+
+func TestHeaderWireFormat(t *testing.T) {
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// The exact bytes documented in header.go; changing them breaks other implementations
+	expected := []byte{
+		0x54, 0x52, 0x50, 0x4e, // "TRPN"
+		0x01,                   // version 1
+		0x16,                   // 22 bytes of fields
+		0x01, 0x02, 0x80, 0x08, // block size 1024
+		0x02, 0x04, 'b', 'l', 'o', 'b', // chunk type
+		0x03, 0x04, 'b', 'l', 'o', 'b', // root type
+		0x04, 0x01, 0x02, // algorithm SHA256
+		0x05, 0x01, 0x20, // digest size 32
+	}
+	if !bytes.Equal(attestations, expected) {
+		t.Fatalf("Expected header % x, got % x", expected, attestations)
+	}
+
+	parsed, err := NewTerrapinWithAttestations(expected)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	if parsed.blockSize != 1024 {
+		t.Errorf("Expected block size 1024, got %d", parsed.blockSize)
+	}
+}
+
+func TestHeaderManualEncoding(t *testing.T) {
+	data := make([]byte, 1<<20+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1<<20), WithHashAlgorithm(SHA512))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// Build the header by hand following the documented byte order, least significant group first
+	header := []byte("TRPN\x01")
+	header = append(header, 23)                     // 23 bytes of fields
+	header = append(header, 1, 3, 0x80, 0x80, 0x40) // block size 1<<20: 0x00, 0x00, 0x40 in 7-bit groups
+	header = append(header, 2, 4, 'b', 'l', 'o', 'b')
+	header = append(header, 3, 4, 'b', 'l', 'o', 'b')
+	header = append(header, 4, 1, 3)    // algorithm SHA512
+	header = append(header, 5, 1, 0x40) // digest size 64
+	if !bytes.HasPrefix(attestations, header) {
+		t.Fatalf("Expected attestations to start with % x, got % x", header, attestations[:len(header)])
+	}
+
+	blob := append(header, attestations[len(header):]...)
+	parsed, err := NewTerrapinWithAttestations(blob)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	if parsed.blockSize != 1<<20 || parsed.algorithm != SHA512 {
+		t.Errorf("Expected block size %d and %s, got %d and %s", 1<<20, SHA512, parsed.blockSize, parsed.algorithm)
+	}
+	match, err := parsed.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+}
+
+func TestEmptyHeaderedAttestations(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"block size": {WithBlockSize(1024)},
+		"sha1":       {WithHashAlgorithm(SHA1)},
+		"merkle":     {WithMerkle()},
+		"variable":   {WithVariableChunks()},
+	} {
+		attestor, err := NewTerrapinWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithOptions returned an error: %v", name, err)
+		}
+		uri, header, err := attestor.Finalize()
+		if err != nil {
+			t.Fatalf("%s: Failed to finalize terrapin: %v", name, err)
+		}
+		if !bytes.HasPrefix(header, []byte("TRPN")) {
+			t.Fatalf("%s: Expected a header, got %q", name, header)
+		}
+
+		// The root gitoid covers the header alone
+		root, err := hashGitoid(gitoid.BLOB, attestor.algorithm, header)
+		if err != nil {
+			t.Fatalf("%s: hashGitoid returned an error: %v", name, err)
+		}
+		if expected := gitoidURI(gitoid.BLOB, attestor.algorithm, root); uri != expected {
+			t.Errorf("%s: Expected URI %s, got %s", name, expected, uri)
+		}
+
+		terrapin, err := NewTerrapinWithAttestations(header)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithAttestations returned an error: %v", name, err)
+		}
+		if terrapin.NumChunks() != 0 {
+			t.Errorf("%s: Expected no chunks, got %d", name, terrapin.NumChunks())
+		}
+		if parsedURI, _, _ := terrapin.Finalize(); parsedURI != uri {
+			t.Errorf("%s: Expected URI %s, got %s", name, uri, parsedURI)
+		}
+
+		match, err := terrapin.VerifyBuffer(bytes.NewReader(nil))
+		if err != nil || !match {
+			t.Errorf("%s: Expected empty data to verify, got %v, %v", name, match, err)
+		}
+		match, err = terrapin.VerifyBuffer(bytes.NewReader([]byte{0}))
+		if err != nil || match {
+			t.Errorf("%s: Expected non-empty data to mismatch, got %v, %v", name, match, err)
+		}
+	}
+}
+
+func TestHashAlgorithms(t *testing.T) {
+	data := make([]byte, 2*1024+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+
+	for _, alg := range []Algorithm{SHA1, SHA256, SHA512} {
+		attestor, err := NewTerrapinWithOptions(WithHashAlgorithm(alg), WithBlockSize(1024))
+		if err != nil {
+			t.Fatalf("%s: failed to create terrapin: %v", alg, err)
+		}
+		if err := attestor.Add(data); err != nil {
+			t.Fatalf("%s: failed to add data: %v", alg, err)
+		}
+		rootURI, attestations, err := attestor.Finalize()
+		if err != nil {
+			t.Fatalf("%s: failed to finalize terrapin: %v", alg, err)
+		}
+		if !strings.HasPrefix(rootURI, "gitoid:blob:"+alg.String()+":") {
+			t.Errorf("%s: unexpected root URI %s", alg, rootURI)
+		}
+
+		terrapin, err := NewTerrapinWithAttestations(attestations)
+		if err != nil {
+			t.Fatalf("%s: failed to create terrapin with attestations: %v", alg, err)
+		}
+		if terrapin.algorithm != alg || len(terrapin.attestations) != 3*alg.Size() {
+			t.Fatalf("%s: expected 3 %d-byte hashes, got algorithm %s and %d bytes", alg, alg.Size(), terrapin.algorithm, len(terrapin.attestations))
+		}
+		match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+		if err != nil || !match {
+			t.Fatalf("%s: VerifyBuffer expected to match, got %v, %v", alg, match, err)
+		}
+	}
+
+	// SHA-1 chunk hashes match classic git blob IDs
+	attestor, _ := NewTerrapinWithOptions(WithHashAlgorithm(SHA1))
+	_ = attestor.Add(data)
+	_, attestations, _ := attestor.Finalize()
+	expected, _ := gitoid.New(bytes.NewReader(data))
+	if !bytes.HasSuffix(attestations, expected.Bytes()) {
+		t.Errorf("Expected SHA-1 chunk hash %x", expected.Bytes())
+	}
+}
+
+func TestInconsistentAlgorithmHeader(t *testing.T) {
+	var invalid *InvalidAttestationsError
+
+	// SHA-256 declared with a 64-byte digest size
+	blob := append([]byte("TRPN\x01\x06\x04\x01\x02\x05\x01\x40"), make([]byte, 64)...)
+	if _, err := NewTerrapinWithAttestations(blob); !errors.As(err, &invalid) {
+		t.Errorf("Expected InvalidAttestationsError for mismatched digest size, got %v", err)
+	}
+
+	// SHA-512 declared over a body of 32-byte hashes
+	blob = append([]byte("TRPN\x01\x03\x04\x01\x03"), make([]byte, 3*sha256.Size)...)
+	if _, err := NewTerrapinWithAttestations(blob); !errors.As(err, &invalid) {
+		t.Errorf("Expected InvalidAttestationsError for mismatched chunk digests, got %v", err)
+	}
+
+	// Unknown algorithm
+	blob = append([]byte("TRPN\x01\x03\x04\x01\x7f"), make([]byte, sha256.Size)...)
+	if _, err := NewTerrapinWithAttestations(blob); !errors.As(err, &invalid) {
+		t.Errorf("Expected InvalidAttestationsError for unknown algorithm, got %v", err)
+	}
+
+	// A consistent SHA-512 header is accepted
+	blob = append([]byte("TRPN\x01\x06\x04\x01\x03\x05\x01\x40"), make([]byte, 64)...)
+	if _, err := NewTerrapinWithAttestations(blob); err != nil {
+		t.Errorf("Expected consistent header to be accepted, got %v", err)
+	}
+}
+
+func TestEpochRoundTrip(t *testing.T) {
+	data := []byte("data attested in a later generation")
+	plain := NewTerrapin()
+	if err := plain.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	plainURI, _, err := plain.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	attestor, err := NewTerrapinWithOptions(WithEpoch(math.MaxUint64 - 1))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uri, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if uri != plainURI {
+		t.Errorf("Expected the epoch to be excluded from the root gitoid, got %s and %s", uri, plainURI)
+	}
+
+	restored, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	if restored.Epoch() != math.MaxUint64-1 {
+		t.Errorf("Expected epoch %d, got %d", uint64(math.MaxUint64-1), restored.Epoch())
+	}
+	restoredURI, restoredAttestations, err := restored.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if restoredURI != uri || !bytes.Equal(restoredAttestations, attestations) {
+		t.Errorf("Expected the restored attestations to round-trip unchanged")
+	}
+	valid, err := restored.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !valid {
+		t.Errorf("Expected data to verify, got %v, %v", valid, err)
+	}
+}
+
+func TestBlockSizeFromAttestations(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for name, tc := range map[string]struct {
+		opts     []Option
+		expected int
+	}{
+		"headerless": {expected: BufferCapacity},
+		"headered":   {opts: []Option{WithBlockSize(1024)}, expected: 1024},
+	} {
+		attestor, err := NewTerrapinWithOptions(tc.opts...)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithOptions returned an error: %v", name, err)
+		}
+		if err := attestor.Add(data); err != nil {
+			t.Fatalf("%s: Failed to add data: %v", name, err)
+		}
+		_, attestations, err := attestor.Finalize()
+		if err != nil {
+			t.Fatalf("%s: Failed to finalize terrapin: %v", name, err)
+		}
+
+		// The block size is recovered without being passed out of band
+		terrapin, err := NewTerrapinWithAttestations(attestations)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithAttestations returned an error: %v", name, err)
+		}
+		if terrapin.BlockSize() != tc.expected {
+			t.Errorf("%s: Expected block size %d, got %d", name, tc.expected, terrapin.BlockSize())
+		}
+		valid, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+		if err != nil || !valid {
+			t.Errorf("%s: Expected data to verify, got %v, %v", name, valid, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package terrapin
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+	"slices"
+)
+
+// jsonAttestations is the document produced by MarshalJSON, for example
+//
+//	{
+//	  "gitoid": "gitoid:blob:sha256:...",
+//	  "algorithm": "sha256",
+//	  "blockSize": 2097152,
+//	  "chunkCount": 2,
+//	  "chunks": ["0123...", "4567..."]
+//	}
+//
+// Variable-size chunks set variableChunks and add chunkLengths, one length per chunk, a nonzero epoch is
+// recorded as epoch, and the length of the attested data as size, when known
+type jsonAttestations struct {
+	Gitoid         string   `json:"gitoid"`                   // Root gitoid URI returned by Finalize
+	Algorithm      string   `json:"algorithm"`                // Name of the hash algorithm
+	BlockSize      int      `json:"blockSize"`                // Block size in bytes
+	ChunkCount     int      `json:"chunkCount"`               // Number of chunk hashes
+	VariableChunks bool     `json:"variableChunks,omitempty"` // Whether chunks may differ in size
+	ChunkLengths   []int    `json:"chunkLengths,omitempty"`   // Length of each chunk, for variable-size chunks
+	Epoch          uint64   `json:"epoch,omitempty"`          // Attestation generation, when set
+	Size           int64    `json:"size,omitempty"`           // Length of the attested data in bytes, when known
+	Chunks         []string `json:"chunks"`                   // Hex-encoded chunk hashes, in chunk order
+}
+
+// MarshalJSON returns the attestations of a finalized instance as a JSON document recording the root gitoid URI,
+// hash algorithm, block size and chunk count alongside the hex-encoded chunk hashes, for storage in metadata
+// systems such as OCI annotations
+// Merkle trees, raw or truncated chunk hashes and non-blob object types cannot be represented
+func (t *Terrapin) MarshalJSON() ([]byte, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	if t.merkle || !t.hasChunkURIs() || t.chunkType != gitoid.BLOB || t.rootType != gitoid.BLOB {
+		return nil, errors.New("only blob chunk gitoids without a Merkle tree can be represented in JSON")
+	}
+
+	chunks := make([]string, 0, t.NumChunks())
+	for i := 0; i < t.NumChunks(); i++ {
+		chunks = append(chunks, hex.EncodeToString(t.attestations[i*t.digestSize():(i+1)*t.digestSize()]))
+	}
+	return json.Marshal(jsonAttestations{
+		Gitoid:         t.rootURI,
+		Algorithm:      t.algorithm.String(),
+		BlockSize:      t.blockSize,
+		ChunkCount:     len(chunks),
+		VariableChunks: t.variable,
+		ChunkLengths:   t.chunkLengths,
+		Epoch:          t.epoch,
+		Size:           t.size,
+		Chunks:         chunks,
+	})
+}
+
+// NewTerrapinFromJSON initializes a Terrapin instance from the JSON document produced by MarshalJSON
+// Settings recorded in the document take precedence over opts. The recorded gitoid must match the one computed
+// from the chunk hashes, so a document whose chunks were altered is rejected
+func NewTerrapinFromJSON(data []byte, opts ...Option) (*Terrapin, error) {
+	var parsed jsonAttestations
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, &InvalidAttestationsError{Reason: err.Error()}
+	}
+	alg, err := ParseAlgorithm(parsed.Algorithm)
+	if err != nil {
+		return nil, &InvalidAttestationsError{Reason: err.Error()}
+	}
+	if parsed.ChunkCount != len(parsed.Chunks) {
+		return nil, &InvalidAttestationsError{
+			Reason: fmt.Sprintf("chunk count %d does not match %d chunk hashes", parsed.ChunkCount, len(parsed.Chunks)),
+		}
+	}
+
+	settings := []Option{WithHashAlgorithm(alg), WithBlockSize(parsed.BlockSize), WithEpoch(parsed.Epoch)}
+	if parsed.VariableChunks {
+		settings = append(settings, WithVariableChunks())
+	}
+	t, err := NewTerrapinWithOptions(slices.Concat(opts, settings)...)
+	if err != nil {
+		return nil, err
+	}
+	if t.sink != nil {
+		return nil, errors.New("attestation sink is only supported when attesting")
+	}
+	if t.merkle || t.chunker != nil {
+		return nil, errors.New("the JSON format does not support Merkle mode or chunkers")
+	}
+
+	// Every chunk must hold exactly one hash of the digest size
+	for i, chunk := range parsed.Chunks {
+		hash, err := hex.DecodeString(chunk)
+		if err != nil {
+			return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("chunk %d: invalid hex chunk hash", i)}
+		}
+		if len(hash) != t.digestSize() {
+			return nil, &InvalidAttestationsError{
+				Reason: fmt.Sprintf("chunk %d: chunk hash of %d bytes, expected %d", i, len(hash), t.digestSize()),
+			}
+		}
+		t.attestations = append(t.attestations, hash...)
+	}
+	if !t.variable && parsed.ChunkLengths != nil {
+		return nil, &InvalidAttestationsError{Reason: "chunk lengths are only recorded for variable-size chunks"}
+	}
+	if t.variable {
+		if len(parsed.ChunkLengths) != len(parsed.Chunks) {
+			return nil, &InvalidAttestationsError{Reason: "chunk lengths do not match the number of chunk hashes"}
+		}
+		for i, length := range parsed.ChunkLengths {
+			if length < 1 || length > MaxBlockSize {
+				return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("chunk %d: invalid length %d", i, length)}
+			}
+		}
+		t.chunkLengths = parsed.ChunkLengths
+	}
+
+	// A recorded size must end within the last chunk, and exactly at its end for variable-size chunks
+	if parsed.Size != 0 {
+		end := t.VerifiablePrefix()
+		if t.NumChunks() == 0 || parsed.Size > end || parsed.Size <= end-int64(t.chunkLength(t.NumChunks()-1)) ||
+			(t.variable && parsed.Size != end) {
+			return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("size %d does not match the chunks", parsed.Size)}
+		}
+		t.size = parsed.Size
+	}
+
+	uri, _, err := t.Finalize()
+	if err != nil {
+		return nil, err
+	}
+	if uri != parsed.Gitoid {
+		return nil, &InvalidAttestationsError{Reason: "gitoid does not match the chunk hashes"}
+	}
+	return t, nil
+}
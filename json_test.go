@@ -0,0 +1,137 @@
+package terrapin
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024), WithHashAlgorithm(SHA1), WithEpoch(3))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if _, err := attestor.MarshalJSON(); err == nil {
+		t.Error("Expected an error marshaling an unfinalized instance")
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uri, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	document, err := json.Marshal(attestor)
+	if err != nil {
+		t.Fatalf("json.Marshal returned an error: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(document, &fields); err != nil {
+		t.Fatalf("Failed to parse JSON document: %v", err)
+	}
+	if fields["gitoid"] != uri || fields["algorithm"] != "sha1" || fields["blockSize"] != 1024.0 || fields["chunkCount"] != 4.0 ||
+		fields["size"] != float64(len(data)) {
+		t.Errorf("Unexpected JSON document %s", document)
+	}
+
+	terrapin, err := NewTerrapinFromJSON(document)
+	if err != nil {
+		t.Fatalf("NewTerrapinFromJSON returned an error: %v", err)
+	}
+	parsedURI, parsedAttestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if parsedURI != uri || !bytes.Equal(parsedAttestations, attestations) {
+		t.Error("Expected the JSON document to round-trip the attestations")
+	}
+	valid, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !valid {
+		t.Errorf("Expected data to verify, got %v, %v", valid, err)
+	}
+	if terrapin.Size() != int64(len(data)) {
+		t.Errorf("Expected the recorded size %d, got %d", len(data), terrapin.Size())
+	}
+
+	// The document's settings are never appended into spare capacity of the caller's options
+	opts := make([]Option, 0, 8)
+	if _, err := NewTerrapinFromJSON(document, opts...); err != nil {
+		t.Fatalf("NewTerrapinFromJSON returned an error: %v", err)
+	}
+	if slices.ContainsFunc(opts[:cap(opts)], func(opt Option) bool { return opt != nil }) {
+		t.Error("Expected the caller's options to be left untouched")
+	}
+	if err := terrapin.Continue(); err == nil {
+		t.Error("Expected an error continuing attestations loaded from JSON")
+	}
+
+	// A document whose chunk hashes were altered no longer matches its gitoid
+	chunk := fields["chunks"].([]any)[1].(string)
+	altered := strings.Replace(string(document), chunk, strings.Repeat("0", len(chunk)), 1)
+	if _, err := NewTerrapinFromJSON([]byte(altered)); err == nil {
+		t.Error("Expected an error for altered chunk hashes")
+	}
+}
+
+func TestMarshalJSONVariableChunks(t *testing.T) {
+	attestor, err := NewTerrapinWithOptions(WithVariableChunks())
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	for _, record := range []string{"first record", "second", "third record of the stream"} {
+		if err := attestor.AddChunk([]byte(record)); err != nil {
+			t.Fatalf("AddChunk returned an error: %v", err)
+		}
+	}
+	uri, _, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	document, err := attestor.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON returned an error: %v", err)
+	}
+	terrapin, err := NewTerrapinFromJSON(document)
+	if err != nil {
+		t.Fatalf("NewTerrapinFromJSON returned an error: %v", err)
+	}
+	parsedURI, _, err := terrapin.Finalize()
+	if err != nil || parsedURI != uri {
+		t.Errorf("Expected gitoid %s, got %s, %v", uri, parsedURI, err)
+	}
+	valid, err := terrapin.VerifyBuffer(strings.NewReader("first recordsecondthird record of the stream"))
+	if err != nil || !valid {
+		t.Errorf("Expected data to verify, got %v, %v", valid, err)
+	}
+}
+
+func TestNewTerrapinFromJSONInvalid(t *testing.T) {
+	hash := `"` + strings.Repeat("ab", 32) + `"`
+	for name, document := range map[string]string{
+		"not json":          `{"chunks": [`,
+		"unknown algo":      `{"algorithm": "md5", "blockSize": 1024, "chunkCount": 1, "chunks": [` + hash + `]}`,
+		"count mismatch":    `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 2, "chunks": [` + hash + `]}`,
+		"not hex":           `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 1, "chunks": ["xyz"]}`,
+		"wrong size":        `{"algorithm": "sha1", "blockSize": 1024, "chunkCount": 1, "chunks": [` + hash + `]}`,
+		"tiny block size":   `{"algorithm": "sha256", "blockSize": 1, "chunkCount": 1, "chunks": [` + hash + `]}`,
+		"lengths mismatch":  `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 1, "variableChunks": true, "chunks": [` + hash + `]}`,
+		"lengths not fixed": `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 1, "chunkLengths": [5], "chunks": [` + hash + `]}`,
+		"size too large":    `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 1, "size": 1025, "chunks": [` + hash + `]}`,
+		"negative size":     `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 1, "size": -1, "chunks": [` + hash + `]}`,
+		"size without data": `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 0, "size": 5, "chunks": []}`,
+		"size not at end":   `{"algorithm": "sha256", "blockSize": 1024, "chunkCount": 1, "variableChunks": true, "chunkLengths": [5], "size": 4, "chunks": [` + hash + `]}`,
+		"wrong gitoid":      `{"gitoid": "gitoid:blob:sha256:00", "algorithm": "sha256", "blockSize": 1024, "chunkCount": 1, "chunks": [` + hash + `]}`,
+	} {
+		if _, err := NewTerrapinFromJSON([]byte(document)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Merkle trees are built over the chunk hashes, hashing leaves and interior nodes with distinct prefixes as
+// in RFC 6962 so that an interior node can never be presented as a leaf. Each node is the digest, using the
+// attestation's hash algorithm and truncated to its digest size, of a one-byte prefix followed by its inputs:
+//
+//	leaf = H(0x00 || chunk hash)
+//	node = H(0x01 || left || right)
+//
+// When a level has an odd number of nodes, the last one is promoted unchanged to the next level.
+// Merkle-mode attestations store the chunk hashes followed by every level of the tree, bottom-up from the
+// leaves, each level complete including promoted nodes, so the final hash is the root. A single leaf is the
+// root, and an empty attestation has no tree.
+
+// Domain separation prefixes of Merkle tree hashes
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// merkleHash returns the digest of prefix followed by the given inputs, truncated to size
+func merkleHash(info algorithmInfo, size int, prefix byte, inputs ...[]byte) []byte {
+	h := info.newHash()
+	h.Write([]byte{prefix})
+	for _, input := range inputs {
+		h.Write(input)
+	}
+	return h.Sum(nil)[:size]
+}
+
+// merkleLevels returns the levels of the Merkle tree over the given chunk hashes, bottom-up from the leaves
+func (t *Terrapin) merkleLevels(attestations []byte) [][][]byte {
+	info, _ := t.algorithm.info()
+	var level [][]byte
+	for i := 0; i+t.digestSize() <= len(attestations); i += t.digestSize() {
+		level = append(level, merkleHash(info, t.digestSize(), merkleLeafPrefix, attestations[i:i+t.digestSize()]))
+	}
+	if len(level) == 0 {
+		return nil
+	}
+
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i+1 < len(level); i += 2 {
+			next = append(next, merkleHash(info, t.digestSize(), merkleNodePrefix, level[i], level[i+1]))
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// MerkleRoot returns the root of the Merkle tree over the chunk hashes, built as described above whether or not
+// the attestations are in Merkle mode. Unlike the gitoid URI returned by Finalize, which hashes the whole
+// attestations blob, the root supports inclusion proofs for single chunks
+func (t *Terrapin) MerkleRoot() ([]byte, error) {
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	if len(t.attestations) == 0 {
+		return nil, errors.New("empty attestations have no Merkle root")
+	}
+	levels := t.merkleLevels(t.attestations)
+	return bytes.Clone(levels[len(levels)-1][0]), nil
+}
+
+// InclusionProof returns the sibling hashes on the path from the leaf of the chunk at chunkIndex up to the
+// Merkle root, bottom-up, with a nil entry for each level where the path's node is promoted and has no sibling
+// Together with the chunk hash, the chunk count and the root, the proof lets VerifyInclusionProof establish
+// that the chunk belongs to the attested data without the rest of the attestations
+func (t *Terrapin) InclusionProof(chunkIndex int) ([][]byte, error) {
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	if chunkIndex < 0 || chunkIndex >= t.NumChunks() {
+		return nil, errors.New("chunk index out of range")
+	}
+
+	levels := t.merkleLevels(t.attestations)
+	var proof [][]byte
+	index := chunkIndex
+	for _, level := range levels[:len(levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof = append(proof, bytes.Clone(level[sibling]))
+		} else {
+			proof = append(proof, nil) // Promoted
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyInclusionProof reports whether proof, as returned by InclusionProof, shows that leaf is the chunk hash
+// at index in the Merkle tree over the given number of leaves with the given root, whose nodes are hashed
+// with alg. The proof must have exactly the length and promoted levels of the path from index in that tree
+func VerifyInclusionProof(alg Algorithm, root, leaf []byte, index, leaves int, proof [][]byte) bool {
+	info, ok := alg.info()
+	if !ok || index < 0 || index >= leaves || len(root) == 0 || len(root) > info.size || len(leaf) != len(root) {
+		return false
+	}
+
+	node := merkleHash(info, len(root), merkleLeafPrefix, leaf)
+	for size := leaves; size > 1; size = (size + 1) / 2 {
+		if len(proof) == 0 {
+			return false
+		}
+		sibling := proof[0]
+		proof = proof[1:]
+
+		// The node is promoted exactly when it is the last of an odd-sized level
+		if promoted := index^1 >= size; promoted != (sibling == nil) {
+			return false
+		}
+		switch {
+		case sibling == nil:
+		case len(sibling) != len(root):
+			return false
+		case index%2 == 0:
+			node = merkleHash(info, len(root), merkleNodePrefix, node, sibling)
+		default:
+			node = merkleHash(info, len(root), merkleNodePrefix, sibling, node)
+		}
+		index /= 2
+	}
+	return len(proof) == 0 && bytes.Equal(node, root)
+}
+
+// splitMerkle separates the chunk hashes of a Merkle-mode body from its tree levels, ensuring the
+// stored nodes are those computed from the chunk hashes
+func (t *Terrapin) splitMerkle(body []byte, chunks int) ([]byte, error) {
+	leavesSize := chunks * t.digestSize()
+	if leavesSize > len(body) {
+		return nil, &InvalidAttestationsError{Reason: "Merkle attestations shorter than their chunk count"}
+	}
+	leaves := body[:leavesSize]
+
+	var expected []byte
+	for _, level := range t.merkleLevels(leaves) {
+		for _, node := range level {
+			expected = append(expected, node...)
+		}
+	}
+	if !bytes.Equal(body[leavesSize:], expected) {
+		return nil, &InvalidAttestationsError{Reason: "Merkle tree does not match its chunk hashes"}
+	}
+	return leaves, nil
+}
+
+// FlattenMerkle converts Merkle-mode attestations into standard flat attestations holding the same
+// chunk hashes and settings, for use with tools that do not understand Merkle mode
+// The result verifies the same data as the original attestations
+func FlattenMerkle(merkleBlob []byte) ([]byte, error) {
+	t, err := NewTerrapinWithAttestations(merkleBlob, WithEmptyAttestations())
+	if err != nil {
+		return nil, err
+	}
+	if !t.merkle {
+		return nil, &InvalidAttestationsError{Reason: "not Merkle-mode attestations"}
+	}
+	t.merkle = false
+	return t.blob(t.attestations), nil
+}
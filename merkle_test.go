@@ -0,0 +1,268 @@
+package terrapin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFlattenMerkle(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+
+	attestor, err := NewTerrapinWithOptions(WithMerkle())
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, merkleBlob, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// Five chunk hashes give levels of 5, 3, 2 and 1 nodes
+	flatAttestor, _ := setupTerrapinWithData(t, data)
+	_, flatExpectedBlob, _ := flatAttestor.Finalize()
+	if !bytes.HasSuffix(merkleBlob[:len(merkleBlob)-11*sha256.Size], flatExpectedBlob) {
+		t.Fatalf("Expected the chunk hashes to precede the tree")
+	}
+	hashes := flatExpectedBlob
+	leaf := func(i int) []byte {
+		h := sha256.Sum256(append([]byte{0x00}, hashes[i*32:(i+1)*32]...))
+		return h[:]
+	}
+	node := func(left, right []byte) []byte {
+		h := sha256.New()
+		h.Write([]byte{0x01})
+		h.Write(left)
+		h.Write(right)
+		return h.Sum(nil)
+	}
+	n01 := node(leaf(0), leaf(1))
+	n23 := node(leaf(2), leaf(3))
+	root := node(node(n01, n23), leaf(4))
+	if !bytes.HasSuffix(merkleBlob, root) {
+		t.Fatalf("Expected the Merkle root %x to end the attestations", root)
+	}
+
+	merkleTerrapin, err := NewTerrapinWithAttestations(merkleBlob)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with Merkle attestations: %v", err)
+	}
+	if match, err := merkleTerrapin.VerifyBuffer(bytes.NewReader(data)); err != nil || !match {
+		t.Fatalf("VerifyBuffer expected to match, got %v, %v", match, err)
+	}
+
+	flat, err := FlattenMerkle(merkleBlob)
+	if err != nil {
+		t.Fatalf("FlattenMerkle returned an error: %v", err)
+	}
+	if !bytes.Equal(flat, flatExpectedBlob) {
+		t.Fatalf("Expected flattened attestations to equal flat attestations of the same data")
+	}
+	flatTerrapin, err := NewTerrapinWithAttestations(flat)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with flattened attestations: %v", err)
+	}
+	if match, err := flatTerrapin.VerifyBuffer(bytes.NewReader(data)); err != nil || !match {
+		t.Fatalf("VerifyBuffer expected to match, got %v, %v", match, err)
+	}
+
+	if _, err := FlattenMerkle(flat); err == nil {
+		t.Fatalf("FlattenMerkle expected to reject flat attestations")
+	}
+	if _, err := FlattenMerkle(nil); err == nil || !strings.Contains(err.Error(), "not Merkle-mode") {
+		t.Errorf("Expected empty attestations to be rejected as not Merkle-mode, got %v", err)
+	}
+}
+
+func TestMerkleTamperedTree(t *testing.T) {
+	attestor, _ := NewTerrapinWithOptions(WithMerkle(), WithBlockSize(1024))
+	if err := attestor.Add(make([]byte, 3*1024)); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, merkleBlob, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	merkleBlob[len(merkleBlob)-1] ^= 0xff
+	if _, err := NewTerrapinWithAttestations(merkleBlob); err == nil {
+		t.Fatalf("Expected a tampered Merkle tree to be rejected")
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	data := make([]byte, 2*1024+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+
+	// The flat and Merkle-mode attestations of the same data share the root, which ends the latter
+	flat, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	merkle, err := NewTerrapinWithOptions(WithBlockSize(1024), WithMerkle())
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	if _, err := flat.MerkleRoot(); err == nil {
+		t.Error("Expected error before finalization, got nil")
+	}
+	var merkleBlob []byte
+	for _, attestor := range []*Terrapin{flat, merkle} {
+		if err := attestor.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if _, merkleBlob, err = attestor.Finalize(); err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+	}
+	leaf := func(hash []byte) []byte {
+		h := sha256.Sum256(append([]byte{0x00}, hash...))
+		return h[:]
+	}
+	node := func(left, right []byte) []byte {
+		h := sha256.Sum256(slices.Concat([]byte{0x01}, left, right))
+		return h[:]
+	}
+	hashes := flat.attestations
+
+	// Three leaves: the first two combine and the third is promoted
+	expected := node(node(leaf(hashes[0:32]), leaf(hashes[32:64])), leaf(hashes[64:96]))
+	for name, attestor := range map[string]*Terrapin{"flat": flat, "merkle": merkle} {
+		root, err := attestor.MerkleRoot()
+		if err != nil {
+			t.Fatalf("%s: MerkleRoot returned an error: %v", name, err)
+		}
+		if !bytes.Equal(root, expected) {
+			t.Errorf("%s: expected root %x, got %x", name, expected, root)
+		}
+	}
+	if !bytes.HasSuffix(merkleBlob, expected) {
+		t.Error("Expected the root to end the Merkle-mode attestations")
+	}
+
+	// A single leaf is the root, and empty attestations have none
+	single, _ := setupTerrapinWithData(t, data[:100])
+	if root, err := single.MerkleRoot(); err != nil || !bytes.Equal(root, leaf(single.attestations)) {
+		t.Errorf("Expected the single leaf as root, got %x, %v", root, err)
+	}
+	empty, _ := setupTerrapinWithData(t, nil)
+	if _, err := empty.MerkleRoot(); err == nil {
+		t.Error("Expected error for empty attestations, got nil")
+	}
+}
+
+func TestInclusionProof(t *testing.T) {
+	data := make([]byte, 4*1024+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	for _, digestSize := range []int{32, 16} {
+		attestor, err := NewTerrapinWithOptions(WithBlockSize(1024), WithDigestSize(digestSize))
+		if err != nil {
+			t.Fatalf("Failed to create terrapin: %v", err)
+		}
+		if err := attestor.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if _, _, err := attestor.Finalize(); err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		root, err := attestor.MerkleRoot()
+		if err != nil {
+			t.Fatalf("MerkleRoot returned an error: %v", err)
+		}
+
+		// Five leaves: every proof has three levels, and the last leaf is promoted twice
+		chunks := attestor.NumChunks()
+		for index := 0; index < chunks; index++ {
+			leaf := attestor.attestations[index*digestSize : (index+1)*digestSize]
+			proof, err := attestor.InclusionProof(index)
+			if err != nil {
+				t.Fatalf("InclusionProof(%d) returned an error: %v", index, err)
+			}
+			if len(proof) != 3 {
+				t.Fatalf("Expected 3 proof levels for chunk %d, got %d", index, len(proof))
+			}
+			if !VerifyInclusionProof(SHA256, root, leaf, index, chunks, proof) {
+				t.Errorf("%d-byte digests: expected the proof of chunk %d to verify", digestSize, index)
+			}
+
+			// A tampered leaf, tampered proof element, or other index does not verify
+			tamperedLeaf := bytes.Clone(leaf)
+			tamperedLeaf[0] ^= 0xff
+			if VerifyInclusionProof(SHA256, root, tamperedLeaf, index, chunks, proof) {
+				t.Errorf("Expected a tampered leaf for chunk %d to fail", index)
+			}
+			for i, sibling := range proof {
+				if sibling == nil {
+					continue
+				}
+				tampered := slices.Clone(proof)
+				tampered[i] = bytes.Clone(sibling)
+				tampered[i][len(sibling)-1] ^= 0x01
+				if VerifyInclusionProof(SHA256, root, leaf, index, chunks, tampered) {
+					t.Errorf("Expected the proof of chunk %d with element %d tampered to fail", index, i)
+				}
+			}
+			for _, other := range []int{index ^ 1, index + 8} {
+				if other != index && VerifyInclusionProof(SHA256, root, leaf, other, chunks, proof) {
+					t.Errorf("Expected the proof of chunk %d to fail for index %d", index, other)
+				}
+			}
+
+			// The proof must have the shape of the path in a tree of the given leaf count: four leaves give
+			// shorter paths, and with six the last leaf is no longer promoted
+			for _, leaves := range []int{chunks - 1, chunks + 1} {
+				if (leaves < chunks || index == chunks-1) && VerifyInclusionProof(SHA256, root, leaf, index, leaves, proof) {
+					t.Errorf("Expected the proof of chunk %d to fail for %d leaves", index, leaves)
+				}
+			}
+			if VerifyInclusionProof(SHA256, root, leaf, index, chunks, proof[:len(proof)-1]) {
+				t.Errorf("Expected the truncated proof of chunk %d to fail", index)
+			}
+			if VerifyInclusionProof(SHA256, root, leaf, index, chunks, append(slices.Clone(proof), nil)) {
+				t.Errorf("Expected the extended proof of chunk %d to fail", index)
+			}
+		}
+
+		// An interior node passed as a leaf, with the remainder of a real proof, does not verify
+		levels := attestor.merkleLevels(attestor.attestations)
+		proof, _ := attestor.InclusionProof(0)
+		for depth := 1; depth < len(levels)-1; depth++ {
+			interior := levels[depth][0]
+			if VerifyInclusionProof(SHA256, root, interior, 0, len(levels[depth]), proof[depth:]) {
+				t.Errorf("Expected the interior node at level %d to be rejected as a leaf", depth)
+			}
+		}
+
+		// A promoted node's missing sibling cannot be hidden, nor a sibling invented for it
+		last, _ := attestor.InclusionProof(chunks - 1)
+		forged := slices.Clone(last)
+		forged[0] = make([]byte, digestSize)
+		if VerifyInclusionProof(SHA256, root, attestor.attestations[(chunks-1)*digestSize:], chunks-1, chunks, forged) {
+			t.Error("Expected a sibling for a promoted node to fail")
+		}
+		hidden := slices.Clone(proof)
+		hidden[0] = nil
+		if VerifyInclusionProof(SHA256, root, attestor.attestations[:digestSize], 0, chunks, hidden) {
+			t.Error("Expected a missing sibling to fail")
+		}
+
+		if VerifyInclusionProof(SHA1, root, attestor.attestations[:digestSize], 0, chunks, proof) {
+			t.Error("Expected the proof to fail with another algorithm")
+		}
+		if _, err := attestor.InclusionProof(attestor.NumChunks()); err == nil {
+			t.Error("Expected error for an out of range chunk, got nil")
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package terrapin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTerrapinFromMmap(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin := NewTerrapin()
+	if err := terrapin.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uri, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "data.attestations")
+	if err := os.WriteFile(path, attestations, 0644); err != nil {
+		t.Fatalf("Failed to write attestations: %v", err)
+	}
+
+	mapped, err := NewTerrapinFromMmap(path)
+	if err != nil {
+		t.Fatalf("NewTerrapinFromMmap returned an error: %v", err)
+	}
+	defer mapped.Close()
+
+	mappedURI, _, err := mapped.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	if mappedURI != uri {
+		t.Errorf("Expected URI %s, got %s", uri, mappedURI)
+	}
+
+	match, err := mapped.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+
+	data[BufferCapacity+1] ^= 0xff
+	match, err = mapped.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if match {
+		t.Fatalf("VerifyBuffer expected to mismatch, but it matched")
+	}
+
+	if err := mapped.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+	if err := mapped.Close(); err != nil {
+		t.Fatalf("Second Close returned an error: %v", err)
+	}
+}
+
+func TestNewTerrapinFromMmap_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewTerrapinFromMmap(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+
+	path := filepath.Join(dir, "invalid.attestations")
+	if err := os.WriteFile(path, make([]byte, 33), 0644); err != nil {
+		t.Fatalf("Failed to write attestations: %v", err)
+	}
+	if _, err := NewTerrapinFromMmap(path); err == nil {
+		t.Errorf("Expected an error for attestations of invalid length")
+	}
+}
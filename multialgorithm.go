@@ -0,0 +1,180 @@
+package terrapin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Multi-algorithm attestations hold the attestations of the same data under several hash algorithms, such as
+// SHA-1 for legacy git tooling alongside SHA-256, produced in a single pass by MultiTerrapin. A header declaring
+// the set of algorithms precedes one section per algorithm, each a complete attestations blob as Finalize would
+// return it for that algorithm:
+//
+//	magic     4 bytes  "TRPM"
+//	version   1 byte   multiVersion
+//	count     uvarint  number of sections
+//	sections  repeated (algorithm uvarint, section length uvarint), in the order the sections follow
+//	bodies    the attestations of each section
+//
+// Uvarints are encoded as in the attestations header. Use SelectAlgorithm to extract the section to verify with.
+
+// multiMagic identifies multi-algorithm attestations
+var multiMagic = []byte("TRPM")
+
+// multiVersion is the version of the multi-algorithm layout written by this package
+const multiVersion = 1
+
+// MultiTerrapin attests data under several hash algorithms at once, feeding every chunk to one Terrapin per
+// algorithm so the data is only read once
+type MultiTerrapin struct {
+	algorithms []Algorithm // Algorithms in the order their sections are written
+	instances  []*Terrapin // Instance attesting with each algorithm
+}
+
+// NewMultiTerrapin returns a MultiTerrapin attesting with each of algs, which must be distinct
+// opts apply to every algorithm; any hash algorithm they set is overridden
+func NewMultiTerrapin(algs []Algorithm, opts ...Option) (*MultiTerrapin, error) {
+	if len(algs) == 0 {
+		return nil, errors.New("at least one hash algorithm is required")
+	}
+
+	m := &MultiTerrapin{}
+	for _, alg := range algs {
+		if slices.Contains(m.algorithms, alg) {
+			return nil, fmt.Errorf("duplicate hash algorithm %s", alg)
+		}
+		t, err := NewTerrapinWithOptions(append(opts, WithHashAlgorithm(alg))...)
+		if err != nil {
+			return nil, err
+		}
+		if t.sink != nil {
+			return nil, errors.New("attestation sink cannot be combined with multiple algorithms")
+		}
+		m.algorithms = append(m.algorithms, alg)
+		m.instances = append(m.instances, t)
+	}
+	return m, nil
+}
+
+// Add feeds data to the attestation of every algorithm
+func (m *MultiTerrapin) Add(data []byte) error {
+	for _, t := range m.instances {
+		if err := t.Add(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finalize completes the attestation of every algorithm and returns their root gitoid URIs, in the order the
+// algorithms were given, along with the combined multi-algorithm attestations
+func (m *MultiTerrapin) Finalize() ([]string, []byte, error) {
+	var uris []string
+	var sections [][]byte
+	for _, t := range m.instances {
+		uri, attestations, err := t.Finalize()
+		if err != nil {
+			return nil, nil, err
+		}
+		uris = append(uris, uri)
+		sections = append(sections, attestations)
+	}
+
+	blob := append([]byte(nil), multiMagic...)
+	blob = append(blob, multiVersion)
+	blob = binary.AppendUvarint(blob, uint64(len(sections)))
+	for i, section := range sections {
+		blob = binary.AppendUvarint(blob, uint64(m.algorithms[i]))
+		blob = binary.AppendUvarint(blob, uint64(len(section)))
+	}
+	return uris, bytes.Join(append([][]byte{blob}, sections...), nil), nil
+}
+
+// MultiAlgorithms returns the algorithms declared by multi-algorithm attestations, in the order of their sections
+func MultiAlgorithms(attestations []byte) ([]Algorithm, error) {
+	sections, err := parseMulti(attestations)
+	if err != nil {
+		return nil, err
+	}
+	algs := make([]Algorithm, len(sections))
+	for i, section := range sections {
+		algs[i] = section.algorithm
+	}
+	return algs, nil
+}
+
+// SelectAlgorithm returns the attestations for alg held by multi-algorithm attestations, ready to be passed to
+// NewTerrapinWithAttestations, so verification can use whichever of the attested algorithms the caller trusts
+func SelectAlgorithm(attestations []byte, alg Algorithm) ([]byte, error) {
+	sections, err := parseMulti(attestations)
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if section.algorithm == alg {
+			return section.attestations, nil
+		}
+	}
+	return nil, fmt.Errorf("attestations do not include hash algorithm %s", alg)
+}
+
+// multiSection is the attestations of a single algorithm within multi-algorithm attestations
+type multiSection struct {
+	algorithm    Algorithm // Hash algorithm of the section
+	attestations []byte    // Attestations blob of the section
+}
+
+// parseMulti splits multi-algorithm attestations into their sections
+func parseMulti(blob []byte) ([]multiSection, error) {
+	if !bytes.HasPrefix(blob, multiMagic) {
+		return nil, &InvalidAttestationsError{Reason: "not multi-algorithm attestations"}
+	}
+	rest := blob[len(multiMagic):]
+	if len(rest) == 0 || rest[0] != multiVersion {
+		return nil, &InvalidAttestationsError{Reason: "unsupported multi-algorithm version"}
+	}
+	rest = rest[1:]
+
+	count, n := uvarint(rest)
+	if n <= 0 || count > uint64(len(rest)) {
+		return nil, &InvalidAttestationsError{Reason: "malformed section count"}
+	}
+	rest = rest[n:]
+
+	// Read the declared set, then slice each section from the bodies that follow
+	sections := make([]multiSection, count)
+	lengths := make([]uint64, count)
+	for i := range sections {
+		id, n := uvarint(rest)
+		if n <= 0 || id > 255 {
+			return nil, &InvalidAttestationsError{Reason: "malformed section algorithm"}
+		}
+		rest = rest[n:]
+		length, n := uvarint(rest)
+		if n <= 0 {
+			return nil, &InvalidAttestationsError{Reason: "malformed section length"}
+		}
+		rest = rest[n:]
+		for _, section := range sections[:i] {
+			if section.algorithm == Algorithm(id) {
+				return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("duplicate section for hash algorithm %s", section.algorithm)}
+			}
+		}
+		sections[i].algorithm = Algorithm(id)
+		lengths[i] = length
+	}
+	for i := range sections {
+		if lengths[i] > uint64(len(rest)) {
+			return nil, &InvalidAttestationsError{Reason: "truncated section"}
+		}
+		sections[i].attestations = rest[:lengths[i]]
+		rest = rest[lengths[i]:]
+	}
+	if len(rest) != 0 {
+		return nil, &InvalidAttestationsError{Reason: "trailing data after the last section"}
+	}
+	return sections, nil
+}
@@ -0,0 +1,80 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMultiTerrapin(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	multi, err := NewMultiTerrapin([]Algorithm{SHA1, SHA256}, WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewMultiTerrapin returned an error: %v", err)
+	}
+	if err := multi.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uris, attestations, err := multi.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize: %v", err)
+	}
+
+	algs, err := MultiAlgorithms(attestations)
+	if err != nil || !slices.Equal(algs, []Algorithm{SHA1, SHA256}) {
+		t.Fatalf("Expected the header to declare sha1 and sha256, got %v, %v", algs, err)
+	}
+
+	corrupt := bytes.Clone(data)
+	corrupt[2*1024] ^= 0xff
+	for i, alg := range algs {
+		// Each section matches a single-algorithm attestation of the same data
+		single, err := NewTerrapinWithOptions(WithBlockSize(1024), WithHashAlgorithm(alg))
+		if err != nil {
+			t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+		}
+		if err := single.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		uri, expected, err := single.Finalize()
+		if err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		section, err := SelectAlgorithm(attestations, alg)
+		if err != nil {
+			t.Fatalf("SelectAlgorithm(%s) returned an error: %v", alg, err)
+		}
+		if uris[i] != uri || !bytes.Equal(section, expected) {
+			t.Errorf("Expected the %s section to match a single-algorithm attestation", alg)
+		}
+
+		verifier, err := NewTerrapinWithAttestations(section)
+		if err != nil {
+			t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+		}
+		if valid, err := verifier.VerifyBuffer(bytes.NewReader(data)); err != nil || !valid {
+			t.Errorf("Expected data to verify with %s, got %v, %v", alg, valid, err)
+		}
+		if valid, err := verifier.VerifyBuffer(bytes.NewReader(corrupt)); err != nil || valid {
+			t.Errorf("Expected corrupt data to fail with %s, got %v, %v", alg, valid, err)
+		}
+	}
+
+	if _, err := SelectAlgorithm(attestations, SHA512); err == nil {
+		t.Error("Expected error selecting an algorithm that was not attested, got nil")
+	}
+	var invalid *InvalidAttestationsError
+	if _, err := SelectAlgorithm(attestations[:len(attestations)-1], SHA1); !errors.As(err, &invalid) {
+		t.Errorf("Expected InvalidAttestationsError for truncated attestations, got %v", err)
+	}
+	if _, err := NewMultiTerrapin([]Algorithm{SHA256, SHA256}); err == nil {
+		t.Error("Expected error for duplicate algorithms, got nil")
+	}
+	if _, err := NewMultiTerrapin(nil); err == nil {
+		t.Error("Expected error without algorithms, got nil")
+	}
+}
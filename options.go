@@ -0,0 +1,222 @@
+package terrapin
+
+import (
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+	"golang.org/x/time/rate"
+	"io"
+	"strings"
+)
+
+// Option configures a Terrapin instance at construction time
+type Option func(t *Terrapin) error
+
+// WithFileGitoid additionally computes the gitoid of the whole file while data is added, retrievable
+// with FileGitoid after Finalize. The gitoid header embeds the content length, so the exact length of
+// the file must be declared up front; Add and Finalize return an error if the data does not match it
+func WithFileGitoid(contentLength int64) Option {
+	return func(t *Terrapin) error {
+		if contentLength < 0 {
+			return errors.New("file length must not be negative")
+		}
+		t.fileGitoid = true
+		t.fileLength = contentLength
+		return nil
+	}
+}
+
+// WithBlockSize sets the size of each attested chunk, which defaults to BufferCapacity
+// Smaller blocks allow finer-grained range verification and lower memory use at the cost of larger attestations
+// Sizes below MinBlockSize are rejected to prevent attestations from dwarfing the data
+func WithBlockSize(size int) Option {
+	return func(t *Terrapin) error {
+		if size < MinBlockSize {
+			return fmt.Errorf("block size %d is below the minimum of %d bytes", size, MinBlockSize)
+		}
+		if size > MaxBlockSize {
+			return fmt.Errorf("block size %d exceeds the maximum of %d bytes", size, MaxBlockSize)
+		}
+		t.blockSize = size
+		return nil
+	}
+}
+
+// WithChunkType sets the git object type used when computing each chunk's gitoid, which defaults to gitoid.BLOB
+func WithChunkType(objectType gitoid.GitObjectType) Option {
+	return func(t *Terrapin) error {
+		if err := validateObjectType(objectType); err != nil {
+			return err
+		}
+		t.chunkType = objectType
+		return nil
+	}
+}
+
+// WithRootType sets the git object type of the root gitoid returned by Finalize, which defaults to gitoid.BLOB
+// This allows the root to be tagged with a custom type such as "terrapin" while chunks remain blobs
+func WithRootType(objectType gitoid.GitObjectType) Option {
+	return func(t *Terrapin) error {
+		if err := validateObjectType(objectType); err != nil {
+			return err
+		}
+		t.rootType = objectType
+		return nil
+	}
+}
+
+// validateObjectType ensures a git object type can be embedded in both gitoid headers and URIs
+func validateObjectType(objectType gitoid.GitObjectType) error {
+	if objectType == "" || strings.ContainsAny(string(objectType), ": \x00") {
+		return fmt.Errorf("invalid git object type %q", objectType)
+	}
+	return nil
+}
+
+// WithAttestationSink streams the attestations to w as they are produced: the header, if any, followed by
+// each chunk hash as soon as its chunk completes, with the final chunk hash written by Finalize
+// Once Finalize succeeds, w has received exactly the attestations Finalize returns
+// The chunk hashes are still retained in memory, so the attestations slice grows as without a sink. The root
+// gitoid hashes a header holding the length of the whole attestations blob before the blob itself, so it can
+// only be computed once the last chunk is known, and Finalize returns the blob and the instance verifies against
+// it afterwards. At one digest, 32 bytes with the defaults, per 2MB chunk they are small compared to the data
+func WithAttestationSink(w io.Writer) Option {
+	return func(t *Terrapin) error {
+		if w == nil {
+			return errors.New("attestation sink must not be nil")
+		}
+		t.sink = w
+		return nil
+	}
+}
+
+// WithHashAlgorithm sets the hash algorithm used for chunk and root gitoids, which defaults to SHA256
+func WithHashAlgorithm(alg Algorithm) Option {
+	return func(t *Terrapin) error {
+		if _, ok := alg.info(); !ok {
+			return fmt.Errorf("unsupported hash algorithm %s", alg)
+		}
+		t.algorithm = alg
+		return nil
+	}
+}
+
+// WithMerkle produces Merkle-mode attestations, which follow the chunk hashes with every level of a
+// binary Merkle tree built over them, allowing the tree to be served without recomputation
+// Use FlattenMerkle to convert them for tools that only understand flat attestations
+func WithMerkle() Option {
+	return func(t *Terrapin) error {
+		t.merkle = true
+		return nil
+	}
+}
+
+// WithReadRateLimit throttles the reads of the attest and verify loops to bytesPerSec using a token bucket,
+// letting background integrity sweeps run without starving foreground I/O
+// The bucket holds one second worth of bytes, so short bursts up to bytesPerSec are not delayed
+func WithReadRateLimit(bytesPerSec int) Option {
+	return func(t *Terrapin) error {
+		if bytesPerSec <= 0 {
+			return fmt.Errorf("read rate limit must be positive, got %d", bytesPerSec)
+		}
+		t.limiter = rate.NewLimiter(rate.Limit(bytesPerSec), bytesPerSec)
+		return nil
+	}
+}
+
+// DefaultInputQueueSize is the number of blocks the pipelined attest paths read ahead of hashing by default
+const DefaultInputQueueSize = 1
+
+// WithInputQueueSize sets how many blocks AttestReaderPipelined and the other pipelined attest paths may read
+// ahead of hashing. Once that many blocks are waiting, reading blocks until the hasher drains one, so slow
+// hashing applies backpressure to the reader instead of growing memory: at most size+1 blocks are held at once
+func WithInputQueueSize(size int) Option {
+	return func(t *Terrapin) error {
+		if size < 1 {
+			return fmt.Errorf("input queue size must be at least 1, got %d", size)
+		}
+		t.inputQueueSize = size
+		return nil
+	}
+}
+
+// WithZeroFillUnreadable sets the policy of AttestReaderAt for regions that cannot be read, such as bad sectors
+// By default a read error fails the attestation. With this option a block that fails to read is re-read in
+// UnreadableSectorSize pieces and each piece that still fails is attested as zeros, so degraded media yields
+// a deterministic attestation. Each zero-filled piece is passed to report, if non-nil, otherwise the zero-fill
+// is silent. Reads past the end of the data are never zero-filled
+func WithZeroFillUnreadable(report func(offset, length int64, err error)) Option {
+	return func(t *Terrapin) error {
+		t.zeroFill = true
+		t.onUnreadable = report
+		return nil
+	}
+}
+
+// MinDigestSize is the smallest digest size accepted by WithDigestSize
+const MinDigestSize = 16
+
+// WithDigestSize truncates each chunk hash to size bytes, which must be between MinDigestSize and the
+// algorithm's digest size, trading collision resistance for smaller attestations. The root gitoid is not
+// truncated, but truncated chunk hashes are not gitoids, so they have no URIs
+func WithDigestSize(size int) Option {
+	return func(t *Terrapin) error {
+		if size < MinDigestSize {
+			return fmt.Errorf("digest size %d is below the minimum of %d bytes", size, MinDigestSize)
+		}
+		t.digestLen = size
+		return nil
+	}
+}
+
+// WithEpoch records epoch in the attestations header as the attestation generation, letting systems that
+// re-attest periodically track which generation a file was last verified against. The epoch is metadata:
+// it is excluded from the root gitoid, so re-attesting unchanged data under a new epoch keeps the same root
+// An epoch of 0 is the same as none
+func WithEpoch(epoch uint64) Option {
+	return func(t *Terrapin) error {
+		t.epoch = epoch
+		return nil
+	}
+}
+
+// DeframeFunc wraps a reader of framed data, such as length-prefixed transport messages, returning a reader
+// of the raw attested bytes the frames carry
+type DeframeFunc func(io.Reader) io.Reader
+
+// WithDeframer strips transport framing from data being verified: every verify method reading an io.Reader
+// passes it through deframe first, so frames need not align with chunks and the verify loops stay unaware of
+// the transport. Attesting is unaffected
+func WithDeframer(deframe DeframeFunc) Option {
+	return func(t *Terrapin) error {
+		if deframe == nil {
+			return errors.New("deframer must not be nil")
+		}
+		t.deframer = deframe
+		return nil
+	}
+}
+
+// WithProgress reports progress to report with the cumulative number of bytes hashed: by Add, AddChunk and
+// Finalize after each chunk of the data being attested, and by VerifyBuffer and the methods built on it after
+// each chunk verified, counting from the start of each verification. Attesting reports stop once Finalize
+// returns. As verifications may run concurrently, report must be safe for concurrent use if they do
+func WithProgress(report func(bytesProcessed int64)) Option {
+	return func(t *Terrapin) error {
+		if report == nil {
+			return errors.New("progress report must not be nil")
+		}
+		t.progress = report
+		return nil
+	}
+}
+
+// WithEmptyAttestations lets NewTerrapinWithAttestations accept a zero-length attestations blob, which is what
+// attesting an empty file with the default settings produces. Without it such a blob is rejected, as it only
+// verifies empty data and more often results from a truncated or missing attestations file
+func WithEmptyAttestations() Option {
+	return func(t *Terrapin) error {
+		t.allowEmpty = true
+		return nil
+	}
+}
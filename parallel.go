@@ -0,0 +1,256 @@
+package terrapin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// AttestReaderParallel reads r to EOF and returns the gitoid URI and attestations of its content, hashing up
+// to workers chunks concurrently while reading continues
+// The result is identical to AttestReaderPipelined, whatever the number of workers
+func AttestReaderParallel(r io.Reader, workers int, opts ...Option) (string, []byte, error) {
+	if workers < 1 {
+		return "", nil, errors.New("workers must be at least 1")
+	}
+	t, err := NewTerrapinWithOptions(opts...)
+	if err != nil {
+		return "", nil, err
+	}
+	if t.variable {
+		return "", nil, errVariableChunks
+	}
+
+	err = t.hashParallel(r, workers, func(index int, data, hash []byte) (bool, error) {
+		return true, t.appendChunk(data, hash)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return t.Finalize()
+}
+
+// VerifyBufferParallel verifies the data stream from the reader against the attestations like VerifyBuffer,
+// hashing up to workers chunks concurrently, and returns the same result as VerifyBuffer
+func (t *Terrapin) VerifyBufferParallel(reader io.Reader, workers int) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+	if workers < 1 {
+		return false, errors.New("workers must be at least 1")
+	}
+	if t.variable {
+		return false, errVariableChunks
+	}
+
+	match := true
+	count := len(t.attestations) / t.digestSize()
+	chunks := 0
+	err := t.hashParallel(t.deframe(reader), workers, func(index int, data, hash []byte) (bool, error) {
+		chunks = index + 1
+		if index >= count {
+			match = false // More data than attested
+			return false, nil
+		}
+		if err := t.checkShortChunk(index, len(data)); err != nil {
+			return false, err
+		}
+		if !bytes.Equal(hash, t.attestations[index*t.digestSize():(index+1)*t.digestSize()]) {
+			match = false // Hash mismatch
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if match && chunks < count {
+		return false, &TruncatedDataError{Chunk: chunks} // The data must cover every attested chunk
+	}
+	return match, nil
+}
+
+// VerifyReaderAtParallel verifies the first size bytes of r against the attestations, with up to workers
+// goroutines each reading and verifying chunks through ReadAt, so hashing is spread across cores without the
+// sequential read of VerifyBufferParallel. It stops the remaining workers as soon as any chunk fails
+// The data must cover exactly the attested chunks, and as with VerifyBuffer only the last chunk may be short
+// Returns true if verification succeeds, false otherwise
+func (t *Terrapin) VerifyReaderAtParallel(r io.ReaderAt, size int64, workers int) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+	if workers < 1 {
+		return false, errors.New("workers must be at least 1")
+	}
+	if size < 0 {
+		return false, errors.New("size must not be negative")
+	}
+
+	// The size must end within the last attested chunk
+	count := len(t.attestations) / t.digestSize()
+	if size > t.chunkOffset(count) || (count > 0 && size <= t.chunkOffset(count-1)) {
+		return false, nil
+	}
+
+	// The rate limit is waited for outside of ctx, so stopping the workers after a failure cannot turn the wait
+	// of a chunk before it into a cancellation error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r = io.NewSectionReader(t.limitReaderAt(context.Background(), r), 0, size)
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for index := 0; index < count; index++ {
+			select {
+			case indexes <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Record the failure with the lowest index, stopping the dispatch of further chunks once one is found
+	// Chunks are dispatched in order, so every chunk before a failure is still verified by the worker holding
+	// it, and the result is that of the first failing chunk, as when verifying serially
+	var mu sync.Mutex
+	failIndex := -1
+	var failErr error
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				valid, err := t.verifyChunkAt(r, index)
+				if valid && err == nil {
+					continue
+				}
+				mu.Lock()
+				if failIndex < 0 || index < failIndex {
+					failIndex, failErr = index, err
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failIndex >= 0 {
+		return false, failErr
+	}
+	return true, nil
+}
+
+// appendChunk records the hash of a complete chunk hashed outside of Add
+func (t *Terrapin) appendChunk(data, hash []byte) error {
+	// Ensure the data does not exceed the declared whole-file length
+	if t.fileHasher != nil && t.size+int64(len(data)) > t.fileLength {
+		return fmt.Errorf("data exceeds declared file length of %d bytes", t.fileLength)
+	}
+
+	// Feed the whole-file hasher if enabled
+	if t.fileHasher != nil {
+		t.fileHasher.Write(data)
+	}
+	t.size += int64(len(data))
+
+	// Stream the hash to the sink, if any
+	if err := t.writeSink(hash); err != nil {
+		return err
+	}
+	t.attestations = append(t.attestations, hash...)
+	t.reportProgress(len(data))
+	return nil
+}
+
+// hashParallel reads r in blocks and hashes up to workers blocks concurrently, passing each block and its
+// hash to consume in order; consume returns false to stop early
+func (t *Terrapin) hashParallel(r io.Reader, workers int, consume func(index int, data, hash []byte) (bool, error)) error {
+	// job is one block being hashed, or the read error that ended the stream
+	type job struct {
+		data []byte
+		hash []byte
+		err  error
+		done chan struct{} // Closed once hash or err is set
+	}
+
+	r = t.limitReader(context.Background(), r)
+
+	// Enough buffers circulate to keep every worker busy while the consumer catches up
+	free := make(chan []byte, 2*workers)
+	for i := 0; i < 2*workers; i++ {
+		free <- make([]byte, t.blockSize)
+	}
+	jobs := make(chan *job)
+	ordered := make(chan *job, workers)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobs {
+				j.hash, j.err = t.hashChunk(j.data)
+				close(j.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		defer close(ordered)
+		for {
+			var buffer []byte
+			select {
+			case buffer = <-free:
+			case <-stop:
+				return
+			}
+
+			n, err := io.ReadFull(r, buffer)
+			if n > 0 {
+				j := &job{data: buffer[:n], done: make(chan struct{})}
+				select {
+				case ordered <- j:
+				case <-stop:
+					return
+				}
+				jobs <- j
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				j := &job{err: fmt.Errorf("failed to read input: %w", err), done: make(chan struct{})}
+				close(j.done)
+				select {
+				case ordered <- j:
+				case <-stop:
+				}
+				return
+			}
+		}
+	}()
+
+	// Consume the hashes in read order, then hand each buffer back to the reader
+	index := 0
+	for j := range ordered {
+		<-j.done
+		if j.err != nil {
+			return j.err
+		}
+		more, err := consume(index, j.data, j.hash)
+		if err != nil || !more {
+			return err
+		}
+		index++
+		free <- j.data[:cap(j.data)]
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestAttestReaderParallel(t *testing.T) {
+	for _, size := range []int{0, 100, 4 * 1024, 9*1024 + 17} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i % 253)
+		}
+		expectedGid, expected, err := AttestReaderPipelined(bytes.NewReader(data), WithBlockSize(1024))
+		if err != nil {
+			t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+		}
+
+		for _, workers := range []int{1, 4} {
+			gid, attestations, err := AttestReaderParallel(bytes.NewReader(data), workers, WithBlockSize(1024))
+			if err != nil {
+				t.Fatalf("AttestReaderParallel returned an error: %v", err)
+			}
+			if gid != expectedGid || !bytes.Equal(attestations, expected) {
+				t.Errorf("size %d, %d workers: expected the result of AttestReaderPipelined", size, workers)
+			}
+		}
+	}
+
+	if _, _, err := AttestReaderParallel(bytes.NewReader(nil), 0); err == nil {
+		t.Error("Expected an error for zero workers")
+	}
+}
+
+func TestVerifyBufferParallel(t *testing.T) {
+	data := make([]byte, 9*1024+17)
+	for i := range data {
+		data[i] = byte(i % 253)
+	}
+	_, attestations, err := AttestReaderParallel(bytes.NewReader(data), 4, WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("AttestReaderParallel returned an error: %v", err)
+	}
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[5*1024+1] ^= 0xff
+	for name, input := range map[string][]byte{
+		"matching":  data,
+		"corrupt":   corrupt,
+		"extended":  append(append([]byte(nil), data...), 0),
+		"truncated": data[:3*1024],
+	} {
+		// Both report data ending on a chunk boundary before the last chunk as truncated
+		expected, expectedErr := terrapin.VerifyBuffer(bytes.NewReader(input))
+		for _, workers := range []int{1, 4} {
+			match, err := terrapin.VerifyBufferParallel(bytes.NewReader(input), workers)
+			if match != expected || fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+				t.Errorf("%s, %d workers: expected %v, %v, got %v, %v", name, workers, expected, expectedErr, match, err)
+			}
+		}
+	}
+}
+
+func TestVerifyReaderAtParallel(t *testing.T) {
+	data := make([]byte, 9*1024+17)
+	for i := range data {
+		data[i] = byte(i % 253)
+	}
+	_, attestations, err := AttestReaderPipelined(bytes.NewReader(data), WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+	}
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[5*1024+1] ^= 0xff
+	// Unlike VerifyBuffer, data ending on an earlier chunk boundary does not verify as a prefix
+	for name, c := range map[string]struct {
+		input    []byte
+		expected bool
+	}{
+		"matching":  {data, true},
+		"corrupt":   {corrupt, false},
+		"extended":  {append(append([]byte(nil), data...), 0), false},
+		"truncated": {data[:3*1024], false},
+	} {
+		for _, workers := range []int{1, 4} {
+			valid, err := terrapin.VerifyReaderAtParallel(bytes.NewReader(c.input), int64(len(c.input)), workers)
+			if err != nil {
+				t.Fatalf("%s: VerifyReaderAtParallel returned an error: %v", name, err)
+			}
+			if valid != c.expected {
+				t.Errorf("%s, %d workers: expected %v, got %v", name, workers, c.expected, valid)
+			}
+		}
+	}
+
+	// A source shorter than the declared size is truncated within chunk 4, and later chunks are missing; the
+	// lowest failing chunk decides the result whichever worker finishes first
+	for range 20 {
+		_, err = terrapin.VerifyReaderAtParallel(bytes.NewReader(data[:4*1024+100]), int64(len(data)), 8)
+		var truncated *TruncatedDataError
+		if !errors.As(err, &truncated) || truncated.Chunk != 4 {
+			t.Fatalf("Expected a TruncatedDataError for chunk 4, got %v", err)
+		}
+	}
+	if _, err := terrapin.VerifyReaderAtParallel(bytes.NewReader(data), int64(len(data)), 0); err == nil {
+		t.Error("Expected an error for zero workers")
+	}
+}
+
+// slowStartReaderAt delays reads at offset 0, so the first chunk is verified after the others
+type slowStartReaderAt struct {
+	reader io.ReaderAt
+	delay  time.Duration
+}
+
+func (r *slowStartReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off == 0 {
+		time.Sleep(r.delay)
+	}
+	return r.reader.ReadAt(p, off)
+}
+
+func TestVerifyReaderAtParallelRateLimited(t *testing.T) {
+	data := make([]byte, 4*1024)
+	for i := range data {
+		data[i] = byte(i % 253)
+	}
+	_, attestations, err := AttestReaderPipelined(bytes.NewReader(data), WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+	}
+	terrapin, err := NewTerrapinWithAttestations(attestations, WithReadRateLimit(2048))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+
+	// Chunk 1 fails while chunk 0 has yet to wait for the rate limit, which must not report a cancellation
+	corrupt := append([]byte(nil), data...)
+	corrupt[1024+1] ^= 0xff
+	r := &slowStartReaderAt{reader: bytes.NewReader(corrupt), delay: 200 * time.Millisecond}
+	valid, err := terrapin.VerifyReaderAtParallel(r, int64(len(corrupt)), 4)
+	if err != nil || valid {
+		t.Errorf("Expected a mismatch without an error, got %v, %v", valid, err)
+	}
+}
+
+// BenchmarkVerifyReaderAtParallel compares serial verification with verification spread over every core
+func BenchmarkVerifyReaderAtParallel(b *testing.B) {
+	data := make([]byte, 1<<30)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	_, attestations, err := AttestReaderParallel(bytes.NewReader(data), runtime.NumCPU())
+	if err != nil {
+		b.Fatalf("AttestReaderParallel returned an error: %v", err)
+	}
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		b.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+
+	b.Run("serial", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if valid, err := terrapin.VerifyBuffer(bytes.NewReader(data)); err != nil || !valid {
+				b.Fatalf("VerifyBuffer returned %v, %v", valid, err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			valid, err := terrapin.VerifyReaderAtParallel(bytes.NewReader(data), int64(len(data)), runtime.NumCPU())
+			if err != nil || !valid {
+				b.Fatalf("VerifyReaderAtParallel returned %v, %v", valid, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package terrapin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// RangeFetcher fetches length bytes of a remote object starting at offset,
+// typically by issuing an HTTP Range GET against object storage
+type RangeFetcher func(offset, length int64) ([]byte, error)
+
+// RangeReaderAt is an io.ReaderAt that pulls data on demand through a RangeFetcher
+// Combined with VerifyReaderAt, only the chunks being verified are ever downloaded
+type RangeReaderAt struct {
+	fetch RangeFetcher // Function used to retrieve byte ranges
+	size  int64        // Total size of the remote object
+}
+
+// NewRangeReaderAt returns a RangeReaderAt for a remote object of the given size
+func NewRangeReaderAt(fetch RangeFetcher, size int64) *RangeReaderAt {
+	return &RangeReaderAt{
+		fetch: fetch,
+		size:  size,
+	}
+}
+
+// Size returns the total size of the remote object
+func (r *RangeReaderAt) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements io.ReaderAt, fetching at most one range per call
+func (r *RangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("terrapin.RangeReaderAt.ReadAt: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	// Never request bytes past the end of the object
+	length := min(int64(len(p)), r.size-off)
+	data, err := r.fetch(off, length)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data)
+	if int64(n) < length {
+		return n, io.ErrUnexpectedEOF // The backend returned less than requested
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// NewTerrapinFromReaderAt initializes a Terrapin instance from an attestations blob of size bytes read through ra,
+// such as a RangeReaderAt over a blob in an OCI registry, in a single ReadAt call
+// The blob is expected to be stored exactly as Finalize returns it: the header described in header.go, if the
+// settings require one, directly followed by the chunk hashes back to back in chunk order, so the hash of chunk i
+// starts i times the digest size past the end of the header. Every hash is read, as the root gitoid covers them
+// all, but they take only a digest per chunk, 32 bytes for every 2MB of data with the defaults; combined with
+// VerifyReaderAt, a single chunk of a huge artifact is then verified with one request for the attestations and
+// one for the chunk
+// Options are applied as by NewTerrapinWithAttestations, so a zero size, the attestations of an empty file, is
+// rejected unless WithEmptyAttestations is given
+func NewTerrapinFromReaderAt(ra io.ReaderAt, size int64, opts ...Option) (*Terrapin, error) {
+	if size < 0 || size > math.MaxInt {
+		return nil, fmt.Errorf("invalid attestations size %d", size)
+	}
+	blob := make([]byte, size)
+	if n, err := ra.ReadAt(blob, 0); n < len(blob) {
+		return nil, fmt.Errorf("failed to read attestations: %w", err)
+	}
+	return NewTerrapinWithAttestations(blob, opts...)
+}
@@ -0,0 +1,146 @@
+package terrapin
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// memoryFetcher serves ranges of blob, recording each request
+type memoryFetcher struct {
+	blob     []byte
+	requests [][2]int64
+}
+
+func (m *memoryFetcher) fetch(offset, length int64) ([]byte, error) {
+	m.requests = append(m.requests, [2]int64{offset, length})
+	if offset < 0 || offset+length > int64(len(m.blob)) {
+		return nil, errors.New("range not satisfiable")
+	}
+	return m.blob[offset : offset+length], nil
+}
+
+func TestRangeReaderAt_VerifyReaderAt(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	fetcher := &memoryFetcher{blob: data}
+	readerAt := NewRangeReaderAt(fetcher.fetch, int64(len(data)))
+
+	// Verify only the second and the final short chunk
+	for _, index := range []int{1, 3} {
+		match, err := terrapin.VerifyReaderAt(readerAt, index)
+		if err != nil {
+			t.Fatalf("VerifyReaderAt(%d) returned an error: %v", index, err)
+		}
+		if !match {
+			t.Fatalf("VerifyReaderAt(%d) expected to match, but it didn't", index)
+		}
+	}
+
+	// Only the requested chunks were fetched
+	expected := [][2]int64{{BufferCapacity, BufferCapacity}, {3 * BufferCapacity, 100}}
+	if len(fetcher.requests) != len(expected) {
+		t.Fatalf("Expected requests %v, got %v", expected, fetcher.requests)
+	}
+	for i := range expected {
+		if fetcher.requests[i] != expected[i] {
+			t.Fatalf("Expected requests %v, got %v", expected, fetcher.requests)
+		}
+	}
+
+	// A corrupted remote chunk does not verify
+	corrupt := append([]byte(nil), data...)
+	corrupt[BufferCapacity+3] ^= 0xff
+	readerAt = NewRangeReaderAt((&memoryFetcher{blob: corrupt}).fetch, int64(len(corrupt)))
+	match, err := terrapin.VerifyReaderAt(readerAt, 1)
+	if err != nil {
+		t.Fatalf("VerifyReaderAt returned an error: %v", err)
+	}
+	if match {
+		t.Fatalf("VerifyReaderAt expected to mismatch, but it matched")
+	}
+
+	if _, err := terrapin.VerifyReaderAt(readerAt, 4); err == nil {
+		t.Fatalf("VerifyReaderAt expected to return an error for an out of range chunk, but it didn't")
+	}
+}
+
+func TestRangeReaderAt_ReadAt(t *testing.T) {
+	fetcher := &memoryFetcher{blob: []byte("0123456789")}
+	readerAt := NewRangeReaderAt(fetcher.fetch, 10)
+
+	buffer := make([]byte, 4)
+	n, err := readerAt.ReadAt(buffer, 8)
+	if n != 2 || err != io.EOF || !bytes.Equal(buffer[:n], []byte("89")) {
+		t.Errorf("Expected 2 bytes and io.EOF, got %d, %v, %q", n, err, buffer[:n])
+	}
+	if n, err := readerAt.ReadAt(buffer, 10); n != 0 || err != io.EOF {
+		t.Errorf("Expected 0 bytes and io.EOF, got %d, %v", n, err)
+	}
+
+	// A negative offset is invalid rather than the end of the data, and fetches nothing
+	n, err = readerAt.ReadAt(buffer, -1)
+	if n != 0 || err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a negative offset error, got %d, %v", n, err)
+	}
+	if len(fetcher.requests) != 1 {
+		t.Errorf("Expected only the first read to fetch, got %v", fetcher.requests)
+	}
+}
+
+func TestNewTerrapinFromReaderAt(t *testing.T) {
+	data := make([]byte, 3*1024+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uri, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// One request loads the attestations and one more fetches the chunk being verified
+	attestationsFetcher := &memoryFetcher{blob: attestations}
+	terrapin, err := NewTerrapinFromReaderAt(NewRangeReaderAt(attestationsFetcher.fetch, int64(len(attestations))), int64(len(attestations)))
+	if err != nil {
+		t.Fatalf("NewTerrapinFromReaderAt returned an error: %v", err)
+	}
+	if terrapin.rootURI != uri || terrapin.BlockSize() != 1024 {
+		t.Errorf("Expected root %s with 1024-byte blocks, got %s with %d", uri, terrapin.rootURI, terrapin.BlockSize())
+	}
+	dataFetcher := &memoryFetcher{blob: data}
+	match, err := terrapin.VerifyReaderAt(NewRangeReaderAt(dataFetcher.fetch, int64(len(data))), 2)
+	if err != nil || !match {
+		t.Errorf("Expected chunk 2 to verify, got %v, %v", match, err)
+	}
+	if len(attestationsFetcher.requests) != 1 || len(dataFetcher.requests) != 1 {
+		t.Errorf("Expected one request each, got %v and %v", attestationsFetcher.requests, dataFetcher.requests)
+	}
+
+	// A blob shorter than its declared size fails to load
+	if _, err := NewTerrapinFromReaderAt(bytes.NewReader(attestations), int64(len(attestations))+1); err == nil {
+		t.Error("Expected an error for a short read")
+	}
+	if _, err := NewTerrapinFromReaderAt(bytes.NewReader(attestations), -1); err == nil {
+		t.Error("Expected an error for a negative size")
+	}
+
+	// The attestations of an empty file must be accepted explicitly
+	if _, err := NewTerrapinFromReaderAt(bytes.NewReader(nil), 0); err == nil {
+		t.Error("Expected an error for empty attestations")
+	}
+	if _, err := NewTerrapinFromReaderAt(bytes.NewReader(nil), 0, WithEmptyAttestations()); err != nil {
+		t.Errorf("Expected empty attestations to load with WithEmptyAttestations, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package terrapin
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"io"
+)
+
+// rateLimitedReader throttles reads from an underlying reader using a token bucket of one token per byte
+type rateLimitedReader struct {
+	ctx     context.Context // Context whose cancellation ends a wait for the rate limit
+	reader  io.Reader       // Underlying reader
+	limiter *rate.Limiter   // Token bucket shared by every read loop of the instance
+}
+
+// Read reads into p, then waits until the bytes read fit within the rate limit
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if waitErr := waitBytes(r.ctx, r.limiter, n); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
+
+// rateLimitedReaderAt throttles reads from an underlying io.ReaderAt
+type rateLimitedReaderAt struct {
+	ctx     context.Context // Context whose cancellation ends a wait for the rate limit
+	reader  io.ReaderAt     // Underlying reader
+	limiter *rate.Limiter   // Token bucket shared by every read loop of the instance
+}
+
+// ReadAt reads into p at off, then waits until the bytes read fit within the rate limit
+func (r *rateLimitedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.reader.ReadAt(p, off)
+	if waitErr := waitBytes(r.ctx, r.limiter, n); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
+
+// waitBytes blocks until n tokens are available, waiting in steps of the burst size since a single wait
+// may not exceed it; it returns ctx's error once ctx is cancelled
+func waitBytes(ctx context.Context, limiter *rate.Limiter, n int) error {
+	for n > 0 {
+		step := min(n, limiter.Burst())
+		if err := limiter.WaitN(ctx, step); err != nil {
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
+
+// limitReader wraps reader so it honours the read rate limit, if one is set, until ctx is cancelled
+func (t *Terrapin) limitReader(ctx context.Context, reader io.Reader) io.Reader {
+	if t.limiter == nil {
+		return reader
+	}
+	return &rateLimitedReader{ctx: ctx, reader: reader, limiter: t.limiter}
+}
+
+// limitReaderAt wraps reader so it honours the read rate limit, if one is set, until ctx is cancelled
+func (t *Terrapin) limitReaderAt(ctx context.Context, reader io.ReaderAt) io.ReaderAt {
+	if t.limiter == nil {
+		return reader
+	}
+	return &rateLimitedReaderAt{ctx: ctx, reader: reader, limiter: t.limiter}
+}
@@ -0,0 +1,62 @@
+package terrapin
+
+import (
+	"context"
+	"io"
+	"math"
+)
+
+// ChunkStats summarizes the bytes of a single verified chunk
+type ChunkStats struct {
+	Min     byte    // Smallest byte value
+	Max     byte    // Largest byte value
+	Mean    float64 // Mean byte value
+	Entropy float64 // Shannon entropy in bits per byte, from 0 for constant data to 8 for uniform data
+}
+
+// Stats summarizes the data read by VerifyBufferStats
+type Stats struct {
+	Chunks     []ChunkStats // Statistics of each verified chunk, in order
+	ZeroChunks int          // Number of verified chunks consisting entirely of zero bytes
+}
+
+// VerifyBufferStats verifies the entire data stream from the reader like VerifyBuffer, and also tallies
+// statistics of each chunk in the same pass to characterize the data without reading it twice
+// The statistics cover the chunks verified before any mismatch
+func (t *Terrapin) VerifyBufferStats(reader io.Reader) (bool, *Stats, error) {
+	stats := &Stats{}
+	match, err := verifyResult(t.verifyBuffer(context.Background(), t.deframe(reader), func(data []byte) {
+		chunk := chunkStats(data)
+		stats.Chunks = append(stats.Chunks, chunk)
+		if chunk.Max == 0 {
+			stats.ZeroChunks++
+		}
+	}))
+	if err != nil {
+		return false, nil, err
+	}
+	return match, stats, nil
+}
+
+// chunkStats computes the statistics of a non-empty chunk from its byte histogram
+func chunkStats(data []byte) ChunkStats {
+	var histogram [256]int
+	for _, b := range data {
+		histogram[b]++
+	}
+
+	stats := ChunkStats{Min: 255}
+	var sum float64
+	for value, occurrences := range histogram {
+		if occurrences == 0 {
+			continue
+		}
+		stats.Min = min(stats.Min, byte(value))
+		stats.Max = max(stats.Max, byte(value))
+		sum += float64(value) * float64(occurrences)
+		p := float64(occurrences) / float64(len(data))
+		stats.Entropy -= p * math.Log2(p)
+	}
+	stats.Mean = sum / float64(len(data))
+	return stats
+}
@@ -2,55 +2,312 @@ package terrapin
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"context"
+	"encoding"
 	"errors"
 	"fmt"
 	"github.com/edwarnicke/gitoid"
+	"golang.org/x/time/rate"
+	"hash"
 	"io"
+	"os"
+	"slices"
 )
 
-// Terrapin is a package for creating and verifying data attestations using SHA-256 hashes.
+// Terrapin is a package for creating and verifying data attestations using chunk hashes, SHA-256 by default or
+// another algorithm chosen with WithHashAlgorithm.
 // The process involves reading data in chunks, hashing each chunk, and storing these hashes (attestations).
 // The hashes can later be used to verify the integrity of the data by comparing computed hashes against the stored attestations.
 
+// Terrapin holds the state of a single attestation
+// Add and Finalize modify the instance and must not be called concurrently. Once finalized, an instance is
+// not modified again unless Continue is called and all state is computed eagerly by Finalize, so the Verify
+// methods, which only read it and allocate their own buffers, are safe for concurrent use by multiple
+// goroutines. Use ConcurrentTerrapin to share an instance that is still being added to
 type Terrapin struct {
-	attestations []byte         // Byte slice to store SHA-256 hashes of data chunks
-	buffer       []byte         // Buffer to hold data before hashing
-	finalized    bool           // Boolean to indicate if the attestation process is finalized
-	gid          *gitoid.GitOID // Pointer to the final gitoid representing the attested data
+	attestations []byte // Byte slice to store the chunk hashes, computed with algorithm and truncated to digestSize
+	buffer       []byte // Buffer to hold data before hashing
+	finalized    bool   // Boolean to indicate if the attestation process is finalized
+	rootURI      string // URI of the final gitoid representing the attested data
+	size         int64  // Total number of bytes added, or recorded alongside loaded attestations
+	blockSize    int    // Size of each attested chunk
+
+	chunkType gitoid.GitObjectType // Git object type used for chunk gitoids
+	rootType  gitoid.GitObjectType // Git object type used for the root gitoid over the attestations
+	algorithm Algorithm            // Hash algorithm used for chunk and root gitoids
+	merkle    bool                 // Whether attestations hold a Merkle tree over the chunk hashes
+	rawChunks bool                 // Whether chunks are hashed without a git object header, as in torrent piece lists
+	digestLen int                  // Length chunk hashes are truncated to, 0 for the algorithm's full digest size
+	epoch     uint64               // Attestation generation recorded as metadata, 0 when unset
+
+	firstChunk int // Index of the first chunk hash when the instance holds an attestations delta
+
+	allowEmpty bool // Whether NewTerrapinWithAttestations accepts a zero-length blob
+
+	variable     bool    // Whether chunks vary in size, each added by AddChunk or split by chunker
+	chunkLengths []int   // Length of each chunk when chunks vary in size
+	chunker      Chunker // Optional chooser of content-defined boundaries for the data passed to Add
+
+	fileGitoid    bool      // Whether the gitoid of the whole file is computed
+	fileHasher    hash.Hash // Optional hasher computing the gitoid of the whole file
+	fileLength    int64     // Declared length of the whole file, required by the gitoid header
+	fileGitoidURI string    // Gitoid URI of the whole file, set by Finalize when fileHasher is used
+
+	sink              io.Writer // Optional writer receiving attestation bytes as chunks complete
+	sinkHeaderWritten bool      // Whether the header has been written to sink
+
+	progress func(bytesProcessed int64) // Optional report of the cumulative bytes hashed
+	hashed   int64                      // Bytes of the chunks attested so far, as reported to progress
+
+	deframer       DeframeFunc   // Optional function stripping transport framing from readers being verified
+	inputQueueSize int           // Number of blocks read ahead of hashing by the pipelined attest paths
+	limiter        *rate.Limiter // Optional token bucket throttling the attest and verify read loops
+	closer         func() error  // Optional function releasing OS resources held by the instance, called once by Close
+
+	zeroFill     bool                                  // Whether AttestReaderAt zero-fills unreadable sectors instead of failing
+	onUnreadable func(offset, length int64, err error) // Optional report of each zero-filled sector
 }
 
-// BufferCapacity defines the maximum size of the buffer (2MB)
+// BufferCapacity defines the maximum size of the buffer (2MB), and the default block size
 const BufferCapacity = 1024 * 1024 * 2 // 2MB buffer capacity
 
+// MinBlockSize is the smallest block size accepted by WithBlockSize
+// Each chunk costs a 32-byte hash, so smaller blocks inflate attestations and hashing overhead
+const MinBlockSize = 512
+
+// MaxBlockSize is the largest block size accepted by WithBlockSize, bounding per-instance buffer allocations
+const MaxBlockSize = 1024 * 1024 * 1024 // 1GB
+
 // NewTerrapin initializes and returns a new Terrapin instance with an empty buffer and attestations
 func NewTerrapin() *Terrapin {
+	res := newDefaultTerrapin()
+	res.buffer = make([]byte, 0, BufferCapacity)
+	return res
+}
+
+// newDefaultTerrapin returns an instance with default settings and no buffer allocated
+func newDefaultTerrapin() *Terrapin {
 	return &Terrapin{
 		attestations: []byte{},
-		buffer:       make([]byte, 0, BufferCapacity),
+		blockSize:    BufferCapacity,
+		chunkType:    gitoid.BLOB,
+		rootType:     gitoid.BLOB,
+		algorithm:    SHA256,
 		finalized:    false,
+
+		inputQueueSize: DefaultInputQueueSize,
+	}
+}
+
+// NewTerrapinWithOptions initializes and returns a new Terrapin instance configured by the given options
+func NewTerrapinWithOptions(opts ...Option) (*Terrapin, error) {
+	res := newDefaultTerrapin()
+	if err := res.applyOptions(opts); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// applyOptions applies opts and allocates the buffer for the configured block size
+func (t *Terrapin) applyOptions(opts []Option) error {
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return err
+		}
+	}
+	if err := t.validateDigestSize(); err != nil {
+		return err
+	}
+	if t.merkle && t.sink != nil {
+		return errors.New("attestation sink cannot be combined with Merkle mode")
 	}
+	if t.variable && t.sink != nil {
+		return errors.New("attestation sink cannot be combined with variable-size chunks")
+	}
+	t.buffer = make([]byte, 0, t.blockSize)
+
+	// Prime the whole-file hasher now that the algorithm is known
+	if t.fileGitoid {
+		info, _ := t.algorithm.info()
+		t.fileHasher = info.newHash()
+		t.fileHasher.Write(gitoid.Header(gitoid.BLOB, t.fileLength))
+	}
+	return nil
 }
 
 // NewTerrapinWithAttestations initializes and returns a new Terrapin instance with provided attestations
-func NewTerrapinWithAttestations(attestations []byte) (*Terrapin, error) {
-	// Ensure the attestations length is a multiple of the SHA-256 size
-	if len(attestations)%sha256.Size != 0 {
-		return nil, errors.New("invalid attestations: length is not a multiple of SHA-256 size")
+// Attestations produced with non-default settings carry a header recording them, and those recorded
+// settings take precedence over opts; for headerless attestations opts must match the producer's settings
+func NewTerrapinWithAttestations(attestations []byte, opts ...Option) (*Terrapin, error) {
+	res := newDefaultTerrapin()
+	for _, opt := range opts {
+		if err := opt(res); err != nil {
+			return nil, err
+		}
 	}
+	if err := res.loadAttestations(attestations); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
 
-	res := &Terrapin{
-		attestations: attestations,
-		buffer:       make([]byte, 0, BufferCapacity),
-		finalized:    false,
+// ReadAttestationsFrom reads an attestations blob from r until EOF and loads it into the instance, which is then
+// finalized as if created by NewTerrapinWithAttestations with the instance's settings, so it can verify data
+// The blob is read straight into the slice the instance keeps, without the separate copy of reading the file
+// first. The instance must be new, with no data added; it is left unchanged if an error is returned
+// Returns the number of bytes read, following io.ReaderFrom
+func (t *Terrapin) ReadAttestationsFrom(r io.Reader) (int64, error) {
+	if t.finalized {
+		return 0, &AlreadyFinalizedError{}
+	}
+	if t.size > 0 || len(t.attestations) > 0 || len(t.chunkLengths) > 0 {
+		return 0, errors.New("attestations can only be read into an instance with no data added")
 	}
 
+	var blob bytes.Buffer
+	n, err := blob.ReadFrom(r)
+	if err != nil {
+		return n, fmt.Errorf("failed to read attestations: %w", err)
+	}
+	loaded := *t
+	if err := loaded.loadAttestations(blob.Bytes()); err != nil {
+		return n, err
+	}
+	*t = loaded
+	return n, nil
+}
+
+// loadAttestations validates an attestations blob, applies the settings recorded in its header and finalizes
+// the instance over its chunk hashes
+func (t *Terrapin) loadAttestations(attestations []byte) error {
+	if t.sink != nil {
+		return errors.New("attestation sink is only supported when attesting")
+	}
+	if err := t.validateDigestSize(); err != nil {
+		return err
+	}
+
+	// A zero-length blob verifies nothing but empty data, so it is more likely lost than an empty file's
+	if len(attestations) == 0 && !t.allowEmpty {
+		return &InvalidAttestationsError{Reason: "attestations are empty; use WithEmptyAttestations to accept those of an empty file"}
+	}
+
+	// Parse the header, if present, leaving only the chunk hashes
+	body, err := t.parseHeader(attestations)
+	if err != nil {
+		return err
+	}
+
+	if t.firstChunk != 0 {
+		return &InvalidAttestationsError{Reason: "attestations delta must be applied with ApplyAttestationsDelta"}
+	}
+
+	// Ensure the attestations length is a multiple of the digest size
+	if len(body)%t.digestSize() != 0 {
+		return &InvalidAttestationsError{Reason: "length is not a multiple of the digest size"}
+	}
+	if t.variable && len(t.chunkLengths) != len(body)/t.digestSize() {
+		return &InvalidAttestationsError{Reason: "chunk lengths do not match the number of chunk hashes"}
+	}
+	t.attestations = body
+	t.buffer = make([]byte, 0, t.blockSize)
+
 	// Finalize the Terrapin instance immediately
-	_, _, _ = res.Finalize()
+	if err := t.finalize(); err != nil {
+		return fmt.Errorf("failed to finalize attestations: %w", err)
+	}
+	return nil
+}
 
+// NewTerrapinFromMmap initializes a Terrapin instance from an attestations file that is memory-mapped
+// rather than read, so chunk hashes are sliced directly from the mapping and never copied into memory
+// The mapping stays valid until Close is called, after which the instance must no longer be used
+func NewTerrapinFromMmap(path string, opts ...Option) (*Terrapin, error) {
+	data, unmap, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := NewTerrapinWithAttestations(data, opts...)
+	if err != nil {
+		_ = unmap()
+		return nil, err
+	}
+	res.closer = unmap
 	return res, nil
 }
 
+// Close releases any OS resources held by the instance, such as the memory mapping of NewTerrapinFromMmap
+// It is a no-op for in-memory instances and safe to call more than once
+// The Verify methods must not be called after, or concurrently with, Close
+func (t *Terrapin) Close() error {
+	if t.closer == nil {
+		return nil
+	}
+	closer := t.closer
+	t.closer = nil
+	t.attestations = nil
+	return closer()
+}
+
+// chunkHash returns the gitoid hash of a single chunk of data, hashed as the given git object type
+func chunkHash(data []byte, objectType gitoid.GitObjectType, alg Algorithm) ([]byte, error) {
+	return hashGitoid(objectType, alg, data)
+}
+
+// hashChunk returns the gitoid hash of a single chunk of data using the instance's chunk type, or its plain
+// digest when chunks are hashed raw
+func (t *Terrapin) hashChunk(data []byte) ([]byte, error) {
+	var hash []byte
+	var err error
+	if t.rawChunks {
+		hash, err = hashRaw(t.algorithm, data)
+	} else {
+		hash, err = chunkHash(data, t.chunkType, t.algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hash[:t.digestSize()], nil
+}
+
+// hasChunkURIs reports whether chunk hashes are complete gitoids, which raw and truncated hashes are not
+func (t *Terrapin) hasChunkURIs() bool {
+	return !t.rawChunks && t.digestLen == 0
+}
+
+// chunkURI returns the gitoid URI of a chunk hash, or an empty string when chunk hashes are not gitoids
+func (t *Terrapin) chunkURI(hash []byte) string {
+	if !t.hasChunkURIs() {
+		return ""
+	}
+	return gitoidURI(t.chunkType, t.algorithm, hash)
+}
+
+// digestSize returns the size in bytes of each chunk hash
+func (t *Terrapin) digestSize() int {
+	if t.digestLen != 0 {
+		return t.digestLen
+	}
+	return t.algorithm.Size()
+}
+
+// validateDigestSize ensures a truncated digest size fits the algorithm, normalizing a full-size one to 0
+func (t *Terrapin) validateDigestSize() error {
+	if t.digestLen == t.algorithm.Size() {
+		t.digestLen = 0
+	}
+	if t.digestLen != 0 && (t.digestLen < MinDigestSize || t.digestLen > t.algorithm.Size()) {
+		return fmt.Errorf("digest size %d is outside the range %d to %d bytes for %s", t.digestLen, MinDigestSize,
+			t.algorithm.Size(), t.algorithm)
+	}
+	return nil
+}
+
+// hashBuffer returns the gitoid hash of the current buffer content without modifying any state
+func (t *Terrapin) hashBuffer() ([]byte, error) {
+	return t.hashChunk(t.buffer)
+}
+
 // updateHashBuffer hashes the current buffer content, appends the hash to attestations, and resets the buffer
 func (t *Terrapin) updateHashBuffer() error {
 	// If buffer is empty, nothing to do
@@ -59,20 +316,50 @@ func (t *Terrapin) updateHashBuffer() error {
 	}
 
 	// Create a new gitoid for the current buffer content
-	gitoidHash, err := gitoid.New(bytes.NewReader(t.buffer), gitoid.WithSha256())
+	hash, err := t.hashBuffer()
 	if err != nil {
 		return err
 	}
-	hash := gitoidHash.Bytes()
 
-	// Append the hash to attestations
+	// Stream the hash to the sink, if any
+	if err := t.writeSink(hash); err != nil {
+		return err
+	}
+
+	// Append the hash to attestations, even with a sink, as Finalize hashes and returns them all
 	t.attestations = append(t.attestations, hash...)
+	t.reportProgress(len(t.buffer))
 
 	// Reset the buffer for the next round
 	t.buffer = t.buffer[:0]
 	return nil
 }
 
+// reportProgress adds n bytes to those attested and reports the total to the progress callback, if any
+func (t *Terrapin) reportProgress(n int) {
+	t.hashed += int64(n)
+	if t.progress != nil {
+		t.progress(t.hashed)
+	}
+}
+
+// writeSink writes attestation bytes to the sink, preceded by the header on first use
+func (t *Terrapin) writeSink(data []byte) error {
+	if t.sink == nil {
+		return nil
+	}
+	if !t.sinkHeaderWritten {
+		if _, err := t.sink.Write(t.marshalHeader(0, true)); err != nil {
+			return fmt.Errorf("failed to write attestations: %w", err)
+		}
+		t.sinkHeaderWritten = true
+	}
+	if _, err := t.sink.Write(data); err != nil {
+		return fmt.Errorf("failed to write attestations: %w", err)
+	}
+	return nil
+}
+
 // Add adds data to the buffer, and processes the buffer if it reaches capacity
 func (t *Terrapin) Add(data []byte) error {
 	// Ensure the Terrapin instance is not finalized
@@ -80,15 +367,36 @@ func (t *Terrapin) Add(data []byte) error {
 		return &AlreadyFinalizedError{}
 	}
 
+	if t.variable && t.chunker == nil {
+		return errors.New("variable-size chunks must be added with AddChunk")
+	}
+
+	// Ensure the data does not exceed the declared whole-file length
+	if t.fileHasher != nil && t.size+int64(len(data)) > t.fileLength {
+		return fmt.Errorf("data exceeds declared file length of %d bytes", t.fileLength)
+	}
+
+	// Feed the whole-file hasher if enabled
+	if t.fileHasher != nil {
+		t.fileHasher.Write(data)
+	}
+	t.size += int64(len(data))
+
+	// Let the chunker choose the boundaries once enough data is buffered
+	if t.chunker != nil {
+		t.buffer = append(t.buffer, data...)
+		return t.splitChunks(false)
+	}
+
 	// Copy data to the buffer in chunks, processing the buffer if it reaches capacity
 	copied := 0
 	for copied < len(data) {
-		toCopy := min(len(data)-copied, BufferCapacity-len(t.buffer))
+		toCopy := min(len(data)-copied, t.blockSize-len(t.buffer))
 		t.buffer = append(t.buffer, data[copied:copied+toCopy]...)
 		copied += toCopy
 
 		// If buffer reaches capacity, update the hash buffer
-		if len(t.buffer) >= BufferCapacity {
+		if len(t.buffer) >= t.blockSize {
 			if err := t.updateHashBuffer(); err != nil {
 				return err
 			}
@@ -98,124 +406,635 @@ func (t *Terrapin) Add(data []byte) error {
 	return nil
 }
 
+// AddReader adds all data read from r until EOF, as if passed to Add, and returns the number of bytes added
+// Read errors other than io.EOF are returned along with the number of bytes added before them
+func (t *Terrapin) AddReader(r io.Reader) (int64, error) {
+	// Ensure the Terrapin instance is not finalized
+	if t.finalized {
+		return 0, &AlreadyFinalizedError{}
+	}
+
+	// Read data in blocks, throttled by any read rate limit
+	r = t.limitReader(context.Background(), r)
+	buffer := make([]byte, t.blockSize)
+	var total int64
+	for {
+		n, err := r.Read(buffer)
+		if n > 0 {
+			if addErr := t.Add(buffer[:n]); addErr != nil {
+				return total, addErr
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// AddFile adds the content of the file at path, as AddReader does, closing the file once it is read
+func (t *Terrapin) AddFile(path string) error {
+	// Ensure the Terrapin instance is not finalized
+	if t.finalized {
+		return &AlreadyFinalizedError{}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := t.AddReader(file); err != nil {
+		return fmt.Errorf("failed to attest %s: %w", path, err)
+	}
+	return nil
+}
+
+// Clone returns an independent copy of the instance, including its buffered data and attestations, so a
+// common prefix can be added once and then continued with different data in each copy
+// The copy holds no OS resources, so it needs no Close. Instances writing to an attestation sink cannot be
+// cloned, as both copies would write to it, nor can whole-file gitoids whose hash state cannot be marshaled
+func (t *Terrapin) Clone() (*Terrapin, error) {
+	if t.sink != nil {
+		return nil, errors.New("cannot clone an instance writing to an attestation sink")
+	}
+
+	clone := *t
+	clone.attestations = bytes.Clone(t.attestations)
+	clone.buffer = append(make([]byte, 0, cap(t.buffer)), t.buffer...)
+	clone.chunkLengths = slices.Clone(t.chunkLengths)
+	clone.closer = nil
+
+	// Copy the whole-file hash state through its binary encoding
+	if t.fileHasher != nil {
+		marshaler, ok := t.fileHasher.(encoding.BinaryMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("cannot clone %s whole-file hash state", t.algorithm)
+		}
+		state, err := marshaler.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("cannot clone %s whole-file hash state: %w", t.algorithm, err)
+		}
+		info, _ := t.algorithm.info()
+		clone.fileHasher = info.newHash()
+		unmarshaler, ok := clone.fileHasher.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return nil, fmt.Errorf("cannot clone %s whole-file hash state", t.algorithm)
+		}
+		if err := unmarshaler.UnmarshalBinary(state); err != nil {
+			return nil, fmt.Errorf("cannot clone %s whole-file hash state: %w", t.algorithm, err)
+		}
+	}
+	return &clone, nil
+}
+
 // Finalize finalizes the attestation process by hashing any remaining buffer content
 // Returns the gitoid URI, attestations, and any error encountered
+// The returned URI is the gitoid of the attestations blob, not of the data itself; see FileGitoid for the latter
+// If hashing fails the instance is left unchanged, so Finalize may simply be retried
 func (t *Terrapin) Finalize() (string, []byte, error) {
+	if err := t.finalize(); err != nil {
+		return "", nil, err
+	}
+	// Return the gitoid URI and a copy of the attestations
+	return t.rootURI, t.blob(t.attestations), nil
+}
+
+// finalize hashes any remaining buffer content and computes the root gitoid, unless already finalized
+func (t *Terrapin) finalize() error {
 	// Ensure the Terrapin instance is not already finalized
 	if !t.finalized {
-		// Update the hash buffer for any remaining data
-		if err := t.updateHashBuffer(); err != nil {
-			return "", nil, err
+		// Ensure the whole file was added before its gitoid is computed
+		if t.fileHasher != nil && t.size != t.fileLength {
+			return fmt.Errorf("added %d bytes but declared file length is %d", t.size, t.fileLength)
 		}
-		// Create a new gitoid for the final attestations
-		gid, err := gitoid.New(bytes.NewReader(t.attestations), gitoid.WithSha256())
+
+		// Split the remaining data into its final chunks
+		if t.chunker != nil {
+			if err := t.splitChunks(true); err != nil {
+				return err
+			}
+		}
+
+		// Hash any remaining data, but only commit it once the root hash succeeds
+		attestations := t.attestations
+		if len(t.buffer) > 0 {
+			hash, err := t.hashBuffer()
+			if err != nil {
+				return err
+			}
+			// Limit capacity so append copies instead of writing into t.attestations
+			attestations = append(t.attestations[:len(t.attestations):len(t.attestations)], hash...)
+		}
+		// Create a new gitoid for the final attestations, including any header
+		root, err := hashGitoid(t.rootType, t.algorithm, t.blobParts(attestations, false)...)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to hash terrapin: %w", err)
+			return fmt.Errorf("failed to hash terrapin: %w", err)
+		}
+		// Complete the streamed attestations with the final chunk hash
+		if err := t.writeSink(attestations[len(t.attestations):]); err != nil {
+			return err
+		}
+		t.attestations = attestations
+		if len(t.buffer) > 0 {
+			t.reportProgress(len(t.buffer))
+		}
+		// The final short chunk stays buffered, so Continue can extend it
+		t.rootURI = gitoidURI(t.rootType, t.algorithm, root)
+		if t.fileHasher != nil {
+			t.fileGitoidURI = gitoidURI(gitoid.BLOB, t.algorithm, t.fileHasher.Sum(nil))
 		}
-		t.gid = gid
 		t.finalized = true
 	}
-	// Return the gitoid URI and a copy of the attestations
-	return t.gid.URI(), append([]byte(nil), t.attestations...), nil
+	return nil
+}
+
+// WriteTo finalizes the instance if needed and writes its attestations to w, implementing io.WriterTo
+// The chunk hashes are written directly from the instance, without the copy of the whole blob Finalize returns
+func (t *Terrapin) WriteTo(w io.Writer) (int64, error) {
+	if err := t.finalize(); err != nil {
+		return 0, err
+	}
+	var written int64
+	for _, part := range t.blobParts(t.attestations, true) {
+		n, err := w.Write(part)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("failed to write attestations: %w", err)
+		}
+	}
+	return written, nil
+}
+
+// Continue reopens a finalized instance so further calls to Add extend the attested data, as if Finalize had
+// not been called: a final short chunk is hashed again once more data completes it, so finalizing again gives
+// the same result as attesting all the data in one pass. The gitoid URI and attestations previously returned
+// by Finalize no longer describe the instance
+// Only instances that attested their own data with Add can be continued, and not those writing to an
+// attestation sink, which already received the final chunk hash, or splitting data with a Chunker, whose final
+// chunk boundary was forced by Finalize
+func (t *Terrapin) Continue() error {
+	if !t.finalized {
+		return errors.New("terrapin not finalized")
+	}
+	if t.sink != nil {
+		return errors.New("cannot continue an instance writing to an attestation sink")
+	}
+	if t.chunker != nil {
+		return errors.New("cannot continue an instance splitting data with a chunker")
+	}
+	// Instances loaded from attestations hashed none of their chunks themselves
+	if t.hashed == 0 && len(t.attestations) > 0 {
+		return errors.New("only instances that attested their own data can be continued")
+	}
+
+	// Drop the hash of the final short chunk, which is still buffered
+	if len(t.buffer) > 0 {
+		t.attestations = t.attestations[:len(t.attestations)-t.digestSize()]
+		t.hashed -= int64(len(t.buffer))
+	}
+	t.finalized = false
+	t.rootURI = ""
+	t.fileGitoidURI = ""
+	return nil
+}
+
+// FileGitoid returns the gitoid URI of the whole attested file, as gitoid.New would compute it over the data
+// This differs from the URI returned by Finalize, which identifies the attestations blob
+// It requires the WithFileGitoid option and a finalized instance
+func (t *Terrapin) FileGitoid() (string, error) {
+	if t.fileHasher == nil {
+		return "", errors.New("whole-file gitoid not enabled")
+	}
+	if !t.finalized {
+		return "", errors.New("terrapin not finalized")
+	}
+	return t.fileGitoidURI, nil
 }
 
 // VerifyBuffer verifies the entire data stream from the reader against the attestations
+// Only the last attested chunk may be short: data ending partway through an earlier chunk, or on a chunk boundary
+// before the last chunk, is reported with a *TruncatedDataError rather than as a mismatch; use VerifyPrefix to
+// verify the beginning of the data
 // Returns true if verification succeeds, false otherwise
 func (t *Terrapin) VerifyBuffer(reader io.Reader) (bool, error) {
+	return t.VerifyBufferContext(context.Background(), reader)
+}
+
+// VerifyBufferContext is like VerifyBuffer, but checks ctx between chunks and returns its error once it is
+// cancelled, so long verifications can be abandoned, for example when the client of a server disconnects
+func (t *Terrapin) VerifyBufferContext(ctx context.Context, reader io.Reader) (bool, error) {
+	return verifyResult(t.verifyBuffer(ctx, t.deframe(reader), nil))
+}
+
+// VerifyBufferDetailed is like VerifyBuffer, but also returns the byte offset of the first chunk that failed
+// verification, so only that range needs to be fetched again; data beyond the attested chunks fails at the
+// offset where the attestations end. The offset is -1 if verification succeeds or returns an error
+func (t *Terrapin) VerifyBufferDetailed(reader io.Reader) (bool, int64, error) {
+	mismatch, err := t.verifyBuffer(context.Background(), t.deframe(reader), nil)
+	if err != nil {
+		return false, -1, err
+	}
+	if mismatch != nil {
+		return false, mismatch.Offset, nil
+	}
+	return true, -1, nil
+}
+
+// VerifyBufferStrict is like VerifyBuffer, but reports the first chunk that fails verification as a
+// *ChunkMismatchError rather than returning false
+// Returns nil if verification succeeds
+func (t *Terrapin) VerifyBufferStrict(reader io.Reader) error {
+	mismatch, err := t.verifyBuffer(context.Background(), t.deframe(reader), nil)
+	if err != nil {
+		return err
+	}
+	if mismatch != nil {
+		return mismatch
+	}
+	return nil
+}
+
+// VerifyWithExpectedGitoid verifies the data stream from the reader like VerifyBufferStrict, after first
+// checking that the attestations are the ones expected: their root gitoid must be expectedURI, such as one
+// from a signed manifest, so swapped-in attestations that are merely consistent with the data are rejected
+// A root that does not match is reported as a *GitoidMismatchError before any data is read, data that does not
+// match as a *ChunkMismatchError, and data missing any of the NumChunks attested chunks as a *TruncatedDataError
+// Returns true if both the attestations and the data verify, with every attested chunk present
+func (t *Terrapin) VerifyWithExpectedGitoid(reader io.Reader, expectedURI string) (bool, error) {
 	// Ensure the Terrapin instance is finalized
 	if !t.finalized {
 		return false, errors.New("terrapin not finalized")
 	}
+	if t.rootURI != expectedURI {
+		return false, &GitoidMismatchError{Expected: expectedURI, Got: t.rootURI}
+	}
+	if err := t.VerifyBufferStrict(reader); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deframe strips transport framing from reader using the deframer, if one is set
+func (t *Terrapin) deframe(reader io.Reader) io.Reader {
+	if t.deframer == nil {
+		return reader
+	}
+	return t.deframer(reader)
+}
+
+// verifyResult converts the result of verifyBuffer into that of VerifyBuffer
+func verifyResult(mismatch *ChunkMismatchError, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	return mismatch == nil, nil
+}
+
+// verifyBuffer verifies the entire data stream from the reader, which has already been deframed, returning the
+// first chunk that fails verification, or nil if all match; it stops with ctx's error once ctx is cancelled
+// If onChunk is not nil, it is called with the data of each chunk once it verifies
+func (t *Terrapin) verifyBuffer(ctx context.Context, reader io.Reader, onChunk func(data []byte)) (*ChunkMismatchError, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	if t.variable {
+		return t.verifyVariable(ctx, reader, onChunk)
+	}
 
-	// Buffer to read data in chunks
-	buffer := make([]byte, BufferCapacity)
+	// Buffer to read data in chunks, throttled by any read rate limit
+	reader = t.limitReader(ctx, reader)
+	buffer := make([]byte, t.blockSize)
 	offset := 0
+	chunks := 0
 
 	// Read data from the reader in chunks and verify against attestations
-	for {
-		n, err := reader.Read(buffer)
-		if err != nil && err != io.EOF {
-			return false, err
+	for ; ; chunks++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := io.ReadFull(reader, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
 		}
 		if n == 0 {
 			break
 		}
 
+		index := offset / t.blockSize
+		attestationIndex := index * t.digestSize()
+		if attestationIndex+t.digestSize() > len(t.attestations) {
+			return &ChunkMismatchError{Index: index, Offset: int64(offset)}, nil // More data than attested
+		}
+		if err := t.checkShortChunk(index, n); err != nil {
+			return nil, err
+		}
+
 		// Create a new gitoid for the current chunk of data
-		gid, err := gitoid.New(bytes.NewReader(buffer[:n]), gitoid.WithSha256())
+		computedHash, err := t.hashChunk(buffer[:n])
 		if err != nil {
-			return false, err
+			return nil, err
 		}
-		computedHash := gid.Bytes()
-		attestationIndex := (offset / BufferCapacity) * sha256.Size
-		expectedHash := t.attestations[attestationIndex : attestationIndex+sha256.Size]
+		expectedHash := t.attestations[attestationIndex : attestationIndex+t.digestSize()]
 
 		// Compare the computed hash with the expected hash
 		if !bytes.Equal(computedHash, expectedHash) {
-			return false, nil // Hash mismatch
+			return t.chunkMismatch(index, int64(offset), computedHash), nil // Hash mismatch
+		}
+		if onChunk != nil {
+			onChunk(buffer[:n])
 		}
 
 		offset += n
+		if t.progress != nil {
+			t.progress(int64(offset))
+		}
 	}
 
-	return true, nil // All hashes match
+	// The data must cover every attested chunk
+	if chunks < t.NumChunks() {
+		return nil, &TruncatedDataError{Chunk: chunks}
+	}
+	return nil, nil // All hashes match
+}
+
+// chunkMismatch returns the error for the chunk at index, starting at offset, whose data hashed to got
+func (t *Terrapin) chunkMismatch(index int, offset int64, got []byte) *ChunkMismatchError {
+	return &ChunkMismatchError{
+		Index:    index,
+		Offset:   offset,
+		Expected: bytes.Clone(t.attestations[index*t.digestSize() : (index+1)*t.digestSize()]),
+		Got:      got,
+	}
+}
+
+// BlockSize returns the size of each attested chunk, as set by WithBlockSize or recorded in the attestations
+// header; only the final chunk may be shorter
+func (t *Terrapin) BlockSize() int {
+	return t.blockSize
+}
+
+// NumChunks returns the number of chunks attested so far
+func (t *Terrapin) NumChunks() int {
+	return len(t.attestations) / t.digestSize()
+}
+
+// Size returns the total number of bytes added so far, the exact length of the attested data once finalized
+// Attestations do not record the data length, so instances loaded from them return 0, except those loaded
+// from a JSON document recording the size
+func (t *Terrapin) Size() int64 {
+	return t.size
+}
+
+// ChunkHashes returns a copy of each chunk hash attested so far, in chunk order, so callers can iterate the
+// digests without slicing the attestations themselves
+func (t *Terrapin) ChunkHashes() [][]byte {
+	hashes := make([][]byte, 0, t.NumChunks())
+	for i := 0; i < t.NumChunks(); i++ {
+		hashes = append(hashes, bytes.Clone(t.attestations[i*t.digestSize():(i+1)*t.digestSize()]))
+	}
+	return hashes
+}
+
+// UniqueChunkHashes returns the distinct chunk hashes attested so far, sorted in ascending byte order, so
+// content-addressed storage can enumerate exactly which chunks to persist. Unlike the attestations, the result
+// records neither the position nor the multiplicity of chunks, so it cannot be used for verification
+func (t *Terrapin) UniqueChunkHashes() [][]byte {
+	hashes := make([][]byte, 0, t.NumChunks())
+	for i := 0; i < t.NumChunks(); i++ {
+		hashes = append(hashes, t.attestations[i*t.digestSize():(i+1)*t.digestSize()])
+	}
+	slices.SortFunc(hashes, bytes.Compare)
+	hashes = slices.CompactFunc(hashes, bytes.Equal)
+
+	// Copy the hashes so callers cannot modify the attestations
+	for i, hash := range hashes {
+		hashes[i] = bytes.Clone(hash)
+	}
+	return hashes
+}
+
+// Epoch returns the attestation generation set by WithEpoch or recorded in the attestations header, 0 if none
+func (t *Terrapin) Epoch() uint64 {
+	return t.epoch
+}
+
+// VerifiablePrefix returns the number of leading bytes covered by the attestations
+// This is useful when only the first chunks of a damaged attestation blob could be recovered
+func (t *Terrapin) VerifiablePrefix() int64 {
+	return t.chunkOffset(len(t.attestations) / t.digestSize())
+}
+
+// ChunkByteRange returns the byte range of the chunk at index within the attested data, from start up to but
+// excluding end. The data length is not recorded in attestations, so for loaded attestations with fixed-size
+// chunks the final chunk's range extends to the full block size; for attested data it ends with the data
+// Indexes outside the attested chunks give an empty range at the nearer end of the attested data
+func (t *Terrapin) ChunkByteRange(index int) (start, end int64) {
+	if index < 0 || t.NumChunks() == 0 {
+		return 0, 0
+	}
+	if index >= t.NumChunks() {
+		_, end = t.ChunkByteRange(t.NumChunks() - 1)
+		return end, end
+	}
+	start = t.chunkOffset(index)
+	end = start + int64(t.chunkLength(index))
+	if t.size > 0 {
+		end = min(end, t.size)
+	}
+	return start, end
+}
+
+// ChunkIndexForOffset returns the index of the chunk holding the byte at offset within the attested data
+// Returns an error if offset is negative or beyond the attested data, as bounded by ChunkByteRange
+func (t *Terrapin) ChunkIndexForOffset(offset int64) (int, error) {
+	if _, end := t.ChunkByteRange(t.NumChunks() - 1); offset < 0 || offset >= end {
+		return 0, fmt.Errorf("offset %d is outside the %d attested bytes", offset, end)
+	}
+	if !t.variable {
+		return int(offset / int64(t.blockSize)), nil
+	}
+	index := 0
+	for offset >= int64(t.chunkLengths[index]) {
+		offset -= int64(t.chunkLengths[index])
+		index++
+	}
+	return index, nil
+}
+
+// VerifyBufferPrefix verifies only the first VerifiablePrefix bytes from the reader against the attestations
+// Data beyond the verifiable prefix is not read
+// Returns true if verification succeeds, false otherwise
+func (t *Terrapin) VerifyBufferPrefix(reader io.Reader) (bool, error) {
+	return verifyResult(t.verifyBuffer(context.Background(), io.LimitReader(t.deframe(reader), t.VerifiablePrefix()), nil))
 }
 
 // VerifyBufferRange verifies a specific range of data from the reader against the attestations
+// The reader must start at the chunk holding startOffset, and every chunk the range overlaps is verified; the
+// range may end within the short final chunk, but a range ending beyond the attested chunks, or beyond the data
+// when its length is known, is an error
 // Returns true if verification succeeds, false otherwise
 func (t *Terrapin) VerifyBufferRange(reader io.Reader, startOffset, endOffset int) (bool, error) {
+	return t.VerifyBufferRangeContext(context.Background(), reader, startOffset, endOffset)
+}
+
+// VerifyRange verifies the bytes from start up to end of rs against the attestations, seeking rs to the start of
+// the chunk holding start and verifying every chunk the range covers
+// Offsets are those of the attested data, so data with transport framing cannot be verified by range, and
+// ranges ending beyond the VerifiablePrefix of the attested chunks are rejected
+// Returns true if verification succeeds, false otherwise
+func (t *Terrapin) VerifyRange(rs io.ReadSeeker, start, end int64) (bool, error) {
 	// Ensure the Terrapin instance is finalized
 	if !t.finalized {
 		return false, errors.New("terrapin not finalized")
 	}
+	if t.variable {
+		return false, errVariableChunks
+	}
+	if t.deframer != nil {
+		return false, errors.New("framed data cannot be verified by range")
+	}
 
 	// Validate the range
-	if startOffset < 0 || endOffset <= startOffset {
+	if start < 0 || end <= start {
 		return false, errors.New("invalid range")
 	}
+	if end > t.VerifiablePrefix() {
+		return false, fmt.Errorf("range ending at offset %d lies beyond the attested data", end)
+	}
+
+	// Seek to the start of the chunk holding start
+	alignedStart := start - start%int64(t.blockSize)
+	if _, err := rs.Seek(alignedStart, io.SeekStart); err != nil {
+		return false, fmt.Errorf("failed to seek to offset %d: %w", alignedStart, err)
+	}
+	return t.VerifyBufferRange(rs, int(alignedStart), int(end))
+}
+
+// VerifyBufferRangeContext is like VerifyBufferRange, but checks ctx between chunks and returns its error once
+// it is cancelled
+func (t *Terrapin) VerifyBufferRangeContext(ctx context.Context, reader io.Reader, startOffset, endOffset int) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+
+	if t.variable {
+		return false, errVariableChunks
+	}
 
-	// Buffer to read data in chunks
-	buffer := make([]byte, BufferCapacity)
-	offset := startOffset
+	// Validate the range
+	if startOffset < 0 || endOffset <= startOffset {
+		return false, errors.New("invalid range")
+	}
 
-	// Align startOffset to BufferCapacity boundary
-	startAlignedOffset := (startOffset / BufferCapacity) * BufferCapacity
-	attestationStartIndex := (startAlignedOffset / BufferCapacity) * sha256.Size
+	// Align the range to chunk boundaries, covering every chunk it overlaps
+	startIndex := startOffset / t.blockSize
+	endIndex := endOffset / t.blockSize
+	if endOffset%t.blockSize != 0 {
+		endIndex++
+	}
+	if startIndex >= t.NumChunks() {
+		// No chunk would be read, which must not pass for a successful verification
+		return false, fmt.Errorf("range starting at offset %d lies beyond the attested data", startOffset)
+	}
+	// The range may end within the short final chunk, but not beyond the data when its length is known
+	if endIndex > t.NumChunks() || (t.size > 0 && int64(endOffset) > t.size) {
+		return false, fmt.Errorf("range ending at offset %d lies beyond the attested data", endOffset)
+	}
 
-	// Align endOffset to BufferCapacity boundary
-	endAlignedOffset := ((endOffset + BufferCapacity - 1) / BufferCapacity) * BufferCapacity
-	attestationEndIndex := (endAlignedOffset / BufferCapacity) * sha256.Size
+	// Buffer to read data in chunks, deframed and throttled by any read rate limit
+	reader = t.limitReader(ctx, t.deframe(reader))
+	buffer := make([]byte, t.blockSize)
 
 	// Read data from the reader in chunks and verify against attestations
-	for attestationIndex := attestationStartIndex; attestationIndex < attestationEndIndex; attestationIndex += sha256.Size {
-		n, err := reader.Read(buffer)
-		if err != nil && err != io.EOF {
+	for index := startIndex; index < endIndex; index++ {
+		if err := ctx.Err(); err != nil {
 			return false, err
 		}
-		if n == 0 {
-			break
+		n, err := io.ReadFull(reader, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return false, err
+		}
+		// Only the final chunk may be short, and its hash covers just the data it holds
+		if err := t.checkShortChunk(index, n); err != nil {
+			return false, err
 		}
 
 		// Create a new gitoid for the current chunk of data
-		gid, err := gitoid.New(bytes.NewReader(buffer[:n]), gitoid.WithSha256())
+		computedHash, err := t.hashChunk(buffer[:n])
 		if err != nil {
 			return false, err
 		}
-		computedHash := gid.Bytes()
 
 		// Compare the computed hash with the expected hash
-		expectedHash := t.attestations[attestationIndex : attestationIndex+sha256.Size]
+		expectedHash := t.attestations[index*t.digestSize() : (index+1)*t.digestSize()]
 
 		if !bytes.Equal(computedHash, expectedHash) {
 			return false, nil // Hash mismatch
 		}
-
-		offset += n
 	}
 
 	return true, nil // All hashes match
 }
 
+// checkShortChunk returns a TruncatedDataError if a read of n bytes for the chunk at index is short although
+// only the last attested chunk may be
+func (t *Terrapin) checkShortChunk(index, n int) error {
+	if n < t.chunkLength(index) && index < len(t.attestations)/t.digestSize()-1 {
+		return &TruncatedDataError{Chunk: index, Length: n}
+	}
+	return nil
+}
+
+// TruncatedDataError is an error type for data that ends before the last attested chunk: partway through an
+// earlier chunk, or on a chunk boundary with attested chunks missing
+type TruncatedDataError struct {
+	Chunk  int // Index of the short chunk
+	Length int // Number of bytes of the chunk that were present
+}
+
+// Error implements the error interface for TruncatedDataError
+func (e *TruncatedDataError) Error() string {
+	if e.Length == 0 {
+		return fmt.Sprintf("data truncated before chunk %d, missing attested chunks", e.Chunk)
+	}
+	return fmt.Sprintf("data truncated within chunk %d after %d bytes, before the last attested chunk", e.Chunk, e.Length)
+}
+
+// ChunkMismatchError is an error type identifying the first chunk that failed verification
+type ChunkMismatchError struct {
+	Index    int    // Index of the chunk
+	Offset   int64  // Offset of the chunk's first byte in the data
+	Expected []byte // Attested hash of the chunk, nil for data beyond the attested chunks
+	Got      []byte // Hash of the chunk actually read, nil for data beyond the attested chunks
+}
+
+// Error implements the error interface for ChunkMismatchError
+func (e *ChunkMismatchError) Error() string {
+	if e.Expected == nil {
+		return fmt.Sprintf("data beyond the %d attested chunks at offset %d", e.Index, e.Offset)
+	}
+	return fmt.Sprintf("chunk %d at offset %d does not match its attestation: expected %x, got %x",
+		e.Index, e.Offset, e.Expected, e.Got)
+}
+
+// GitoidMismatchError is an error type for attestations whose root gitoid is not the one expected
+type GitoidMismatchError struct {
+	Expected string // Root gitoid URI the attestations were expected to have
+	Got      string // Root gitoid URI of the attestations
+}
+
+// Error implements the error interface for GitoidMismatchError
+func (e *GitoidMismatchError) Error() string {
+	return fmt.Sprintf("attestations root gitoid %s does not match the expected %s", e.Got, e.Expected)
+}
+
 // AlreadyFinalizedError is an error type for when the Terrapin instance is already finalized
 type AlreadyFinalizedError struct{}
 
@@ -223,3 +1042,13 @@ type AlreadyFinalizedError struct{}
 func (e *AlreadyFinalizedError) Error() string {
 	return "terrapin attestor already finalized"
 }
+
+// InvalidAttestationsError is an error type for attestations that are malformed or internally inconsistent
+type InvalidAttestationsError struct {
+	Reason string // Description of the problem
+}
+
+// Error implements the error interface for InvalidAttestationsError
+func (e *InvalidAttestationsError) Error() string {
+	return "invalid attestations: " + e.Reason
+}
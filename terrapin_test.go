@@ -2,8 +2,14 @@ package terrapin
 
 import (
 	"bytes"
+	"errors"
 	"github.com/edwarnicke/gitoid"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"testing/iotest"
 )
 
 // Tests
@@ -32,6 +38,87 @@ func TestAddData(t *testing.T) {
 	}
 }
 
+func TestAddReader(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	expected := NewTerrapin()
+	if err := expected.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	expectedURI, expectedAttestations, err := expected.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	terrapin := NewTerrapin()
+	n, err := terrapin.AddReader(iotest.HalfReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("AddReader returned an error: %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("Expected %d bytes added, got %d", len(data), n)
+	}
+	uri, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if uri != expectedURI || !bytes.Equal(attestations, expectedAttestations) {
+		t.Error("Expected AddReader to attest the same as Add")
+	}
+
+	// Read errors are propagated with the bytes added before them
+	failing := NewTerrapin()
+	n, err = failing.AddReader(iotest.TimeoutReader(bytes.NewReader(data)))
+	if !errors.Is(err, iotest.ErrTimeout) {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+	if n != BufferCapacity {
+		t.Errorf("Expected %d bytes added before the error, got %d", BufferCapacity, n)
+	}
+
+	var finalizedErr *AlreadyFinalizedError
+	if _, err := terrapin.AddReader(bytes.NewReader(data)); !errors.As(err, &finalizedErr) {
+		t.Errorf("Expected AlreadyFinalizedError, got %v", err)
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	path := filepath.Join(t.TempDir(), "input.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	expectedURI, expectedAttestations, err := AttestReaderPipelined(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+	}
+
+	terrapin := NewTerrapin()
+	if err := terrapin.AddFile(path); err != nil {
+		t.Fatalf("AddFile returned an error: %v", err)
+	}
+	uri, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if uri != expectedURI || !bytes.Equal(attestations, expectedAttestations) {
+		t.Error("Expected AddFile to attest the same as the file's content")
+	}
+
+	if err := NewTerrapin().AddFile(path + ".missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a wrapped not-exist error, got %v", err)
+	}
+	var finalizedErr *AlreadyFinalizedError
+	if err := terrapin.AddFile(path); !errors.As(err, &finalizedErr) {
+		t.Errorf("Expected AlreadyFinalizedError, got %v", err)
+	}
+}
+
 func TestAddDataWhenFinalized(t *testing.T) {
 	terrapin := NewTerrapin()
 	terrapin.Finalize()
@@ -79,3 +166,733 @@ func TestFinalizeWhenAlreadyFinalized(t *testing.T) {
 		t.Errorf("Expected same attestations, got %v and %v", attestation1, attestation2)
 	}
 }
+
+// limitedWriter accepts up to limit bytes, then fails every write
+type limitedWriter struct {
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	n := min(len(p), w.limit)
+	w.limit -= n
+	if n < len(p) {
+		return n, errors.New("writer full")
+	}
+	return n, nil
+}
+
+func TestWriteTo(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	for name, opts := range map[string][]Option{
+		"headerless":  nil,
+		"with header": {WithEpoch(7)},
+		"merkle":      {WithMerkle()},
+	} {
+		attestor, err := NewTerrapinWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithOptions returned an error: %v", name, err)
+		}
+		if err := attestor.Add(data); err != nil {
+			t.Fatalf("%s: Failed to add data: %v", name, err)
+		}
+
+		// WriteTo finalizes the instance itself
+		var written bytes.Buffer
+		n, err := attestor.WriteTo(&written)
+		if err != nil {
+			t.Fatalf("%s: WriteTo returned an error: %v", name, err)
+		}
+		_, attestations, err := attestor.Finalize()
+		if err != nil {
+			t.Fatalf("%s: Failed to finalize terrapin: %v", name, err)
+		}
+		if n != int64(len(attestations)) || !bytes.Equal(written.Bytes(), attestations) {
+			t.Errorf("%s: Expected WriteTo to write the %d bytes of the attestations, wrote %d", name, len(attestations), n)
+		}
+
+		// A failing writer reports the bytes written before the failure
+		n, err = attestor.WriteTo(&limitedWriter{limit: len(attestations) - 1})
+		if err == nil || n != int64(len(attestations)-1) {
+			t.Errorf("%s: Expected a write error after %d bytes, got %d, %v", name, len(attestations)-1, n, err)
+		}
+	}
+}
+
+func TestFinalizeRetryAfterRootHashFailure(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5}
+	reference := NewTerrapin()
+	if err := reference.Add(data); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedGid, expectedAttestations, err := reference.Finalize()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	terrapin := NewTerrapin()
+	if err := terrapin.Add(data); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Fail the second gitoid computation, which is the root hash over the attestations
+	calls := 0
+	original := hashGitoid
+	hashGitoid = func(objectType gitoid.GitObjectType, alg Algorithm, parts ...[]byte) ([]byte, error) {
+		calls++
+		if calls == 2 {
+			return nil, errors.New("injected failure")
+		}
+		return original(objectType, alg, parts...)
+	}
+	defer func() { hashGitoid = original }()
+
+	if _, _, err := terrapin.Finalize(); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if terrapin.finalized {
+		t.Error("Expected finalized to be false after failure")
+	}
+	if len(terrapin.attestations) != 0 {
+		t.Errorf("Expected attestations to be unchanged, got %d bytes", len(terrapin.attestations))
+	}
+	if len(terrapin.buffer) != len(data) {
+		t.Errorf("Expected buffer length %d, got %d", len(data), len(terrapin.buffer))
+	}
+
+	gid, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Expected no error on retry, got %v", err)
+	}
+	if gid != expectedGid {
+		t.Errorf("Expected gid %s, got %s", expectedGid, gid)
+	}
+	if !bytes.Equal(attestations, expectedAttestations) {
+		t.Errorf("Expected attestations %v, got %v", expectedAttestations, attestations)
+	}
+}
+
+func TestNewTerrapinWithAttestations_Empty(t *testing.T) {
+	var invalid *InvalidAttestationsError
+	if _, err := NewTerrapinWithAttestations(nil); !errors.As(err, &invalid) {
+		t.Errorf("Expected empty attestations to be rejected, got %v", err)
+	}
+
+	// A single chunk hash is still accepted
+	single, _ := setupTerrapinWithData(t, []byte("hello"))
+	_, attestations, err := single.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if _, err := NewTerrapinWithAttestations(attestations); err != nil {
+		t.Errorf("Expected a single chunk hash to be accepted, got %v", err)
+	}
+
+	// The attestations of an empty file are accepted on request and verify only empty data
+	empty, err := NewTerrapinWithAttestations(nil, WithEmptyAttestations())
+	if err != nil {
+		t.Fatalf("Expected empty attestations to be accepted with WithEmptyAttestations, got %v", err)
+	}
+	expected, _ := setupTerrapinWithData(t, nil)
+	if empty.rootURI != expected.rootURI {
+		t.Errorf("Expected the root of empty data %s, got %s", expected.rootURI, empty.rootURI)
+	}
+	if match, err := empty.VerifyBuffer(bytes.NewReader([]byte("x"))); err != nil || match {
+		t.Errorf("Expected non-empty data to mismatch, got %v, %v", match, err)
+	}
+}
+
+func TestReadAttestationsFrom(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024), WithEpoch(2))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uri, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// A blob with a partial chunk hash is rejected, leaving the instance usable
+	terrapin := NewTerrapin()
+	var invalidErr *InvalidAttestationsError
+	if _, err := terrapin.ReadAttestationsFrom(bytes.NewReader(attestations[:len(attestations)-1])); !errors.As(err, &invalidErr) {
+		t.Errorf("Expected InvalidAttestationsError for a partial chunk hash, got %v", err)
+	}
+
+	// Stream the attestations through a pipe, so they are read incrementally
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := attestor.WriteTo(writer)
+		writer.CloseWithError(err)
+	}()
+	n, err := terrapin.ReadAttestationsFrom(reader)
+	if err != nil {
+		t.Fatalf("ReadAttestationsFrom returned an error: %v", err)
+	}
+	if n != int64(len(attestations)) {
+		t.Errorf("Expected %d bytes read, got %d", len(attestations), n)
+	}
+	if terrapin.BlockSize() != 1024 || terrapin.Epoch() != 2 {
+		t.Errorf("Expected the header settings to be applied, got block size %d and epoch %d", terrapin.BlockSize(), terrapin.Epoch())
+	}
+	parsedURI, _, err := terrapin.Finalize()
+	if err != nil || parsedURI != uri {
+		t.Errorf("Expected gitoid %s, got %s, %v", uri, parsedURI, err)
+	}
+	valid, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !valid {
+		t.Errorf("Expected data to verify, got %v, %v", valid, err)
+	}
+
+	var finalizedErr *AlreadyFinalizedError
+	if _, err := terrapin.ReadAttestationsFrom(bytes.NewReader(attestations)); !errors.As(err, &finalizedErr) {
+		t.Errorf("Expected AlreadyFinalizedError, got %v", err)
+	}
+	used := NewTerrapin()
+	if err := used.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if _, err := used.ReadAttestationsFrom(bytes.NewReader(attestations)); err == nil {
+		t.Error("Expected an error reading attestations into an instance with data added")
+	}
+}
+
+func TestNewTerrapinWithAttestations_FinalizeFailure(t *testing.T) {
+	attestations := bytes.Repeat([]byte{0xab}, 2*32)
+	injected := errors.New("injected failure")
+
+	original := hashGitoid
+	hashGitoid = func(objectType gitoid.GitObjectType, alg Algorithm, parts ...[]byte) ([]byte, error) {
+		return nil, injected
+	}
+	defer func() { hashGitoid = original }()
+
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if !errors.Is(err, injected) {
+		t.Fatalf("Expected the finalize error to be returned, got %v", err)
+	}
+	if terrapin != nil {
+		t.Error("Expected no instance when finalizing fails")
+	}
+}
+
+func TestFileGitoid(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	expected, err := gitoid.New(bytes.NewReader(data), gitoid.WithSha256())
+	if err != nil {
+		t.Fatalf("Failed to compute gitoid: %v", err)
+	}
+
+	terrapin, err := NewTerrapinWithOptions(WithFileGitoid(int64(len(data))))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := terrapin.FileGitoid(); err == nil {
+		t.Error("Expected error before finalization, got nil")
+	}
+	// Add in uneven pieces to exercise buffering
+	if err := terrapin.Add(data[:100]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := terrapin.Add(data[100:]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	rootURI, _, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	fileURI, err := terrapin.FileGitoid()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fileURI != expected.URI() {
+		t.Errorf("Expected file gitoid %s, got %s", expected.URI(), fileURI)
+	}
+	if fileURI == rootURI {
+		t.Error("Expected file gitoid to differ from the attestations gitoid")
+	}
+}
+
+func TestFileGitoidLengthMismatch(t *testing.T) {
+	terrapin, err := NewTerrapinWithOptions(WithFileGitoid(4))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := terrapin.Add([]byte{1, 2, 3, 4, 5}); err == nil {
+		t.Error("Expected error adding more than the declared length, got nil")
+	}
+	if err := terrapin.Add([]byte{1, 2, 3}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := terrapin.Finalize(); err == nil {
+		t.Error("Expected error finalizing less than the declared length, got nil")
+	}
+	if _, err := NewTerrapin().FileGitoid(); err == nil {
+		t.Error("Expected error when whole-file gitoid is not enabled, got nil")
+	}
+}
+
+func TestAttestationSink(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+
+	for _, opts := range [][]Option{nil, {WithBlockSize(1024)}} {
+		var sink bytes.Buffer
+		terrapin, err := NewTerrapinWithOptions(append(opts, WithAttestationSink(&sink))...)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := terrapin.Add(data); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(opts) > 0 && sink.Len() == 0 {
+			t.Error("Expected completed chunks to be streamed before Finalize")
+		}
+		_, attestations, err := terrapin.Finalize()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !bytes.Equal(sink.Bytes(), attestations) {
+			t.Errorf("Expected streamed attestations %x, got %x", attestations, sink.Bytes())
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	// Close is a no-op for in-memory instances, before and after Finalize
+	terrapin := NewTerrapin()
+	if err := terrapin.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+	terrapin, _ = setupTerrapinWithData(t, []byte("data"))
+	if err := terrapin.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+
+	_, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "data.attestations")
+	if err := os.WriteFile(path, attestations, 0644); err != nil {
+		t.Fatalf("Failed to write attestations: %v", err)
+	}
+	mapped, err := NewTerrapinFromMmap(path)
+	if err != nil {
+		t.Fatalf("NewTerrapinFromMmap returned an error: %v", err)
+	}
+
+	// The mapping is released exactly once
+	calls := 0
+	unmap := mapped.closer
+	mapped.closer = func() error {
+		calls++
+		return unmap()
+	}
+	for i := 0; i < 2; i++ {
+		if err := mapped.Close(); err != nil {
+			t.Fatalf("Close returned an error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the mapping to be released once, got %d", calls)
+	}
+	if mapped.attestations != nil {
+		t.Errorf("Expected Close to drop the mapped attestations")
+	}
+}
+
+func TestUniqueChunkHashes(t *testing.T) {
+	// Chunks 0, 2 and 3 are identical, as are chunks 1 and 4
+	first := bytes.Repeat([]byte{1}, BufferCapacity)
+	second := bytes.Repeat([]byte{2}, BufferCapacity)
+	last := []byte{3}
+	attestor := NewTerrapin()
+	for _, chunk := range [][]byte{first, second, first, first, second, last} {
+		if err := attestor.Add(chunk); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+	if _, _, err := attestor.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	var expected [][]byte
+	for _, chunk := range [][]byte{first, second, last} {
+		hash, err := chunkHash(chunk, gitoid.BLOB, SHA256)
+		if err != nil {
+			t.Fatalf("chunkHash returned an error: %v", err)
+		}
+		expected = append(expected, hash)
+	}
+	slices.SortFunc(expected, bytes.Compare)
+
+	unique := attestor.UniqueChunkHashes()
+	if !slices.EqualFunc(unique, expected, bytes.Equal) {
+		t.Errorf("Expected unique chunk hashes %x, got %x", expected, unique)
+	}
+
+	// The result must not alias the attestations
+	unique[0][0] ^= 0xff
+	if !slices.EqualFunc(attestor.UniqueChunkHashes(), expected, bytes.Equal) {
+		t.Error("Expected modifying the result to leave the attestations unchanged")
+	}
+}
+
+func TestSize(t *testing.T) {
+	data := make([]byte, 2*1024+10)
+	terrapin, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := terrapin.Add(data[:100]); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if _, err := terrapin.AddReader(bytes.NewReader(data[100:])); err != nil {
+		t.Fatalf("AddReader returned an error: %v", err)
+	}
+	_, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if terrapin.Size() != int64(len(data)) {
+		t.Errorf("Expected size %d, got %d", len(data), terrapin.Size())
+	}
+
+	// The data length is not recorded in the attestations
+	loaded, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin instance with attestations: %v", err)
+	}
+	if loaded.Size() != 0 {
+		t.Errorf("Expected an unknown size for loaded attestations, got %d", loaded.Size())
+	}
+}
+
+func TestChunkHashes(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	for _, alg := range []Algorithm{SHA1, SHA256, SHA512} {
+		terrapin, err := NewTerrapinWithOptions(WithBlockSize(1024), WithHashAlgorithm(alg))
+		if err != nil {
+			t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+		}
+		if err := terrapin.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if _, _, err := terrapin.Finalize(); err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+
+		hashes := terrapin.ChunkHashes()
+		if len(hashes) != terrapin.NumChunks() {
+			t.Fatalf("%s: Expected %d chunk hashes, got %d", alg, terrapin.NumChunks(), len(hashes))
+		}
+		for i, hash := range hashes {
+			if len(hash) != alg.Size() {
+				t.Errorf("%s: Expected chunk %d hash of %d bytes, got %d", alg, i, alg.Size(), len(hash))
+			}
+			expected, err := chunkHash(data[i*1024:min((i+1)*1024, len(data))], gitoid.BLOB, alg)
+			if err != nil || !bytes.Equal(hash, expected) {
+				t.Errorf("%s: Expected chunk %d hash %x, got %x", alg, i, expected, hash)
+			}
+		}
+
+		// Modifying the returned hashes leaves the attestations intact
+		hashes[0][0] ^= 0xff
+		if bytes.Equal(terrapin.ChunkHashes()[0], hashes[0]) {
+			t.Errorf("%s: Expected ChunkHashes to return copies", alg)
+		}
+	}
+}
+
+func TestChunkByteRange(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	loaded, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin instance with attestations: %v", err)
+	}
+	variable, err := NewTerrapinWithOptions(WithVariableChunks())
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	for _, length := range []int{700, 5, 1300} {
+		if err := variable.AddChunk(make([]byte, length)); err != nil {
+			t.Fatalf("AddChunk returned an error: %v", err)
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		terrapin *Terrapin
+		index    int
+		start    int64
+		end      int64
+	}{
+		"first chunk":             {attestor, 0, 0, 1024},
+		"short final chunk":       {attestor, 3, 3072, 3082},
+		"past the last chunk":     {attestor, 4, 3082, 3082},
+		"negative index":          {attestor, -1, 0, 0},
+		"loaded final chunk":      {loaded, 3, 3072, 4096},
+		"variable middle chunk":   {variable, 1, 700, 705},
+		"variable past the chunk": {variable, 3, 2005, 2005},
+	} {
+		start, end := tc.terrapin.ChunkByteRange(tc.index)
+		if start != tc.start || end != tc.end {
+			t.Errorf("%s: Expected range %d-%d, got %d-%d", name, tc.start, tc.end, start, end)
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		terrapin *Terrapin
+		offset   int64
+		index    int
+		valid    bool
+	}{
+		"first byte":          {attestor, 0, 0, true},
+		"chunk boundary":      {attestor, 2048, 2, true},
+		"last byte":           {attestor, 3081, 3, true},
+		"past the data":       {attestor, 3082, 0, false},
+		"negative offset":     {attestor, -1, 0, false},
+		"loaded final block":  {loaded, 4095, 3, true},
+		"past loaded chunks":  {loaded, 4096, 0, false},
+		"variable short one":  {variable, 704, 1, true},
+		"variable last chunk": {variable, 705, 2, true},
+		"past variable":       {variable, 2005, 0, false},
+	} {
+		index, err := tc.terrapin.ChunkIndexForOffset(tc.offset)
+		if !tc.valid {
+			if err == nil {
+				t.Errorf("%s: Expected an error, got chunk %d", name, index)
+			}
+			continue
+		}
+		if err != nil || index != tc.index {
+			t.Errorf("%s: Expected chunk %d, got %d, %v", name, tc.index, index, err)
+		}
+	}
+	if _, err := NewTerrapin().ChunkIndexForOffset(0); err == nil {
+		t.Error("Expected an error for an instance without chunks")
+	}
+}
+
+func TestNumChunks(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithBlockSize(1024), WithHashAlgorithm(SHA512)}} {
+		attestor, err := NewTerrapinWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+		}
+		if attestor.NumChunks() != 0 {
+			t.Errorf("Expected no chunks before adding data, got %d", attestor.NumChunks())
+		}
+		if err := attestor.Add(make([]byte, 3*attestor.BlockSize()+1)); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		_, attestations, err := attestor.Finalize()
+		if err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		if attestor.NumChunks() != 4 {
+			t.Errorf("Expected 4 chunks after finalizing, got %d", attestor.NumChunks())
+		}
+
+		// The count is the same when parsed from the attestations, headered or not
+		parsed, err := NewTerrapinWithAttestations(attestations)
+		if err != nil {
+			t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+		}
+		if parsed.NumChunks() != 4 {
+			t.Errorf("Expected 4 chunks from the attestations, got %d", parsed.NumChunks())
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	prefix := make([]byte, BufferCapacity+100)
+	for i := range prefix {
+		prefix[i] = byte(i % 251)
+	}
+	original, err := NewTerrapinWithOptions(WithFileGitoid(int64(len(prefix) + 10)))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := original.Add(prefix); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	// Clone mid-stream, with data buffered, then continue each copy differently
+	clone, err := original.Clone()
+	if err != nil {
+		t.Fatalf("Clone returned an error: %v", err)
+	}
+	if err := original.Add(bytes.Repeat([]byte{'a'}, 10)); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if err := clone.Add(bytes.Repeat([]byte{'b'}, 10)); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	originalURI, _, err := original.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize original: %v", err)
+	}
+	cloneURI, cloneAttestations, err := clone.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize clone: %v", err)
+	}
+	if originalURI == cloneURI {
+		t.Error("Expected different continuations to produce different gitoids")
+	}
+
+	// The clone matches attesting its full data directly
+	data := append(bytes.Clone(prefix), bytes.Repeat([]byte{'b'}, 10)...)
+	expectedURI, expectedAttestations, err := AttestFull(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("AttestFull returned an error: %v", err)
+	}
+	fileURI, err := clone.FileGitoid()
+	if err != nil {
+		t.Fatalf("FileGitoid returned an error: %v", err)
+	}
+	if fileURI != expectedURI || !bytes.Equal(cloneAttestations, expectedAttestations) {
+		t.Error("Expected the clone to attest the prefix followed by its own data")
+	}
+
+	withSink, err := NewTerrapinWithOptions(WithAttestationSink(&bytes.Buffer{}))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if _, err := withSink.Clone(); err == nil {
+		t.Error("Expected error cloning an instance with an attestation sink, got nil")
+	}
+}
+
+func TestContinue(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+300)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	expectedURI, expectedAttestations, err := AttestReaderPipelined(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("AttestReaderPipelined returned an error: %v", err)
+	}
+
+	// Finalize mid-chunk, then continue with the rest of the data
+	terrapin := NewTerrapin()
+	if err := terrapin.Continue(); err == nil {
+		t.Error("Expected an error continuing an unfinalized instance")
+	}
+	if err := terrapin.Add(data[:BufferCapacity+100]); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	firstURI, _, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize: %v", err)
+	}
+	if err := terrapin.Continue(); err != nil {
+		t.Fatalf("Continue returned an error: %v", err)
+	}
+	if err := terrapin.Add(data[BufferCapacity+100:]); err != nil {
+		t.Fatalf("Failed to add data after continuing: %v", err)
+	}
+	uri, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize after continuing: %v", err)
+	}
+	if uri == firstURI {
+		t.Error("Expected the gitoid to change after continuing")
+	}
+	if uri != expectedURI || !bytes.Equal(attestations, expectedAttestations) {
+		t.Error("Expected continued attestation to match single-pass attestation")
+	}
+
+	// Attestations loaded without their data cannot be extended
+	loaded, err := NewTerrapinWithAttestations(expectedAttestations)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	if err := loaded.Continue(); err == nil {
+		t.Error("Expected an error continuing loaded attestations")
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	data := make([]byte, 3*1024+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	var reports []int64
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024), WithProgress(func(n int64) {
+		reports = append(reports, n)
+	}))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	for _, part := range [][]byte{data[:700], data[700:2100], data[2100:]} {
+		if err := attestor.Add(part); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+	if !slices.Equal(reports, []int64{1024, 2048, 3072}) {
+		t.Errorf("Expected a report after each full chunk, got %v", reports)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if !slices.Equal(reports, []int64{1024, 2048, 3072, 3172}) {
+		t.Errorf("Expected Finalize to report the final chunk, got %v", reports)
+	}
+	if _, _, err := attestor.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if len(reports) != 4 {
+		t.Errorf("Expected no reports after Finalize, got %v", reports)
+	}
+
+	// Verification reports the bytes verified so far
+	reports = nil
+	verifier, err := NewTerrapinWithAttestations(attestations, WithProgress(func(n int64) {
+		reports = append(reports, n)
+	}))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	if valid, err := verifier.VerifyBuffer(bytes.NewReader(data)); err != nil || !valid {
+		t.Fatalf("Expected data to verify, got %v, %v", valid, err)
+	}
+	if !slices.Equal(reports, []int64{1024, 2048, 3072, 3172}) {
+		t.Errorf("Expected a report after each verified chunk, got %v", reports)
+	}
+
+	if _, err := NewTerrapinWithOptions(WithProgress(nil)); err == nil {
+		t.Error("Expected error for a nil progress report, got nil")
+	}
+}
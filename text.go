@@ -0,0 +1,139 @@
+package terrapin
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The text attestation format is a human-editable alternative to the binary blob, convenient for version
+// control and grep: one lowercase hex chunk hash per line, in chunk order, preceded by a comment line recording
+// the settings, for example
+//
+//	# algo=sha256 chunk=2097152
+//	0123...
+//
+// Blank lines and lines starting with '#' are ignored, except that key=value words in comments set the hash
+// algorithm (algo) and block size (chunk); any other key is rejected. Only the hash algorithm and block size
+// can be recorded, so other settings have no text form.
+
+// WriteAttestationsText writes the attestations of a finalized instance to w in the text format
+func (t *Terrapin) WriteAttestationsText(w io.Writer) error {
+	if !t.finalized {
+		return errors.New("terrapin not finalized")
+	}
+	if t.variable || t.merkle || t.rawChunks || t.digestLen != 0 || t.chunkType != gitoid.BLOB || t.rootType != gitoid.BLOB {
+		return errors.New("the text format only records the hash algorithm and block size")
+	}
+
+	buffered := bufio.NewWriter(w)
+	fmt.Fprintf(buffered, "# algo=%s chunk=%d\n", t.algorithm, t.blockSize)
+	for i := 0; i < t.NumChunks(); i++ {
+		fmt.Fprintf(buffered, "%x\n", t.attestations[i*t.digestSize():(i+1)*t.digestSize()])
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write attestations: %w", err)
+	}
+	return nil
+}
+
+// NewTerrapinFromText initializes a Terrapin instance from attestations in the text format read from r
+// Settings recorded in comments take precedence over opts, which otherwise must match the producer's settings
+func NewTerrapinFromText(r io.Reader, opts ...Option) (*Terrapin, error) {
+	// hashLine is a chunk hash and the line holding it
+	type hashLine struct {
+		hash []byte
+		line int
+	}
+
+	var settings []Option
+	var hashes []hashLine
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		// Comments may record settings as key=value words
+		if strings.HasPrefix(text, "#") {
+			for _, word := range strings.Fields(text[1:]) {
+				key, value, ok := strings.Cut(word, "=")
+				if !ok {
+					continue
+				}
+				if seen[key] {
+					return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("line %d: duplicate setting %s", line, key)}
+				}
+				seen[key] = true
+				option, err := textSetting(key, value)
+				if err != nil {
+					return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("line %d: %v", line, err)}
+				}
+				settings = append(settings, option)
+			}
+			continue
+		}
+
+		hash, err := hex.DecodeString(text)
+		if err != nil {
+			return nil, &InvalidAttestationsError{Reason: fmt.Sprintf("line %d: invalid hex chunk hash", line)}
+		}
+		hashes = append(hashes, hashLine{hash: hash, line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read attestations: %w", err)
+	}
+
+	t, err := NewTerrapinWithOptions(slices.Concat(opts, settings)...)
+	if err != nil {
+		return nil, err
+	}
+	if t.sink != nil {
+		return nil, errors.New("attestation sink is only supported when attesting")
+	}
+	if t.variable || t.merkle {
+		return nil, errors.New("the text format does not support variable-size chunks or Merkle mode")
+	}
+
+	// Every line must hold exactly one hash of the digest size
+	for _, h := range hashes {
+		if len(h.hash) != t.digestSize() {
+			return nil, &InvalidAttestationsError{
+				Reason: fmt.Sprintf("line %d: chunk hash of %d bytes, expected %d", h.line, len(h.hash), t.digestSize()),
+			}
+		}
+		t.attestations = append(t.attestations, h.hash...)
+	}
+	if _, _, err := t.Finalize(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// textSetting returns the option applying a key=value setting of the text format
+func textSetting(key, value string) (Option, error) {
+	switch key {
+	case "algo":
+		alg, err := ParseAlgorithm(value)
+		if err != nil {
+			return nil, err
+		}
+		return WithHashAlgorithm(alg), nil
+	case "chunk":
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block size %q", value)
+		}
+		return WithBlockSize(size), nil
+	default:
+		return nil, fmt.Errorf("unknown setting %s", key)
+	}
+}
@@ -0,0 +1,80 @@
+package terrapin
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAttestationsTextRoundTrip(t *testing.T) {
+	data := make([]byte, 3*1024+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024), WithHashAlgorithm(SHA1))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	uri, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	var text bytes.Buffer
+	if err := attestor.WriteAttestationsText(&text); err != nil {
+		t.Fatalf("WriteAttestationsText returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	if len(lines) != 5 || lines[0] != "# algo=sha1 chunk=1024" {
+		t.Fatalf("Expected a settings comment and 4 chunk hashes, got %q", text.String())
+	}
+
+	// Comments and blank lines may be added by hand
+	edited := strings.Replace(text.String(), "\n", "\n\n# reviewed\n", 2)
+	terrapin, err := NewTerrapinFromText(strings.NewReader(edited))
+	if err != nil {
+		t.Fatalf("NewTerrapinFromText returned an error: %v", err)
+	}
+	parsedURI, parsedAttestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if parsedURI != uri || !bytes.Equal(parsedAttestations, attestations) {
+		t.Error("Expected the text format to round-trip the attestations")
+	}
+	valid, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !valid {
+		t.Errorf("Expected data to verify, got %v, %v", valid, err)
+	}
+
+	// The recorded settings are never appended into spare capacity of the caller's options
+	opts := make([]Option, 0, 8)
+	if _, err := NewTerrapinFromText(strings.NewReader(edited), opts...); err != nil {
+		t.Fatalf("NewTerrapinFromText returned an error: %v", err)
+	}
+	if slices.ContainsFunc(opts[:cap(opts)], func(opt Option) bool { return opt != nil }) {
+		t.Error("Expected the caller's options to be left untouched")
+	}
+}
+
+func TestNewTerrapinFromTextInvalid(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	for name, text := range map[string]string{
+		"not hex":         "# algo=sha256\nxyz\n",
+		"wrong size":      "# algo=sha256\n" + hash[:62] + "\n",
+		"size of another": "# algo=sha1\n" + hash + "\n",
+		"unknown setting": "# algo=sha256 raw=1\n" + hash + "\n",
+		"unknown algo":    "# algo=md5\n" + hash + "\n",
+		"duplicate":       "# chunk=1024\n# chunk=2048\n" + hash + "\n",
+		"bad block size":  "# chunk=big\n" + hash + "\n",
+		"tiny block size": "# chunk=1\n" + hash + "\n",
+	} {
+		if _, err := NewTerrapinFromText(strings.NewReader(text)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
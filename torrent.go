@@ -0,0 +1,32 @@
+package terrapin
+
+import (
+	"crypto/sha1" // #nosec G505 -- BitTorrent piece hashes are SHA-1
+	"fmt"
+)
+
+// WithRawChunkHashes hashes each chunk as a plain digest of its data rather than as a gitoid, matching the
+// piece hashes of BitTorrent-like systems. Raw chunk hashes have no gitoid URIs, so VerifyBufferURIs is not
+// supported and ChunkMismatch URIs are left empty; the root gitoid is computed as usual
+func WithRawChunkHashes() Option {
+	return func(t *Terrapin) error {
+		t.rawChunks = true
+		return nil
+	}
+}
+
+// ImportTorrentPieces converts the piece length and concatenated SHA-1 piece hashes of a torrent into SHA-1
+// attestations with raw chunk hashes, so Terrapin can verify the torrent's data
+// The piece length must be a valid block size; torrent piece lengths are powers of two of at least 16 KiB
+func ImportTorrentPieces(pieceLength int, piecesSHA1 []byte) ([]byte, error) {
+	if len(piecesSHA1)%sha1.Size != 0 {
+		return nil, fmt.Errorf("pieces length %d is not a multiple of the SHA-1 size", len(piecesSHA1))
+	}
+	t, err := NewTerrapinWithOptions(WithBlockSize(pieceLength), WithHashAlgorithm(SHA1), WithRawChunkHashes())
+	if err != nil {
+		return nil, err
+	}
+	t.attestations = append(t.attestations, piecesSHA1...)
+	_, attestations, err := t.Finalize()
+	return attestations, err
+}
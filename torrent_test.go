@@ -0,0 +1,75 @@
+package terrapin
+
+import (
+	"bytes"
+	"crypto/sha1" // #nosec G505 -- BitTorrent piece hashes are SHA-1
+	"testing"
+)
+
+func TestImportTorrentPieces(t *testing.T) {
+	const pieceLength = 16 * 1024
+	data := make([]byte, 3*pieceLength+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	// Build the pieces list as a torrent would: the SHA-1 of each piece, the last one short
+	var pieces []byte
+	for offset := 0; offset < len(data); offset += pieceLength {
+		sum := sha1.Sum(data[offset:min(offset+pieceLength, len(data))])
+		pieces = append(pieces, sum[:]...)
+	}
+
+	attestations, err := ImportTorrentPieces(pieceLength, pieces)
+	if err != nil {
+		t.Fatalf("ImportTorrentPieces returned an error: %v", err)
+	}
+
+	// The imported attestations match attesting the data directly with raw SHA-1 chunk hashes
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(pieceLength), WithHashAlgorithm(SHA1), WithRawChunkHashes())
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, expected, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if !bytes.Equal(attestations, expected) {
+		t.Fatalf("Expected imported attestations to match direct attestation")
+	}
+
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+	data[pieceLength+1] ^= 0xff
+	mismatches, err := terrapin.VerifyAllMismatchesDetailed(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyAllMismatchesDetailed returned an error: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Index != 1 || mismatches[0].ComputedURI != "" {
+		t.Errorf("Expected piece 1 to mismatch without URIs, got %+v", mismatches)
+	}
+
+	for name, tc := range map[string]struct {
+		pieceLength int
+		pieces      []byte
+	}{
+		"partial hash":      {pieceLength, pieces[:sha1.Size+1]},
+		"tiny piece length": {100, pieces},
+	} {
+		if _, err := ImportTorrentPieces(tc.pieceLength, tc.pieces); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
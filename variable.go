@@ -0,0 +1,133 @@
+package terrapin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errVariableChunks is returned by verification methods that assume every chunk but the last is block-sized
+var errVariableChunks = errors.New("not supported for attestations with variable-size chunks")
+
+// WithVariableChunks produces attestations whose chunks may differ in size, such as one chunk per record
+// Data is added with AddChunk, each call attesting exactly one chunk, and the length of every chunk is recorded
+// in the header so verification knows where chunk boundaries fall
+func WithVariableChunks() Option {
+	return func(t *Terrapin) error {
+		t.variable = true
+		return nil
+	}
+}
+
+// AddChunk attests data as a single chunk of its own length; it requires the WithVariableChunks option
+// Chunks must be non-empty and no larger than MaxBlockSize
+func (t *Terrapin) AddChunk(data []byte) error {
+	// Ensure the Terrapin instance is not finalized
+	if t.finalized {
+		return &AlreadyFinalizedError{}
+	}
+	if !t.variable {
+		return errors.New("AddChunk requires variable-size chunks")
+	}
+	if t.chunker != nil {
+		return errors.New("AddChunk cannot be combined with a chunker")
+	}
+	if len(data) == 0 || len(data) > MaxBlockSize {
+		return fmt.Errorf("chunk size %d is outside the range 1 to %d bytes", len(data), MaxBlockSize)
+	}
+
+	// Ensure the data does not exceed the declared whole-file length
+	if t.fileHasher != nil && t.size+int64(len(data)) > t.fileLength {
+		return fmt.Errorf("data exceeds declared file length of %d bytes", t.fileLength)
+	}
+
+	hash, err := t.hashChunk(data)
+	if err != nil {
+		return err
+	}
+
+	// Feed the whole-file hasher if enabled
+	if t.fileHasher != nil {
+		t.fileHasher.Write(data)
+	}
+	t.size += int64(len(data))
+	t.attestations = append(t.attestations, hash...)
+	t.chunkLengths = append(t.chunkLengths, len(data))
+	t.reportProgress(len(data))
+	return nil
+}
+
+// chunkOffset returns the offset of the chunk at index within the attested data
+func (t *Terrapin) chunkOffset(index int) int64 {
+	if !t.variable {
+		return int64(index) * int64(t.blockSize)
+	}
+	var offset int64
+	for _, length := range t.chunkLengths[:index] {
+		offset += int64(length)
+	}
+	return offset
+}
+
+// chunkLength returns the length of the chunk at index; with fixed-size chunks the last one may be shorter
+func (t *Terrapin) chunkLength(index int) int {
+	if !t.variable {
+		return t.blockSize
+	}
+	return t.chunkLengths[index]
+}
+
+// verifyVariable verifies the entire data stream from the reader against variable-size chunk attestations,
+// returning the first chunk that fails verification as verifyBuffer does; it stops with ctx's error once ctx
+// is cancelled, and calls any onChunk with the data of each chunk once it verifies
+func (t *Terrapin) verifyVariable(ctx context.Context, reader io.Reader, onChunk func(data []byte)) (*ChunkMismatchError, error) {
+	reader = t.limitReader(ctx, reader)
+	buffer := make([]byte, 0, t.blockSize)
+	var offset int64
+
+	// Read each chunk's declared length before hashing it
+	for index, length := range t.chunkLengths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if cap(buffer) < length {
+			buffer = make([]byte, 0, length)
+		}
+		n, err := io.ReadFull(reader, buffer[:length])
+		if err == io.EOF {
+			// As with fixed-size chunks, data ending on a chunk boundary is missing the remaining chunks
+			return nil, &TruncatedDataError{Chunk: index}
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+
+		computedHash, err := t.hashChunk(buffer[:n])
+		if err != nil {
+			return nil, err
+		}
+		expectedHash := t.attestations[index*t.digestSize() : (index+1)*t.digestSize()]
+		if n < length || !bytes.Equal(computedHash, expectedHash) {
+			return t.chunkMismatch(index, offset, computedHash), nil // Chunk shorter than attested or hash mismatch
+		}
+		if onChunk != nil {
+			onChunk(buffer[:n])
+		}
+		offset += int64(n)
+		if t.progress != nil {
+			t.progress(offset)
+		}
+	}
+
+	// The data must end with the last chunk
+	n, err := reader.Read(make([]byte, 1))
+	if n > 0 {
+		return &ChunkMismatchError{Index: len(t.chunkLengths), Offset: offset}, nil // More data than attested
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,99 @@
+package terrapin
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVariableChunks(t *testing.T) {
+	chunks := [][]byte{
+		bytes.Repeat([]byte{1}, 700),
+		bytes.Repeat([]byte{2}, 3),
+		bytes.Repeat([]byte{3}, 5000),
+	}
+	data := bytes.Join(chunks, nil)
+
+	attestor, err := NewTerrapinWithOptions(WithVariableChunks())
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.Add(data); err == nil {
+		t.Fatal("Expected Add to be rejected for variable-size chunks")
+	}
+	for _, chunk := range chunks {
+		if err := attestor.AddChunk(chunk); err != nil {
+			t.Fatalf("AddChunk returned an error: %v", err)
+		}
+	}
+	uri, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	if parsedURI, _, _ := terrapin.Finalize(); parsedURI != uri {
+		t.Errorf("Expected URI %s, got %s", uri, parsedURI)
+	}
+
+	match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+	for i := range chunks {
+		match, err := terrapin.VerifyReaderAt(bytes.NewReader(data), i)
+		if err != nil {
+			t.Fatalf("VerifyReaderAt returned an error: %v", err)
+		}
+		if !match {
+			t.Errorf("VerifyReaderAt expected chunk %d to match, but it didn't", i)
+		}
+	}
+
+	// Corrupted, shifted or extended data does not verify
+	corrupt := append([]byte(nil), data...)
+	corrupt[702] ^= 0xff
+	for name, input := range map[string][]byte{
+		"corrupt":  corrupt,
+		"shifted":  append([]byte{0}, data[:len(data)-1]...),
+		"extended": append(append([]byte(nil), data...), 0),
+	} {
+		match, err := terrapin.VerifyBuffer(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: VerifyBuffer returned an error: %v", name, err)
+		}
+		if match {
+			t.Errorf("%s: VerifyBuffer expected to mismatch, but it matched", name)
+		}
+	}
+}
+
+func TestVariableChunks_Invalid(t *testing.T) {
+	if err := NewTerrapin().AddChunk([]byte{1}); err == nil {
+		t.Error("Expected AddChunk to require variable-size chunks")
+	}
+	attestor, err := NewTerrapinWithOptions(WithVariableChunks())
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := attestor.AddChunk(nil); err == nil {
+		t.Error("Expected empty chunks to be rejected")
+	}
+	if err := attestor.AddChunk([]byte{1}); err != nil {
+		t.Fatalf("AddChunk returned an error: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// Dropping the chunk hash leaves a length without a hash
+	if _, err := NewTerrapinWithAttestations(attestations[:len(attestations)-32]); err == nil {
+		t.Error("Expected chunk lengths without matching hashes to be rejected")
+	}
+}
@@ -0,0 +1,109 @@
+package terrapin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// TestVector is a canonical attestation of fixed input, allowing other implementations to check conformance
+type TestVector struct {
+	Name         string `json:"name"`         // Short description of the case
+	Input        string `json:"input"`        // Hex-encoded input data
+	ChunkSize    int    `json:"chunkSize"`    // Block size in bytes
+	Algorithm    string `json:"algorithm"`    // Name of the hash algorithm
+	Attestations string `json:"attestations"` // Hex-encoded attestations blob, including any header
+	RootURI      string `json:"rootURI"`      // Gitoid URI returned by Finalize
+}
+
+// testVectorCase describes the input of a single test vector
+type testVectorCase struct {
+	name      string
+	size      int
+	chunkSize int
+	algorithm Algorithm
+}
+
+// testVectorCases cover empty, sub-chunk, exact-chunk and multi-chunk data, with and without a header
+var testVectorCases = []testVectorCase{
+	{"empty, default chunk size", 0, BufferCapacity, SHA256},
+	{"sub-chunk, default chunk size", 100, BufferCapacity, SHA256},
+	{"empty", 0, MinBlockSize, SHA256},
+	{"sub-chunk", 100, MinBlockSize, SHA256},
+	{"exact chunk", MinBlockSize, MinBlockSize, SHA256},
+	{"multi-chunk", 3*MinBlockSize + 7, MinBlockSize, SHA256},
+	{"multi-chunk, exact", 2 * MinBlockSize, MinBlockSize, SHA256},
+	{"multi-chunk, sha1", 3*MinBlockSize + 7, MinBlockSize, SHA1},
+	{"multi-chunk, sha512", 3*MinBlockSize + 7, MinBlockSize, SHA512},
+}
+
+// GenerateTestVectors returns the canonical test vectors for this implementation
+// The input of each vector is the byte sequence 0, 1, 2, ... taken modulo 251
+func GenerateTestVectors() ([]TestVector, error) {
+	vectors := make([]TestVector, 0, len(testVectorCases))
+	for _, c := range testVectorCases {
+		input := make([]byte, c.size)
+		for i := range input {
+			input[i] = byte(i % 251)
+		}
+
+		t, err := NewTerrapinWithOptions(WithBlockSize(c.chunkSize), WithHashAlgorithm(c.algorithm))
+		if err != nil {
+			return nil, err
+		}
+		if err := t.Add(input); err != nil {
+			return nil, err
+		}
+		uri, attestations, err := t.Finalize()
+		if err != nil {
+			return nil, err
+		}
+
+		vectors = append(vectors, TestVector{
+			Name:         c.name,
+			Input:        hex.EncodeToString(input),
+			ChunkSize:    c.chunkSize,
+			Algorithm:    c.algorithm.String(),
+			Attestations: hex.EncodeToString(attestations),
+			RootURI:      uri,
+		})
+	}
+	return vectors, nil
+}
+
+// Known answer of SelfTest: the default settings over BufferCapacity+100 bytes of the test vector input, one
+// full chunk and one short chunk
+const (
+	selfTestAttestations = "4d525236dcb46bcdc07e034f93e1bc0645ce161cd08a76b083563a4d932a9c00" +
+		"bcd188ac7fc3811d7047b0f7e9dfc3170609d71c6a26f6c4eb10639d24928695"
+	selfTestRootURI = "gitoid:blob:sha256:4364930f608dadb5e6240cda5db4621e838702e13e14836da1a66babab313963"
+)
+
+// SelfTest attests a fixed input with the default settings and checks the attestations and root gitoid URI
+// against known answers, so a broken hash implementation or corrupted build is caught at startup rather than
+// producing attestations nothing else agrees with
+func SelfTest() error {
+	input := make([]byte, BufferCapacity+100)
+	for i := range input {
+		input[i] = byte(i % 251)
+	}
+
+	t := NewTerrapin()
+	if err := t.Add(input); err != nil {
+		return fmt.Errorf("self-test failed: %w", err)
+	}
+	uri, attestations, err := t.Finalize()
+	if err != nil {
+		return fmt.Errorf("self-test failed: %w", err)
+	}
+
+	expected, _ := hex.DecodeString(selfTestAttestations)
+	if !bytes.Equal(attestations, expected) {
+		return errors.New("self-test failed: attestations do not match the known answer")
+	}
+	if uri != selfTestRootURI {
+		return errors.New("self-test failed: root gitoid does not match the known answer")
+	}
+	return nil
+}
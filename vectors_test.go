@@ -0,0 +1,89 @@
+package terrapin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/edwarnicke/gitoid"
+	"os"
+	"testing"
+)
+
+func TestGenerateTestVectors(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatalf("Failed to read test vectors: %v", err)
+	}
+	var expected []TestVector
+	if err := json.Unmarshal(fixture, &expected); err != nil {
+		t.Fatalf("Failed to parse test vectors: %v", err)
+	}
+
+	vectors, err := GenerateTestVectors()
+	if err != nil {
+		t.Fatalf("GenerateTestVectors returned an error: %v", err)
+	}
+	if len(vectors) != len(expected) {
+		t.Fatalf("Expected %d test vectors, got %d", len(expected), len(vectors))
+	}
+	for i, vector := range vectors {
+		if vector != expected[i] {
+			t.Errorf("%s: generated vector differs from testdata/vectors.json", expected[i].Name)
+		}
+	}
+
+	// Each committed vector verifies against its own input
+	for _, vector := range expected {
+		input, err := hex.DecodeString(vector.Input)
+		if err != nil {
+			t.Fatalf("%s: invalid input: %v", vector.Name, err)
+		}
+		attestations, err := hex.DecodeString(vector.Attestations)
+		if err != nil {
+			t.Fatalf("%s: invalid attestations: %v", vector.Name, err)
+		}
+		// The empty vectors hold the zero-length attestations of empty data
+		terrapin, err := NewTerrapinWithAttestations(attestations, WithEmptyAttestations())
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithAttestations returned an error: %v", vector.Name, err)
+		}
+		uri, _, err := terrapin.Finalize()
+		if err != nil {
+			t.Fatalf("%s: Finalize returned an error: %v", vector.Name, err)
+		}
+		if uri != vector.RootURI {
+			t.Errorf("%s: Expected root URI %s, got %s", vector.Name, vector.RootURI, uri)
+		}
+		match, err := terrapin.VerifyBuffer(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: VerifyBuffer returned an error: %v", vector.Name, err)
+		}
+		if !match {
+			t.Errorf("%s: VerifyBuffer expected to match, but it didn't", vector.Name)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("SelfTest returned an error: %v", err)
+	}
+
+	// A hasher producing wrong digests is caught, whether it breaks the chunk hashes or only the root
+	original := hashGitoid
+	defer func() { hashGitoid = original }()
+	for name, tamperedCall := range map[string]int{"chunk": 1, "root": 3} {
+		calls := 0
+		hashGitoid = func(objectType gitoid.GitObjectType, alg Algorithm, parts ...[]byte) ([]byte, error) {
+			hash, err := original(objectType, alg, parts...)
+			calls++
+			if err == nil && calls == tamperedCall {
+				hash[0] ^= 0x01
+			}
+			return hash, err
+		}
+		if err := SelfTest(); err == nil {
+			t.Errorf("%s: expected SelfTest to catch the tampered hasher, got nil", name)
+		}
+	}
+}
@@ -2,8 +2,20 @@ package terrapin
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
 	"io"
+	"math"
+	"slices"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func setupTerrapinWithData(t *testing.T, data []byte) (*Terrapin, io.Reader) {
@@ -55,6 +67,315 @@ func TestVerifyBuffer_MismatchedData(t *testing.T) {
 	}
 }
 
+// shortReader returns at most size bytes from each read of the underlying reader
+type shortReader struct {
+	reader io.Reader
+	size   int
+}
+
+func (r *shortReader) Read(p []byte) (int, error) {
+	return r.reader.Read(p[:min(len(p), r.size)])
+}
+
+func TestVerifyBuffer_PartialReads(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+	corrupt := bytes.Clone(data)
+	corrupt[2*BufferCapacity+5] ^= 0xff
+
+	// Reads returning 7 bytes at a time must not shift the chunk boundaries
+	for name, verify := range map[string]func(io.Reader) (bool, error){
+		"buffer":   terrapin.VerifyBuffer,
+		"parallel": func(r io.Reader) (bool, error) { return terrapin.VerifyBufferParallel(r, 4) },
+		"strict": func(r io.Reader) (bool, error) {
+			err := terrapin.VerifyBufferStrict(r)
+			var mismatch *ChunkMismatchError
+			if errors.As(err, &mismatch) {
+				return false, nil
+			}
+			return err == nil, err
+		},
+		"reader": func(r io.Reader) (bool, error) {
+			_, err := io.Copy(io.Discard, terrapin.VerifyingReader(r))
+			var mismatch *ChunkMismatchError
+			if errors.As(err, &mismatch) {
+				return false, nil
+			}
+			return err == nil, err
+		},
+	} {
+		match, err := verify(&shortReader{reader: bytes.NewReader(data), size: 7})
+		if err != nil || !match {
+			t.Errorf("%s: expected match, got %v, %v", name, match, err)
+		}
+		match, err = verify(&shortReader{reader: bytes.NewReader(corrupt), size: 7})
+		if err != nil || match {
+			t.Errorf("%s: expected mismatch, got %v, %v", name, match, err)
+		}
+	}
+}
+
+func TestVerifyBufferDetailed(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+
+	match, offset, err := terrapin.VerifyBufferDetailed(reader)
+	if err != nil || !match || offset != -1 {
+		t.Fatalf("Expected matching data to verify with offset -1, got %v, %d, %v", match, offset, err)
+	}
+
+	// Corrupt a byte in the third chunk
+	data[2*BufferCapacity+5] ^= 0xff
+	match, offset, err = terrapin.VerifyBufferDetailed(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBufferDetailed returned an error: %v", err)
+	}
+	if match || offset != 2*BufferCapacity {
+		t.Errorf("Expected mismatch at offset %d, got %v, %d", 2*BufferCapacity, match, offset)
+	}
+
+	// Data beyond the attested chunks fails where the attestations end
+	data[2*BufferCapacity+5] ^= 0xff
+	match, offset, err = terrapin.VerifyBufferDetailed(bytes.NewReader(append(data, 1)))
+	if err != nil {
+		t.Fatalf("VerifyBufferDetailed returned an error: %v", err)
+	}
+	if match || offset != int64(len(data)) {
+		t.Errorf("Expected mismatch at offset %d, got %v, %d", len(data), match, offset)
+	}
+}
+
+func TestVerifyBufferStrict(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+	if err := terrapin.VerifyBufferStrict(reader); err != nil {
+		t.Fatalf("Expected matching data to verify, got %v", err)
+	}
+
+	// The first failing chunk is identified along with both hashes
+	corrupt := bytes.Clone(data)
+	corrupt[2*BufferCapacity+7] ^= 0xff
+	corrupt[3*BufferCapacity+1] ^= 0xff
+	err := terrapin.VerifyBufferStrict(bytes.NewReader(corrupt))
+	var mismatch *ChunkMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a ChunkMismatchError, got %v", err)
+	}
+	got, err := chunkHash(corrupt[2*BufferCapacity:3*BufferCapacity], gitoid.BLOB, SHA256)
+	if err != nil {
+		t.Fatalf("chunkHash returned an error: %v", err)
+	}
+	if mismatch.Index != 2 || mismatch.Offset != 2*BufferCapacity ||
+		!bytes.Equal(mismatch.Expected, terrapin.attestations[2*32:3*32]) || !bytes.Equal(mismatch.Got, got) {
+		t.Errorf("Unexpected mismatch %+v", mismatch)
+	}
+
+	// Data beyond the attested chunks has no hashes to compare
+	err = terrapin.VerifyBufferStrict(bytes.NewReader(append(bytes.Clone(data), 1)))
+	if !errors.As(err, &mismatch) || mismatch.Index != 4 || mismatch.Offset != 4*BufferCapacity || mismatch.Expected != nil {
+		t.Errorf("Expected extra data to mismatch at chunk 4, got %v", err)
+	}
+}
+
+func TestVerifyingReader(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+
+	// Matching data passes through unchanged
+	passed, err := io.ReadAll(terrapin.VerifyingReader(reader))
+	if err != nil || !bytes.Equal(passed, data) {
+		t.Fatalf("Expected matching data to pass through, got %d bytes, %v", len(passed), err)
+	}
+
+	// Only the chunks before the first failing one are passed through
+	corrupt := bytes.Clone(data)
+	corrupt[BufferCapacity+7] ^= 0xff
+	passed, err = io.ReadAll(terrapin.VerifyingReader(bytes.NewReader(corrupt)))
+	var mismatch *ChunkMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Index != 1 {
+		t.Errorf("Expected chunk 1 to mismatch, got %v", err)
+	}
+	if !bytes.Equal(passed, data[:BufferCapacity]) {
+		t.Errorf("Expected only the first chunk to pass through, got %d bytes", len(passed))
+	}
+
+	// Data truncated within a chunk or extending past the attested data fails as with VerifyBufferStrict
+	_, err = io.ReadAll(terrapin.VerifyingReader(bytes.NewReader(data[:BufferCapacity+7])))
+	var truncated *TruncatedDataError
+	if !errors.As(err, &truncated) || truncated.Chunk != 1 {
+		t.Errorf("Expected truncation within chunk 1, got %v", err)
+	}
+	// The short last chunk takes in the extra data
+	_, err = io.ReadAll(terrapin.VerifyingReader(bytes.NewReader(append(bytes.Clone(data), 1))))
+	if !errors.As(err, &mismatch) || mismatch.Index != 3 {
+		t.Errorf("Expected extra data to mismatch at chunk 3, got %v", err)
+	}
+	exact, _ := setupTerrapinWithData(t, data[:3*BufferCapacity])
+	_, err = io.ReadAll(exact.VerifyingReader(bytes.NewReader(data)))
+	if !errors.As(err, &mismatch) || mismatch.Index != 3 || mismatch.Expected != nil {
+		t.Errorf("Expected data beyond the attested chunks to mismatch at chunk 3, got %v", err)
+	}
+
+	if _, err := io.ReadAll(NewTerrapin().VerifyingReader(bytes.NewReader(data))); err == nil {
+		t.Error("Expected an error reading through an unfinalized instance")
+	}
+}
+
+func TestVerifyBuffer_TruncatedOnChunkBoundary(t *testing.T) {
+	data := make([]byte, 2*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := terrapin.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if _, _, err := terrapin.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	variable, err := NewTerrapinWithOptions(WithVariableChunks())
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	for _, chunk := range [][]byte{data[:1024], data[1024:]} {
+		if err := variable.AddChunk(chunk); err != nil {
+			t.Fatalf("AddChunk returned an error: %v", err)
+		}
+	}
+	if _, _, err := variable.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// Data ending on a chunk boundary is missing the remaining chunks, however it is verified
+	for _, length := range []int{0, 1024} {
+		verifiers := map[string]func(r io.Reader) error{
+			"VerifyBuffer": func(r io.Reader) error {
+				_, err := terrapin.VerifyBuffer(r)
+				return err
+			},
+			"VerifyBufferDetailed": func(r io.Reader) error {
+				_, _, err := terrapin.VerifyBufferDetailed(r)
+				return err
+			},
+			"VerifyBufferStrict": terrapin.VerifyBufferStrict,
+			"VerifyBufferParallel": func(r io.Reader) error {
+				_, err := terrapin.VerifyBufferParallel(r, 4)
+				return err
+			},
+			"VerifyingReader": func(r io.Reader) error {
+				_, err := io.ReadAll(terrapin.VerifyingReader(r))
+				return err
+			},
+			"variable chunks": func(r io.Reader) error {
+				_, err := variable.VerifyBuffer(r)
+				return err
+			},
+		}
+		for name, verify := range verifiers {
+			var truncated *TruncatedDataError
+			err := verify(bytes.NewReader(data[:length]))
+			if !errors.As(err, &truncated) || truncated.Chunk != length/1024 {
+				t.Errorf("%s, %d bytes: expected truncation before chunk %d, got %v", name, length, length/1024, err)
+			}
+		}
+	}
+}
+
+func TestVerifyWithExpectedGitoid(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+	uri, _, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if match, err := terrapin.VerifyWithExpectedGitoid(reader, uri); err != nil || !match {
+		t.Fatalf("Expected matching data and gitoid to verify, got %v, %v", match, err)
+	}
+
+	// Attestations of other data are rejected by their gitoid, even though the data matches them
+	other, otherReader := setupTerrapinWithData(t, data[:BufferCapacity])
+	match, err := other.VerifyWithExpectedGitoid(otherReader, uri)
+	var gitoidMismatch *GitoidMismatchError
+	if !errors.As(err, &gitoidMismatch) || match || gitoidMismatch.Expected != uri || gitoidMismatch.Got != other.rootURI {
+		t.Errorf("Expected a GitoidMismatchError, got %v, %v", match, err)
+	}
+
+	// The expected attestations still reject corrupt data
+	corrupt := bytes.Clone(data)
+	corrupt[BufferCapacity+1] ^= 0xff
+	match, err = terrapin.VerifyWithExpectedGitoid(bytes.NewReader(corrupt), uri)
+	var chunkMismatch *ChunkMismatchError
+	if !errors.As(err, &chunkMismatch) || match || chunkMismatch.Index != 1 {
+		t.Errorf("Expected a ChunkMismatchError for chunk 1, got %v, %v", match, err)
+	}
+
+	// Every attested chunk must be present, so empty and truncated data do not verify
+	for _, length := range []int{0, BufferCapacity, 2 * BufferCapacity} {
+		match, err := terrapin.VerifyWithExpectedGitoid(bytes.NewReader(data[:length]), uri)
+		var truncated *TruncatedDataError
+		if !errors.As(err, &truncated) || match || truncated.Chunk != length/BufferCapacity {
+			t.Errorf("%d bytes: expected a TruncatedDataError for chunk %d, got %v, %v", length, length/BufferCapacity, match, err)
+		}
+	}
+}
+
+// cancelingReader cancels a context once the first read from the underlying reader completes
+type cancelingReader struct {
+	reader io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.cancel()
+	return n, err
+}
+
+func TestVerifyBufferContext(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+
+	match, err := terrapin.VerifyBufferContext(context.Background(), reader)
+	if err != nil || !match {
+		t.Fatalf("Expected matching data to verify, got %v, %v", match, err)
+	}
+
+	// Cancelling during the first chunk stops verification before the next one
+	for name, verify := range map[string]func(context.Context, io.Reader) (bool, error){
+		"buffer": terrapin.VerifyBufferContext,
+		"range": func(ctx context.Context, r io.Reader) (bool, error) {
+			return terrapin.VerifyBufferRangeContext(ctx, r, 0, len(data))
+		},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		match, err := verify(ctx, &cancelingReader{reader: bytes.NewReader(data), cancel: cancel})
+		if !errors.Is(err, context.Canceled) || match {
+			t.Errorf("%s: expected context.Canceled, got %v, %v", name, match, err)
+		}
+	}
+}
+
 func TestVerifyBufferRange_MatchingData(t *testing.T) {
 	data := make([]byte, 4*BufferCapacity)
 	for i := range data {
@@ -75,6 +396,42 @@ func TestVerifyBufferRange_MatchingData(t *testing.T) {
 	}
 }
 
+func TestVerifyRange(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// Unaligned ranges verify the chunks covering them, including the short last chunk
+	for _, rng := range [][2]int64{{0, 1}, {BufferCapacity + 7, 2*BufferCapacity + 9}, {3*BufferCapacity + 50, int64(len(data))}} {
+		match, err := terrapin.VerifyRange(bytes.NewReader(data), rng[0], rng[1])
+		if err != nil || !match {
+			t.Errorf("Range %d-%d: expected match, got %v, %v", rng[0], rng[1], match, err)
+		}
+	}
+
+	// Corruption outside the covering chunks is not read
+	corrupt := bytes.Clone(data)
+	corrupt[BufferCapacity-1] ^= 0xff
+	if match, err := terrapin.VerifyRange(bytes.NewReader(corrupt), BufferCapacity+7, BufferCapacity+9); err != nil || !match {
+		t.Errorf("Expected a range after the corruption to match, got %v, %v", match, err)
+	}
+	if match, err := terrapin.VerifyRange(bytes.NewReader(corrupt), BufferCapacity-2, BufferCapacity+9); err != nil || match {
+		t.Errorf("Expected a range covering the corruption to mismatch, got %v, %v", match, err)
+	}
+
+	for name, rng := range map[string][2]int64{
+		"negative start":  {-1, 10},
+		"empty":           {10, 10},
+		"beyond attested": {0, 4*BufferCapacity + 1},
+	} {
+		if _, err := terrapin.VerifyRange(bytes.NewReader(data), rng[0], rng[1]); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
 func TestVerifyBufferRange_MismatchedData(t *testing.T) {
 	data := make([]byte, 4*BufferCapacity)
 	for i := range data {
@@ -98,6 +455,78 @@ func TestVerifyBufferRange_MismatchedData(t *testing.T) {
 	}
 }
 
+func TestVerifyBufferRange_OneByteReader(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// A reader returning a byte at a time must still be hashed in whole chunks, up to the short last chunk
+	startOffset := BufferCapacity
+	reader := iotest.OneByteReader(bytes.NewReader(data[startOffset:]))
+	match, err := terrapin.VerifyBufferRange(reader, startOffset, len(data))
+	if err != nil || !match {
+		t.Fatalf("Expected the range to verify through one-byte reads, got %v, %v", match, err)
+	}
+
+	corrupt := bytes.Clone(data)
+	corrupt[2*BufferCapacity+5] ^= 0xff
+	reader = iotest.OneByteReader(bytes.NewReader(corrupt[startOffset:]))
+	match, err = terrapin.VerifyBufferRange(reader, startOffset, len(data))
+	if err != nil || match {
+		t.Errorf("Expected the corrupted range to mismatch, got %v, %v", match, err)
+	}
+}
+
+func TestVerifyBufferRange_PartialFinalChunk(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+	_, attestations, _ := terrapin.Finalize()
+	loaded, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin instance with attestations: %v", err)
+	}
+
+	// Ranges ending within the short final chunk verify it over the data it holds
+	for _, end := range []int{3*BufferCapacity + 80, len(data)} {
+		for name, instance := range map[string]*Terrapin{"attested": terrapin, "loaded": loaded} {
+			match, err := instance.VerifyBufferRange(bytes.NewReader(data[3*BufferCapacity:]), 3*BufferCapacity+50, end)
+			if err != nil || !match {
+				t.Errorf("%s: range ending at %d: expected match, got %v, %v", name, end, match, err)
+			}
+		}
+	}
+	corrupt := bytes.Clone(data)
+	corrupt[len(corrupt)-1] ^= 0xff
+	if match, err := terrapin.VerifyBufferRange(bytes.NewReader(corrupt[2*BufferCapacity:]), 2*BufferCapacity, len(data)); err != nil || match {
+		t.Errorf("Expected a corrupted final chunk to mismatch, got %v, %v", match, err)
+	}
+
+	// The attested data length bounds the range, while loaded attestations only know the final block
+	if _, err := terrapin.VerifyBufferRange(bytes.NewReader(data[3*BufferCapacity:]), 3*BufferCapacity, len(data)+10); err == nil {
+		t.Error("Expected an error for a range ending beyond the attested data")
+	}
+	if match, err := loaded.VerifyBufferRange(bytes.NewReader(data[3*BufferCapacity:]), 3*BufferCapacity, len(data)+10); err != nil || !match {
+		t.Errorf("Expected a range ending within the final block to match, got %v, %v", match, err)
+	}
+	for _, end := range []int{4*BufferCapacity + 1, 10 * BufferCapacity} {
+		if _, err := loaded.VerifyBufferRange(bytes.NewReader(data), 0, end); err == nil {
+			t.Errorf("Expected an error for a range ending at %d, beyond the attested chunks", end)
+		}
+	}
+
+	// Data ending on a chunk boundary within the range is truncated, not a match
+	var truncated *TruncatedDataError
+	match, err := terrapin.VerifyBufferRange(bytes.NewReader(data[BufferCapacity:2*BufferCapacity]), BufferCapacity, 3*BufferCapacity)
+	if !errors.As(err, &truncated) || truncated.Chunk != 2 || truncated.Length != 0 || match {
+		t.Errorf("Expected a TruncatedDataError for chunk 2, got %v, %v", match, err)
+	}
+}
+
 func TestVerifyBufferRange_InvalidRange(t *testing.T) {
 	data := make([]byte, 4*BufferCapacity)
 	for i := range data {
@@ -113,6 +542,25 @@ func TestVerifyBufferRange_InvalidRange(t *testing.T) {
 	}
 }
 
+func TestVerifyBufferRange_PastEnd(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// A reader positioned past the end of the data yields nothing to verify
+	startOffset := 3 * BufferCapacity
+	endOffset := 4 * BufferCapacity
+	match, err := terrapin.VerifyBufferRange(bytes.NewReader(nil), startOffset, endOffset)
+	if err == nil {
+		t.Fatalf("VerifyBufferRange expected to return an error for a range past the end, but it didn't")
+	}
+	if match {
+		t.Fatalf("VerifyBufferRange expected to mismatch, but it matched")
+	}
+}
+
 func TestVerifyBuffer_BeforeFinalization(t *testing.T) {
 	terrapin := NewTerrapin()
 	data := make([]byte, 4*BufferCapacity)
@@ -142,3 +590,1078 @@ func TestVerifyBufferRange_BeforeFinalization(t *testing.T) {
 		t.Fatalf("VerifyBufferRange expected to return an error and not match before finalization, but it didn't")
 	}
 }
+
+func TestVerifyBufferPrefix_TruncatedAttestations(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	original, _ := setupTerrapinWithData(t, data)
+	_, attestations, err := original.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// Keep only the first two chunk hashes, as if the rest of the blob were lost
+	terrapin, err := NewTerrapinWithAttestations(attestations[:2*sha256.Size])
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with attestations: %v", err)
+	}
+	if prefix := terrapin.VerifiablePrefix(); prefix != 2*BufferCapacity {
+		t.Fatalf("Expected verifiable prefix %d, got %d", 2*BufferCapacity, prefix)
+	}
+
+	match, err := terrapin.VerifyBufferPrefix(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBufferPrefix returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBufferPrefix expected to match, but it didn't")
+	}
+
+	// The full data extends past the attestations and must not verify
+	match, err = terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if match {
+		t.Fatalf("VerifyBuffer expected to mismatch, but it matched")
+	}
+
+	// Corruption outside the prefix is not covered, corruption inside it is detected
+	data[3*BufferCapacity] ^= 0xff
+	if match, err = terrapin.VerifyBufferPrefix(bytes.NewReader(data)); err != nil || !match {
+		t.Fatalf("VerifyBufferPrefix expected to match despite corruption past the prefix, got %v, %v", match, err)
+	}
+	data[BufferCapacity+1] ^= 0xff
+	if match, err = terrapin.VerifyBufferPrefix(bytes.NewReader(data)); err != nil || match {
+		t.Fatalf("VerifyBufferPrefix expected to mismatch on corruption inside the prefix, got %v, %v", match, err)
+	}
+}
+
+func TestVerifyAllMismatches(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+
+	mismatches, err := terrapin.VerifyAllMismatches(reader)
+	if err != nil {
+		t.Fatalf("VerifyAllMismatches returned an error: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("Expected no mismatches, got %v", mismatches)
+	}
+
+	// Corrupt two chunks and append data beyond the attested chunks
+	data[5] ^= 0xff
+	data[2*BufferCapacity+5] ^= 0xff
+	mismatches, err = terrapin.VerifyAllMismatches(bytes.NewReader(append(bytes.Clone(data), 1)))
+	if err != nil {
+		t.Fatalf("VerifyAllMismatches returned an error: %v", err)
+	}
+	expected := []int{0, 2, 4}
+	if len(mismatches) != len(expected) {
+		t.Fatalf("Expected mismatches %v, got %v", expected, mismatches)
+	}
+	for i := range expected {
+		if mismatches[i] != expected[i] {
+			t.Fatalf("Expected mismatches %v, got %v", expected, mismatches)
+		}
+	}
+
+	// Data ending before the last chunk, on a chunk boundary or within a chunk, is truncated
+	var truncated *TruncatedDataError
+	for length, chunk := range map[int]int{3 * BufferCapacity: 3, BufferCapacity + 7: 1} {
+		_, err = terrapin.VerifyAllMismatches(bytes.NewReader(data[:length]))
+		if !errors.As(err, &truncated) || truncated.Chunk != chunk {
+			t.Errorf("%d bytes: expected truncation at chunk %d, got %v", length, chunk, err)
+		}
+	}
+}
+
+func TestVerifyAllMismatchesDetailed(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+	_, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+
+	// Corrupt the second chunk
+	data[BufferCapacity+5] ^= 0xff
+	mismatches, err := terrapin.VerifyAllMismatchesDetailed(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyAllMismatchesDetailed returned an error: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Index != 1 {
+		t.Fatalf("Expected chunk 1 to mismatch, got %v", mismatches)
+	}
+
+	expected := "gitoid:blob:sha256:" + fmt.Sprintf("%x", attestations[sha256.Size:])
+	if mismatches[0].ExpectedURI != expected {
+		t.Errorf("Expected attested URI %s, got %s", expected, mismatches[0].ExpectedURI)
+	}
+	computed, err := gitoid.New(bytes.NewReader(data[BufferCapacity:]), gitoid.WithSha256(), gitoid.WithContentLength(BufferCapacity))
+	if err != nil {
+		t.Fatalf("gitoid.New returned an error: %v", err)
+	}
+	if mismatches[0].ComputedURI != computed.URI() {
+		t.Errorf("Expected computed URI %s, got %s", computed.URI(), mismatches[0].ComputedURI)
+	}
+	if mismatches[0].ComputedURI == mismatches[0].ExpectedURI {
+		t.Errorf("Expected computed and attested URIs to differ")
+	}
+
+	// Truncated data is reported as such rather than as mismatched chunks
+	var truncated *TruncatedDataError
+	for length, chunk := range map[int]int{0: 0, BufferCapacity: 1, BufferCapacity / 2: 0} {
+		_, err = terrapin.VerifyAllMismatchesDetailed(bytes.NewReader(data[:length]))
+		if !errors.As(err, &truncated) || truncated.Chunk != chunk {
+			t.Errorf("%d bytes: expected truncation at chunk %d, got %v", length, chunk, err)
+		}
+	}
+}
+
+func TestVerifyChunk(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	unfinalized := NewTerrapin()
+	if _, err := unfinalized.VerifyChunk(0, data[:BufferCapacity]); err == nil {
+		t.Error("Expected error verifying a chunk before finalization, got nil")
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// Each chunk verifies on its own, in any order
+	for _, index := range []int{2, 0, 1} {
+		chunk := data[index*BufferCapacity : min((index+1)*BufferCapacity, len(data))]
+		valid, err := terrapin.VerifyChunk(index, chunk)
+		if err != nil || !valid {
+			t.Errorf("Expected chunk %d to verify, got %v, %v", index, valid, err)
+		}
+	}
+
+	// A chunk does not verify at another index or once altered
+	if valid, err := terrapin.VerifyChunk(1, data[:BufferCapacity]); err != nil || valid {
+		t.Errorf("Expected chunk 0 not to verify as chunk 1, got %v, %v", valid, err)
+	}
+	corrupted := bytes.Clone(data[BufferCapacity : 2*BufferCapacity])
+	corrupted[7] ^= 0xff
+	if valid, err := terrapin.VerifyChunk(1, corrupted); err != nil || valid {
+		t.Errorf("Expected corrupted chunk not to verify, got %v, %v", valid, err)
+	}
+
+	for _, index := range []int{-1, 3} {
+		if _, err := terrapin.VerifyChunk(index, data[:10]); err == nil {
+			t.Errorf("Expected error for chunk index %d, got nil", index)
+		}
+	}
+}
+
+func TestVerifyGitoid(t *testing.T) {
+	data := make([]byte, 3*1024+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	corrupt := bytes.Clone(data)
+	corrupt[2*1024+5] ^= 0xff
+
+	for name, opts := range map[string][]Option{
+		"default": nil,
+		"sha1":    {WithBlockSize(1024), WithHashAlgorithm(SHA1)},
+		"merkle":  {WithBlockSize(1024), WithMerkle()},
+		"raw":     {WithBlockSize(1024), WithRawChunkHashes()},
+	} {
+		attestor, err := NewTerrapinWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithOptions returned an error: %v", name, err)
+		}
+		if err := attestor.Add(data); err != nil {
+			t.Fatalf("%s: Failed to add data: %v", name, err)
+		}
+		_, attestations, err := attestor.Finalize()
+		if err != nil {
+			t.Fatalf("%s: Failed to finalize terrapin: %v", name, err)
+		}
+
+		// The settings recorded in the attestations are used to attest the data again
+		terrapin, err := NewTerrapinWithAttestations(attestations)
+		if err != nil {
+			t.Fatalf("%s: NewTerrapinWithAttestations returned an error: %v", name, err)
+		}
+		if match, err := terrapin.VerifyGitoid(bytes.NewReader(data)); err != nil || !match {
+			t.Errorf("%s: expected the data to reproduce the gitoid, got %v, %v", name, match, err)
+		}
+		if match, err := terrapin.VerifyGitoid(bytes.NewReader(corrupt)); err != nil || match {
+			t.Errorf("%s: expected corrupt data not to reproduce the gitoid, got %v, %v", name, match, err)
+		}
+	}
+
+	if _, err := NewTerrapin().VerifyGitoid(bytes.NewReader(data)); err == nil {
+		t.Error("Expected an error for an unfinalized instance")
+	}
+	variable, err := NewTerrapinWithOptions(WithVariableChunks())
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := variable.AddChunk(data); err != nil {
+		t.Fatalf("AddChunk returned an error: %v", err)
+	}
+	if _, _, err := variable.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	if _, err := variable.VerifyGitoid(bytes.NewReader(data)); !errors.Is(err, errVariableChunks) {
+		t.Errorf("Expected errVariableChunks, got %v", err)
+	}
+}
+
+func TestVerifyCompressed(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+	compress := func(data []byte) []byte {
+		t.Helper()
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := writer.Write(data); err != nil {
+			t.Fatalf("Failed to compress data: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to compress data: %v", err)
+		}
+		return compressed.Bytes()
+	}
+
+	compressed := compress(data)
+	if match, err := terrapin.VerifyCompressed(bytes.NewReader(compressed)); err != nil || !match {
+		t.Errorf("Expected the decompressed data to verify, got %v, %v", match, err)
+	}
+	corrupt := bytes.Clone(data)
+	corrupt[BufferCapacity+3] ^= 0xff
+	if match, err := terrapin.VerifyCompressed(bytes.NewReader(compress(corrupt))); err != nil || match {
+		t.Errorf("Expected changed data to mismatch, got %v, %v", match, err)
+	}
+
+	// Damaged compressed data fails to decompress rather than mismatching
+	var decompressionErr *DecompressionError
+	if _, err := terrapin.VerifyCompressed(bytes.NewReader(data)); !errors.As(err, &decompressionErr) {
+		t.Errorf("Expected a DecompressionError for uncompressed data, got %v", err)
+	}
+	_, err := terrapin.VerifyCompressed(bytes.NewReader(compressed[:len(compressed)-4]))
+	if !errors.As(err, &decompressionErr) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a DecompressionError for truncated compressed data, got %v", err)
+	}
+	damaged := bytes.Clone(compressed)
+	damaged[len(damaged)-5] ^= 0xff // Within the CRC-32 of the trailer
+	if _, err := terrapin.VerifyCompressed(bytes.NewReader(damaged)); !errors.Is(err, gzip.ErrChecksum) {
+		t.Errorf("Expected a checksum error, got %v", err)
+	}
+}
+
+func TestVerifyFileGitoid(t *testing.T) {
+	data := make([]byte, BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	sha256Gitoid, err := gitoid.New(bytes.NewReader(data), gitoid.WithSha256())
+	if err != nil {
+		t.Fatalf("Failed to compute gitoid: %v", err)
+	}
+	sha1Gitoid, err := gitoid.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to compute gitoid: %v", err)
+	}
+
+	for _, uri := range []string{sha256Gitoid.URI(), sha1Gitoid.URI()} {
+		// Exercise both the known-length and the unknown-length paths
+		for _, reader := range []io.Reader{bytes.NewReader(data), io.MultiReader(bytes.NewReader(data))} {
+			match, err := VerifyFileGitoid(reader, uri)
+			if err != nil {
+				t.Fatalf("VerifyFileGitoid returned an error: %v", err)
+			}
+			if !match {
+				t.Fatalf("VerifyFileGitoid expected to match %s, but it didn't", uri)
+			}
+		}
+	}
+
+	data[7] ^= 0xff
+	match, err := VerifyFileGitoid(bytes.NewReader(data), sha256Gitoid.URI())
+	if err != nil {
+		t.Fatalf("VerifyFileGitoid returned an error: %v", err)
+	}
+	if match {
+		t.Fatalf("VerifyFileGitoid expected to mismatch, but it matched")
+	}
+
+	if _, err := VerifyFileGitoid(bytes.NewReader(data), "not-a-gitoid"); err == nil {
+		t.Fatalf("VerifyFileGitoid expected to return an error for an invalid URI, but it didn't")
+	}
+}
+
+func TestWithBlockSize_RejectsTinyBlocks(t *testing.T) {
+	for _, size := range []int{-1, 0, 1, MinBlockSize - 1} {
+		if _, err := NewTerrapinWithOptions(WithBlockSize(size)); err == nil {
+			t.Errorf("Expected block size %d to be rejected", size)
+		}
+	}
+	if _, err := NewTerrapinWithOptions(WithBlockSize(MinBlockSize)); err != nil {
+		t.Errorf("Expected block size %d to be accepted, got %v", MinBlockSize, err)
+	}
+}
+
+func TestVerifyBuffer_SmallBlockSize(t *testing.T) {
+	const blockSize = 1024
+	data := make([]byte, 10*blockSize+300)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(blockSize))
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	// The non-default block size is recorded in a header ahead of the chunk hashes
+	if !bytes.HasPrefix(attestations, attestationMagic) || len(attestations) <= 11*sha256.Size {
+		t.Fatalf("Expected a header followed by %d chunk hashes, got %d bytes", 11, len(attestations))
+	}
+	hashes := attestations[len(attestations)-11*sha256.Size:]
+
+	// Each chunk hash must be the gitoid of its 1KB slice
+	for i := 0; i < 11; i++ {
+		chunk := data[i*blockSize : min((i+1)*blockSize, len(data))]
+		expected, err := gitoid.New(bytes.NewReader(chunk), gitoid.WithSha256())
+		if err != nil {
+			t.Fatalf("Failed to compute gitoid: %v", err)
+		}
+		if !bytes.Equal(hashes[i*sha256.Size:(i+1)*sha256.Size], expected.Bytes()) {
+			t.Fatalf("Chunk %d hash mismatch", i)
+		}
+	}
+
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with attestations: %v", err)
+	}
+	match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil || !match {
+		t.Fatalf("VerifyBuffer expected to match, got %v, %v", match, err)
+	}
+	match, err = terrapin.VerifyBufferRange(bytes.NewReader(data[3*blockSize:5*blockSize]), 3*blockSize, 5*blockSize)
+	if err != nil || !match {
+		t.Fatalf("VerifyBufferRange expected to match, got %v, %v", match, err)
+	}
+
+	data[7*blockSize+1] ^= 0xff
+	mismatches, err := terrapin.VerifyAllMismatches(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyAllMismatches returned an error: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0] != 7 {
+		t.Fatalf("Expected chunk 7 to mismatch, got %v", mismatches)
+	}
+}
+
+func TestVerifyBufferURIs(t *testing.T) {
+	data := make([]byte, 2*BufferCapacity+100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+
+	match, uris, err := terrapin.VerifyBufferURIs(reader)
+	if err != nil {
+		t.Fatalf("VerifyBufferURIs returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBufferURIs expected to match, but it didn't")
+	}
+	if len(uris) != 3 {
+		t.Fatalf("Expected 3 URIs, got %d", len(uris))
+	}
+	for i, uri := range uris {
+		chunk := data[i*BufferCapacity : min((i+1)*BufferCapacity, len(data))]
+		expected, err := gitoid.New(bytes.NewReader(chunk), gitoid.WithSha256())
+		if err != nil {
+			t.Fatalf("Failed to compute gitoid: %v", err)
+		}
+		if uri != expected.URI() {
+			t.Errorf("Chunk %d: expected URI %s, got %s", i, expected.URI(), uri)
+		}
+	}
+
+	// Only the chunks preceding a mismatch are reported
+	data[BufferCapacity+1] ^= 0xff
+	match, uris, err = terrapin.VerifyBufferURIs(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBufferURIs returned an error: %v", err)
+	}
+	if match || len(uris) != 1 {
+		t.Fatalf("Expected a mismatch after 1 verified chunk, got %v with %d URIs", match, len(uris))
+	}
+
+	// Truncated data fails with the URIs of the chunks read before it ended
+	var truncated *TruncatedDataError
+	match, uris, err = terrapin.VerifyBufferURIs(bytes.NewReader(data[:BufferCapacity]))
+	if match || !errors.As(err, &truncated) || truncated.Chunk != 1 || len(uris) != 1 {
+		t.Errorf("Expected truncation at chunk 1 after 1 URI, got %v, %v with %d URIs", match, err, len(uris))
+	}
+	match, uris, err = terrapin.VerifyBufferURIs(bytes.NewReader(data[:BufferCapacity+7]))
+	if match || !errors.As(err, &truncated) || truncated.Chunk != 1 || truncated.Length != 7 || len(uris) != 1 {
+		t.Errorf("Expected truncation within chunk 1 after 1 URI, got %v, %v with %d URIs", match, err, len(uris))
+	}
+}
+
+func TestVerifyConcurrentFinalized(t *testing.T) {
+	data := make([]byte, 2*1024+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	attestor, err := NewTerrapinWithOptions(WithBlockSize(1024))
+	if err != nil {
+		t.Fatalf("Failed to create terrapin: %v", err)
+	}
+	if err := attestor.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	_, attestations, err := attestor.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	terrapin, err := NewTerrapinWithAttestations(attestations)
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with attestations: %v", err)
+	}
+
+	// Run with -race to detect unsynchronized access to the shared instance
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var match bool
+			var err error
+			if i%2 == 0 {
+				match, err = terrapin.VerifyBuffer(bytes.NewReader(data))
+			} else {
+				match, err = terrapin.VerifyReaderAt(bytes.NewReader(data), i%3)
+			}
+			if err != nil || !match {
+				errs <- fmt.Errorf("goroutine %d: expected match, got %v, %v", i, match, err)
+			}
+			if _, _, err := terrapin.Finalize(); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestWithReadRateLimit(t *testing.T) {
+	if _, err := NewTerrapinWithOptions(WithReadRateLimit(0)); err == nil {
+		t.Fatalf("Expected an error for a zero rate limit")
+	}
+
+	const rateLimit = 16 * 1024
+	data := make([]byte, 2*rateLimit)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, err := NewTerrapinWithOptions(WithBlockSize(1024), WithReadRateLimit(rateLimit))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+	}
+	if err := terrapin.Add(data); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if _, _, err := terrapin.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	// The first second worth of bytes is served from the full bucket, the rest at the limited rate
+	start := time.Now()
+	match, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+	minimum := time.Duration(len(data)-rateLimit) * time.Second / rateLimit
+	if elapsed < minimum*9/10 {
+		t.Errorf("Expected verification to take at least %v, took %v", minimum, elapsed)
+	}
+
+	// Cancelling the context ends a verification waiting for the rate limit
+	throttled, err := NewTerrapinWithAttestations(terrapin.attestations, WithBlockSize(1024), WithReadRateLimit(1024))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	verifications := map[string]func(ctx context.Context) (bool, error){
+		"VerifyBufferContext": func(ctx context.Context) (bool, error) {
+			return throttled.VerifyBufferContext(ctx, bytes.NewReader(data))
+		},
+		"VerifyBufferRangeContext": func(ctx context.Context) (bool, error) {
+			return throttled.VerifyBufferRangeContext(ctx, bytes.NewReader(data), 0, len(data))
+		},
+	}
+	for name, verify := range verifications {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		if _, err := verify(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: expected cancellation to end the wait, took %v", name, elapsed)
+		}
+		cancel()
+	}
+}
+
+func TestMatchChunk(t *testing.T) {
+	attest := func(data []byte, opts ...Option) []byte {
+		t.Helper()
+		terrapin, err := NewTerrapinWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+		}
+		if err := terrapin.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		_, attestations, err := terrapin.Finalize()
+		if err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		return attestations
+	}
+
+	data := make([]byte, 3*1024)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	other := bytes.Repeat([]byte{7}, 3*1024)
+	chunk := data[1024:2048]
+
+	candidates := [][]byte{
+		[]byte("not attestations"),
+		attest(other, WithBlockSize(1024)),
+		attest(data, WithBlockSize(1024), WithHashAlgorithm(SHA512)),
+		attest(data, WithBlockSize(1024)),
+	}
+	index, ok := MatchChunk(chunk, candidates)
+	if !ok || index != 2 {
+		t.Errorf("Expected candidate 2 to match, got %d, %v", index, ok)
+	}
+	index, ok = MatchChunk(chunk, candidates[:2])
+	if ok || index != -1 {
+		t.Errorf("Expected no candidate to match, got %d, %v", index, ok)
+	}
+}
+
+func TestVerifyBufferStats(t *testing.T) {
+	// A chunk cycling through every byte value, a zero chunk, and a short chunk of a single value
+	data := make([]byte, 2*BufferCapacity+100)
+	for i := 0; i < BufferCapacity; i++ {
+		data[i] = byte(i % 256)
+	}
+	for i := 2 * BufferCapacity; i < len(data); i++ {
+		data[i] = 7
+	}
+	terrapin, reader := setupTerrapinWithData(t, data)
+
+	match, stats, err := terrapin.VerifyBufferStats(reader)
+	if err != nil {
+		t.Fatalf("VerifyBufferStats returned an error: %v", err)
+	}
+	if !match {
+		t.Fatalf("VerifyBufferStats expected to match, but it didn't")
+	}
+	expected := []ChunkStats{
+		{Min: 0, Max: 255, Mean: 127.5, Entropy: 8},
+		{Min: 0, Max: 0, Mean: 0, Entropy: 0},
+		{Min: 7, Max: 7, Mean: 7, Entropy: 0},
+	}
+	if len(stats.Chunks) != len(expected) {
+		t.Fatalf("Expected stats for %d chunks, got %d", len(expected), len(stats.Chunks))
+	}
+	for i := range expected {
+		if stats.Chunks[i] != expected[i] {
+			t.Errorf("Chunk %d: expected %+v, got %+v", i, expected[i], stats.Chunks[i])
+		}
+	}
+	if stats.ZeroChunks != 1 {
+		t.Errorf("Expected 1 zero chunk, got %d", stats.ZeroChunks)
+	}
+
+	// Verification follows VerifyBuffer, reporting progress and truncation
+	_, attestations, _ := terrapin.Finalize()
+	var progress int64
+	verifier, err := NewTerrapinWithAttestations(attestations, WithProgress(func(n int64) { progress = n }))
+	if err != nil {
+		t.Fatalf("Failed to create terrapin with attestations: %v", err)
+	}
+	if match, _, err := verifier.VerifyBufferStats(bytes.NewReader(data)); err != nil || !match || progress != int64(len(data)) {
+		t.Errorf("Expected a match with progress %d, got %v, %v, progress %d", len(data), match, err, progress)
+	}
+	var truncated *TruncatedDataError
+	if _, _, err := verifier.VerifyBufferStats(bytes.NewReader(data[:BufferCapacity])); !errors.As(err, &truncated) {
+		t.Errorf("Expected a TruncatedDataError for data ending on a chunk boundary, got %v", err)
+	}
+	if _, _, err := verifier.VerifyBufferStats(bytes.NewReader(data[:BufferCapacity+1])); !errors.As(err, &truncated) {
+		t.Errorf("Expected a TruncatedDataError for a short chunk before the last, got %v", err)
+	}
+	match, stats, err = verifier.VerifyBufferStats(bytes.NewReader(append(bytes.Clone(data), 1)))
+	if err != nil || match || len(stats.Chunks) != 2 {
+		t.Errorf("Expected trailing data to mismatch the short last chunk, got %v, %+v, %v", match, stats, err)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	changed := append([]byte(nil), data...)
+	changed[BufferCapacity+3] ^= 0xff
+
+	attest := func(data []byte, opts ...Option) []byte {
+		t.Helper()
+		terrapin, err := NewTerrapinWithOptions(opts...)
+		if err != nil {
+			t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+		}
+		if err := terrapin.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		_, attestations, err := terrapin.Finalize()
+		if err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		return attestations
+	}
+
+	for name, tc := range map[string]struct {
+		a, b     []byte
+		expected bool
+	}{
+		"identical":           {attest(data), attest(data), true},
+		"one chunk different": {attest(data), attest(changed), false},
+		"truncated":           {attest(data), attest(data[:BufferCapacity]), false},
+		"root type differs":   {attest(data), attest(data, WithRootType("terrapin")), true},
+	} {
+		same, err := SameContent(tc.a, tc.b)
+		if err != nil {
+			t.Fatalf("%s: SameContent returned an error: %v", name, err)
+		}
+		if same != tc.expected {
+			t.Errorf("%s: Expected %v, got %v", name, tc.expected, same)
+		}
+	}
+
+	if _, err := SameContent(attest(data), attest(data, WithHashAlgorithm(SHA512))); err == nil {
+		t.Error("Expected attestations with different algorithms to be rejected")
+	}
+}
+
+// failingReaderAt returns an error for reads starting at failOffset
+type failingReaderAt struct {
+	data       []byte
+	failOffset int64
+}
+
+func (f *failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off == f.failOffset {
+		return 0, errors.New("read error")
+	}
+	return bytes.NewReader(f.data).ReadAt(p, off)
+}
+
+func TestDiff(t *testing.T) {
+	data := make([]byte, 4*1024+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	attest := func(data []byte, opts ...Option) *Terrapin {
+		t.Helper()
+		terrapin, err := NewTerrapinWithOptions(append([]Option{WithBlockSize(1024)}, opts...)...)
+		if err != nil {
+			t.Fatalf("NewTerrapinWithOptions returned an error: %v", err)
+		}
+		if err := terrapin.Add(data); err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		if _, _, err := terrapin.Finalize(); err != nil {
+			t.Fatalf("Failed to finalize terrapin: %v", err)
+		}
+		return terrapin
+	}
+	changed := bytes.Clone(data)
+	changed[1024+3] ^= 0xff
+	changed[3*1024] ^= 0xff
+
+	original := attest(data)
+	for name, test := range map[string]struct {
+		other    *Terrapin
+		expected []int
+	}{
+		"identical": {attest(data), nil},
+		"changed":   {attest(changed), []int{1, 3}},
+		"shorter":   {attest(data[:2*1024]), []int{2, 3, 4}},
+		"longer":    {attest(append(bytes.Clone(data), make([]byte, 2048)...)), []int{4, 5, 6}},
+	} {
+		diff, err := Diff(original, test.other)
+		if err != nil {
+			t.Errorf("%s: Diff returned an error: %v", name, err)
+		}
+		if !slices.Equal(diff, test.expected) {
+			t.Errorf("%s: expected changed chunks %v, got %v", name, test.expected, diff)
+		}
+	}
+
+	if _, err := Diff(original, attest(data, WithHashAlgorithm(SHA1))); err == nil {
+		t.Error("Expected an error comparing different hash algorithms")
+	}
+	if _, err := Diff(original, NewTerrapin()); err == nil {
+		t.Error("Expected an error comparing with an unfinalized instance")
+	}
+}
+
+func TestVerifyAllMismatchesAt(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	report, err := terrapin.VerifyAllMismatchesAt(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyAllMismatchesAt returned an error: %v", err)
+	}
+	if len(report.Mismatched) != 0 || len(report.Unreadable) != 0 {
+		t.Fatalf("Expected no failures, got %+v", report)
+	}
+
+	// Chunk 1 cannot be read and chunk 3 is corrupt
+	data[3*BufferCapacity+5] ^= 0xff
+	report, err = terrapin.VerifyAllMismatchesAt(&failingReaderAt{data: data, failOffset: BufferCapacity})
+	if err != nil {
+		t.Fatalf("VerifyAllMismatchesAt returned an error: %v", err)
+	}
+	if len(report.Unreadable) != 1 || report.Unreadable[0] != 1 {
+		t.Errorf("Expected chunk 1 to be unreadable, got %v", report.Unreadable)
+	}
+	if len(report.Mismatched) != 1 || report.Mismatched[0] != 3 {
+		t.Errorf("Expected chunk 3 to mismatch, got %v", report.Mismatched)
+	}
+
+	// Extending the data changes the short final chunk and adds a mismatch past the last chunk
+	report, err = terrapin.VerifyAllMismatchesAt(bytes.NewReader(append(data, make([]byte, BufferCapacity)...)))
+	if err != nil {
+		t.Fatalf("VerifyAllMismatchesAt returned an error: %v", err)
+	}
+	if len(report.Mismatched) != 3 || report.Mismatched[1] != 4 || report.Mismatched[2] != 5 {
+		t.Errorf("Expected chunks 3, 4 and 5 to mismatch, got %v", report.Mismatched)
+	}
+}
+
+// countingReaderAt counts the reads starting at each offset
+type countingReaderAt struct {
+	data  []byte
+	reads map[int64]int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads[off]++
+	return bytes.NewReader(c.data).ReadAt(p, off)
+}
+
+func TestVerifyRanges(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// Heavily overlapping ranges clustered over chunks 1 and 2, plus one in the short final chunk
+	var ranges []ByteRange
+	for i := int64(0); i < 20; i++ {
+		ranges = append(ranges, ByteRange{Offset: BufferCapacity + i*1000, Length: BufferCapacity})
+	}
+	ranges = append(ranges, ByteRange{Offset: 4 * BufferCapacity, Length: 10})
+
+	reader := &countingReaderAt{data: data, reads: map[int64]int{}}
+	valid, err := terrapin.VerifyRanges(reader, ranges)
+	if err != nil {
+		t.Fatalf("VerifyRanges returned an error: %v", err)
+	}
+	if !valid {
+		t.Fatalf("VerifyRanges expected to match, but it didn't")
+	}
+	for _, offset := range []int64{BufferCapacity, 2 * BufferCapacity, 4 * BufferCapacity} {
+		if reader.reads[offset] != 1 {
+			t.Errorf("Expected the chunk at offset %d to be read once, got %d", offset, reader.reads[offset])
+		}
+	}
+	if len(reader.reads) != 3 {
+		t.Errorf("Expected only the 3 covering chunks to be read, got %v", reader.reads)
+	}
+
+	data[2*BufferCapacity+1] ^= 0xff
+	valid, err = terrapin.VerifyRanges(bytes.NewReader(data), ranges)
+	if err != nil {
+		t.Fatalf("VerifyRanges returned an error: %v", err)
+	}
+	if valid {
+		t.Fatalf("VerifyRanges expected to mismatch, but it matched")
+	}
+
+	for _, rng := range []ByteRange{
+		{Offset: 5 * BufferCapacity, Length: 10},
+		{Offset: 4 * BufferCapacity, Length: 20}, // Within the final chunk but past the end of the data
+		{Offset: 1, Length: math.MaxInt64},       // The end offset overflows
+	} {
+		if _, err := terrapin.VerifyRanges(bytes.NewReader(data), []ByteRange{rng}); err == nil {
+			t.Errorf("Expected range %+v past the attested data to be rejected", rng)
+		}
+	}
+}
+
+// lengthPrefixedReader yields the payloads of frames each holding a 4-byte big-endian length and that many bytes
+type lengthPrefixedReader struct {
+	framed    io.Reader
+	remaining uint32 // Bytes left in the current frame's payload
+}
+
+func (l *lengthPrefixedReader) Read(p []byte) (int, error) {
+	for l.remaining == 0 {
+		var prefix [4]byte
+		if _, err := io.ReadFull(l.framed, prefix[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return 0, errors.New("truncated frame length")
+			}
+			return 0, err
+		}
+		l.remaining = binary.BigEndian.Uint32(prefix[:])
+	}
+	n, err := l.framed.Read(p[:min(len(p), int(l.remaining))])
+	l.remaining -= uint32(n)
+	if err == io.EOF && l.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func TestVerifyBufferWithDeframer(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// Frame the data in messages that do not align with chunks
+	var framed []byte
+	for offset, size := 0, 1000; offset < len(data); offset, size = offset+size, size*3+7 {
+		payload := data[offset:min(offset+size, len(data))]
+		framed = binary.BigEndian.AppendUint32(framed, uint32(len(payload)))
+		framed = append(framed, payload...)
+	}
+
+	valid, err := terrapin.VerifyBuffer(bytes.NewReader(framed))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if valid {
+		t.Fatalf("VerifyBuffer expected framed data to mismatch without a deframer, but it matched")
+	}
+
+	_, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+	deframing, err := NewTerrapinWithAttestations(attestations, WithDeframer(func(r io.Reader) io.Reader {
+		return &lengthPrefixedReader{framed: r}
+	}))
+	if err != nil {
+		t.Fatalf("NewTerrapinWithAttestations returned an error: %v", err)
+	}
+	valid, err = deframing.VerifyBuffer(bytes.NewReader(framed))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !valid {
+		t.Fatalf("VerifyBuffer expected deframed data to match, but it didn't")
+	}
+	valid, err = deframing.VerifyBufferPrefix(bytes.NewReader(framed))
+	if err != nil || !valid {
+		t.Errorf("Expected deframed prefix to verify, got %v, %v", valid, err)
+	}
+
+	// Corrupting a payload byte is detected through the framing
+	framed[len(framed)-1] ^= 0xff
+	valid, err = deframing.VerifyBuffer(bytes.NewReader(framed))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if valid {
+		t.Fatalf("VerifyBuffer expected corrupted data to mismatch, but it matched")
+	}
+}
+
+func TestVerifyPrefix(t *testing.T) {
+	data := make([]byte, 4*BufferCapacity)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	for name, tc := range map[string]struct {
+		data       []byte
+		matched    int
+		consistent bool
+	}{
+		"empty":           {data: nil, matched: 0, consistent: true},
+		"2.5 chunks":      {data: data[:5*BufferCapacity/2], matched: 2, consistent: true},
+		"whole":           {data: data, matched: 4, consistent: true},
+		"extra data":      {data: append(append([]byte(nil), data...), 1), matched: 4, consistent: false},
+		"corrupt chunk":   {data: append(append([]byte(nil), data[:BufferCapacity]...), make([]byte, BufferCapacity)...), matched: 1, consistent: false},
+		"partial chunk 0": {data: data[:10], matched: 0, consistent: true},
+	} {
+		matched, consistent, err := terrapin.VerifyPrefix(bytes.NewReader(tc.data))
+		if err != nil {
+			t.Fatalf("%s: VerifyPrefix returned an error: %v", name, err)
+		}
+		if matched != tc.matched || consistent != tc.consistent {
+			t.Errorf("%s: Expected %d chunks matched and consistent %v, got %d and %v", name, tc.matched, tc.consistent, matched, consistent)
+		}
+	}
+
+	// A short final chunk that is complete counts as matched
+	short, _ := setupTerrapinWithData(t, data[:3*BufferCapacity+10])
+	matched, consistent, err := short.VerifyPrefix(bytes.NewReader(data[:3*BufferCapacity+10]))
+	if err != nil {
+		t.Fatalf("VerifyPrefix returned an error: %v", err)
+	}
+	if matched != 4 || !consistent {
+		t.Errorf("Expected 4 chunks matched and consistent, got %d and %v", matched, consistent)
+	}
+}
+
+func TestVerifyBufferShortChunks(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// The short final chunk is legitimate
+	valid, err := terrapin.VerifyBuffer(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("VerifyBuffer returned an error: %v", err)
+	}
+	if !valid {
+		t.Fatalf("VerifyBuffer expected to match, but it didn't")
+	}
+
+	// Data ending within an earlier chunk is truncated
+	for name, verify := range map[string]func(io.Reader) (bool, error){
+		"serial":   terrapin.VerifyBuffer,
+		"parallel": func(r io.Reader) (bool, error) { return terrapin.VerifyBufferParallel(r, 4) },
+		"range": func(r io.Reader) (bool, error) {
+			return terrapin.VerifyBufferRange(r, 0, len(data))
+		},
+	} {
+		valid, err := verify(bytes.NewReader(data[:BufferCapacity+100]))
+		var truncated *TruncatedDataError
+		if !errors.As(err, &truncated) {
+			t.Fatalf("%s: Expected a TruncatedDataError, got %v", name, err)
+		}
+		if truncated.Chunk != 1 || truncated.Length != 100 {
+			t.Errorf("%s: Expected chunk 1 truncated after 100 bytes, got %+v", name, truncated)
+		}
+		if valid {
+			t.Errorf("%s: Expected truncated data to mismatch", name)
+		}
+	}
+}
+
+func TestVerifyReaderAtShortChunks(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+
+	// ReadAt returns io.EOF with the short final chunk, which is legitimate
+	valid, err := terrapin.VerifyReaderAt(bytes.NewReader(data), 3)
+	if err != nil || !valid {
+		t.Fatalf("Expected the final chunk to verify, got %v, %v", valid, err)
+	}
+
+	// A source ending within an earlier chunk is truncated, and a chunk beyond its end is missing
+	truncatedData := bytes.NewReader(data[:BufferCapacity+100])
+	valid, err = terrapin.VerifyReaderAt(truncatedData, 1)
+	var truncated *TruncatedDataError
+	if !errors.As(err, &truncated) || truncated.Chunk != 1 || truncated.Length != 100 || valid {
+		t.Errorf("Expected chunk 1 truncated after 100 bytes, got %v, %v", valid, err)
+	}
+	valid, err = terrapin.VerifyReaderAt(truncatedData, 2)
+	if err != nil || valid {
+		t.Errorf("Expected the missing chunk to mismatch, got %v, %v", valid, err)
+	}
+}
+
+func TestVerifyAttestationsRoot(t *testing.T) {
+	data := make([]byte, 3*BufferCapacity+10)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+	terrapin, _ := setupTerrapinWithData(t, data)
+	rootURI, attestations, err := terrapin.Finalize()
+	if err != nil {
+		t.Fatalf("Failed to finalize terrapin: %v", err)
+	}
+
+	valid, err := VerifyAttestationsRoot(attestations, rootURI)
+	if err != nil {
+		t.Fatalf("VerifyAttestationsRoot returned an error: %v", err)
+	}
+	if !valid {
+		t.Fatalf("VerifyAttestationsRoot expected to match, but it didn't")
+	}
+
+	// Flipping a bit of one chunk hash changes the root
+	attestations[sha256.Size+3] ^= 0x01
+	valid, err = VerifyAttestationsRoot(attestations, rootURI)
+	if err != nil {
+		t.Fatalf("VerifyAttestationsRoot returned an error: %v", err)
+	}
+	if valid {
+		t.Fatalf("VerifyAttestationsRoot expected tampered attestations to mismatch, but they matched")
+	}
+
+	if _, err := VerifyAttestationsRoot(attestations[:10], rootURI); err == nil {
+		t.Error("Expected malformed attestations to be rejected")
+	}
+}
@@ -0,0 +1,727 @@
+package terrapin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/edwarnicke/gitoid"
+	"io"
+	"slices"
+	"strings"
+)
+
+// VerifyAllMismatches verifies the entire data stream from the reader against the attestations
+// Unlike VerifyBuffer it does not stop at the first mismatch, and returns the index of every chunk that failed
+// Data beyond the attested chunks is reported as a mismatch too, while data ending before the last attested
+// chunk is reported with a *TruncatedDataError
+// An empty result means the data matches the attestations
+func (t *Terrapin) VerifyAllMismatches(reader io.Reader) ([]int, error) {
+	details, err := t.VerifyAllMismatchesDetailed(reader)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []int
+	for _, mismatch := range details {
+		mismatches = append(mismatches, mismatch.Index)
+	}
+	return mismatches, nil
+}
+
+// ChunkMismatch describes a chunk that failed verification
+type ChunkMismatch struct {
+	Index       int    // Index of the chunk
+	ExpectedURI string // Gitoid URI of the attested chunk, empty for data beyond the attested chunks
+	ComputedURI string // Gitoid URI of the chunk actually read
+
+	// Both URIs are empty for raw chunk hashes, which are not gitoids
+}
+
+// VerifyAllMismatchesDetailed is like VerifyAllMismatches, but also returns the expected and computed gitoid
+// URIs of each failing chunk, so the data actually read can be compared against other attestation sets
+func (t *Terrapin) VerifyAllMismatchesDetailed(reader io.Reader) ([]ChunkMismatch, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	if t.variable {
+		return nil, errVariableChunks
+	}
+
+	// Buffer to read data in chunks, deframed and throttled by any read rate limit
+	reader = t.limitReader(context.Background(), t.deframe(reader))
+	buffer := make([]byte, t.blockSize)
+	count := len(t.attestations) / t.digestSize()
+	var mismatches []ChunkMismatch
+
+	index := 0
+	for ; ; index++ {
+		n, err := io.ReadFull(reader, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+		if index < count {
+			if err := t.checkShortChunk(index, n); err != nil {
+				return nil, err
+			}
+		}
+
+		computedHash, err := t.hashChunk(buffer[:n])
+		if err != nil {
+			return nil, err
+		}
+		if index < count {
+			expectedHash := t.attestations[index*t.digestSize() : (index+1)*t.digestSize()]
+			if !bytes.Equal(computedHash, expectedHash) {
+				mismatches = append(mismatches, ChunkMismatch{
+					Index:       index,
+					ExpectedURI: t.chunkURI(expectedHash),
+					ComputedURI: t.chunkURI(computedHash),
+				})
+			}
+		} else {
+			// More data than attested
+			mismatches = append(mismatches, ChunkMismatch{
+				Index:       index,
+				ComputedURI: t.chunkURI(computedHash),
+			})
+		}
+
+		if n < t.blockSize {
+			index++
+			break
+		}
+	}
+
+	if index < count {
+		return nil, &TruncatedDataError{Chunk: index} // The data must cover every attested chunk
+	}
+
+	return mismatches, nil
+}
+
+// MismatchReport lists the chunks that failed verification, separating those that could not be read
+type MismatchReport struct {
+	Mismatched []int // Indexes of chunks whose data does not match, including missing chunks and extra data
+	Unreadable []int // Indexes of chunks that could not be read
+}
+
+// VerifyAllMismatchesAt verifies every attested chunk read from r, like VerifyAllMismatches, but a read error
+// on one chunk is recorded as unreadable and the remaining chunks are still checked, so a scrub over failing
+// media reports every bad chunk in one pass
+// Data beyond the attested chunks is reported as a mismatch at index NumChunks
+func (t *Terrapin) VerifyAllMismatchesAt(r io.ReaderAt) (*MismatchReport, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+
+	r = t.limitReaderAt(context.Background(), r)
+	report := &MismatchReport{}
+	count := len(t.attestations) / t.digestSize()
+	for index := 0; index < count; index++ {
+		// The final chunk may be short, in which case ReadAt reports io.EOF
+		buffer := make([]byte, t.chunkLength(index))
+		n, err := r.ReadAt(buffer, t.chunkOffset(index))
+		if err != nil && err != io.EOF {
+			report.Unreadable = append(report.Unreadable, index)
+			continue
+		}
+		if n == 0 {
+			report.Mismatched = append(report.Mismatched, index) // Attested chunk missing from the data
+			continue
+		}
+
+		computedHash, err := t.hashChunk(buffer[:n])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(computedHash, t.attestations[index*t.digestSize():(index+1)*t.digestSize()]) {
+			report.Mismatched = append(report.Mismatched, index)
+		}
+	}
+
+	// The data must end with the last attested chunk
+	n, err := r.ReadAt(make([]byte, 1), t.chunkOffset(count))
+	switch {
+	case n > 0:
+		report.Mismatched = append(report.Mismatched, count)
+	case err != nil && err != io.EOF:
+		report.Unreadable = append(report.Unreadable, count)
+	}
+	return report, nil
+}
+
+// VerifyBufferURIs verifies the entire data stream from the reader against the attestations and returns the
+// gitoid URI of each verified chunk, recording exactly which content-addressed chunks the data is composed of
+// On a mismatch it returns false along with the URIs of the chunks verified before it, and data ending before the
+// last attested chunk is reported with a *TruncatedDataError
+func (t *Terrapin) VerifyBufferURIs(reader io.Reader) (bool, []string, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, nil, errors.New("terrapin not finalized")
+	}
+	if t.variable {
+		return false, nil, errVariableChunks
+	}
+	if !t.hasChunkURIs() {
+		return false, nil, errors.New("raw or truncated chunk hashes have no gitoid URIs")
+	}
+
+	// Buffer to read data in chunks, deframed and throttled by any read rate limit
+	reader = t.limitReader(context.Background(), t.deframe(reader))
+	buffer := make([]byte, t.blockSize)
+	count := len(t.attestations) / t.digestSize()
+	var uris []string
+
+	for index := 0; ; index++ {
+		n, err := io.ReadFull(reader, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return false, nil, err
+		}
+		if n == 0 {
+			if index < count {
+				return false, uris, &TruncatedDataError{Chunk: index} // The data must cover every attested chunk
+			}
+			return true, uris, nil
+		}
+		if index >= count {
+			return false, uris, nil // More data than attested
+		}
+		if err := t.checkShortChunk(index, n); err != nil {
+			return false, uris, err
+		}
+
+		computedHash, err := t.hashChunk(buffer[:n])
+		if err != nil {
+			return false, nil, err
+		}
+		expectedHash := t.attestations[index*t.digestSize() : (index+1)*t.digestSize()]
+
+		// Compare the computed hash with the expected hash
+		if !bytes.Equal(computedHash, expectedHash) {
+			return false, uris, nil // Hash mismatch
+		}
+		uris = append(uris, gitoidURI(t.chunkType, t.algorithm, computedHash))
+
+		if n < t.blockSize {
+			return true, uris, nil // Only the last chunk may be short
+		}
+	}
+}
+
+// VerifyBufferN verifies the data from the reader against the attestations, reading no more than the first
+// maxChunks chunks. When maxChunks is less than the number of attested chunks, only those leading chunks are
+// verified and the data must provide all of them; otherwise the data is verified as by VerifyBuffer, including
+// the report of trailing data, reading at most one byte past the attested chunks to detect it
+// Either way the amount read is bounded, so a reader that never returns EOF cannot cause a hang
+// Returns true if verification succeeds, false otherwise
+func (t *Terrapin) VerifyBufferN(reader io.Reader, maxChunks int) (bool, error) {
+	if maxChunks < 0 {
+		return false, errors.New("maxChunks must not be negative")
+	}
+	count := len(t.attestations) / t.digestSize()
+	if maxChunks >= count {
+		return verifyResult(t.verifyBuffer(context.Background(), io.LimitReader(t.deframe(reader), t.chunkOffset(count)+1), nil))
+	}
+
+	// Data ending exactly after the leading chunks is only truncated with respect to the whole attestations
+	mismatch, err := t.verifyBuffer(context.Background(), io.LimitReader(t.deframe(reader), t.chunkOffset(maxChunks)), nil)
+	var truncated *TruncatedDataError
+	if errors.As(err, &truncated) && truncated.Chunk == maxChunks && truncated.Length == 0 {
+		return true, nil
+	}
+	return verifyResult(mismatch, err)
+}
+
+// VerifyPrefix verifies a stream that may hold only the beginning of the attested data, such as a file still
+// being downloaded, returning the number of leading chunks that matched and whether the stream is consistent
+// with the attestations so far. Every complete chunk the stream provides is verified, and a short final read
+// counts as matched if it is the whole final attested chunk, which may itself be short, and is otherwise
+// treated as incomplete rather than failed
+// Verification stops at the first mismatch, and data beyond the attested chunks is inconsistent
+func (t *Terrapin) VerifyPrefix(reader io.Reader) (int, bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return 0, false, errors.New("terrapin not finalized")
+	}
+
+	// Buffer to read data in chunks, deframed and throttled by any read rate limit
+	reader = t.limitReader(context.Background(), t.deframe(reader))
+	buffer := make([]byte, t.blockSize)
+	count := len(t.attestations) / t.digestSize()
+	for index := 0; ; index++ {
+		length := t.blockSize
+		if index < count {
+			length = t.chunkLength(index)
+		}
+		n, err := io.ReadFull(reader, buffer[:length])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return index, false, err
+		}
+		if n == 0 {
+			return index, true, nil // The stream ends on a chunk boundary
+		}
+		if index >= count {
+			return index, false, nil // More data than attested
+		}
+
+		computedHash, err := t.hashChunk(buffer[:n])
+		if err != nil {
+			return index, false, err
+		}
+		if bytes.Equal(computedHash, t.attestations[index*t.digestSize():(index+1)*t.digestSize()]) {
+			continue
+		}
+		if n < length {
+			return index, true, nil // Incomplete chunk, which cannot be verified yet
+		}
+		return index, false, nil // Hash mismatch
+	}
+}
+
+// VerifyReaderAt verifies a single chunk, read from r at the chunk's offset, against its attestation
+// Only the requested chunk is read, and since io.ReaderAt permits concurrent calls, several goroutines
+// may verify different chunks of the same source at once. As with VerifyBuffer, only the last attested chunk
+// may be short: data ending partway through an earlier chunk is reported with a *TruncatedDataError
+// Returns true if verification succeeds, false otherwise
+func (t *Terrapin) VerifyReaderAt(r io.ReaderAt, chunkIndex int) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+
+	// Ensure the chunk is attested
+	if chunkIndex < 0 || chunkIndex >= len(t.attestations)/t.digestSize() {
+		return false, errors.New("chunk index out of range")
+	}
+
+	return t.verifyChunkAt(t.limitReaderAt(context.Background(), r), chunkIndex)
+}
+
+// verifyChunkAt reads the attested chunk at chunkIndex from r and verifies it
+func (t *Terrapin) verifyChunkAt(r io.ReaderAt, chunkIndex int) (bool, error) {
+	// Read the chunk; the final chunk may be short, in which case ReadAt reports io.EOF
+	buffer := make([]byte, t.chunkLength(chunkIndex))
+	n, err := r.ReadAt(buffer, t.chunkOffset(chunkIndex))
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil // Attested chunk missing from the data
+	}
+	if err := t.checkShortChunk(chunkIndex, n); err != nil {
+		return false, err
+	}
+
+	computedHash, err := t.hashChunk(buffer[:n])
+	if err != nil {
+		return false, err
+	}
+	expectedHash := t.attestations[chunkIndex*t.digestSize() : (chunkIndex+1)*t.digestSize()]
+
+	// Compare the computed hash with the expected hash
+	return bytes.Equal(computedHash, expectedHash), nil
+}
+
+// ByteRange is a range of bytes of the attested data
+type ByteRange struct {
+	Offset int64 // Offset of the first byte
+	Length int64 // Number of bytes, which must be positive
+}
+
+// VerifyRanges verifies every chunk covering any of the ranges, reading the chunks from r
+// Each chunk is read and hashed at most once per call, however many ranges overlap it, so clustered ranges
+// cost no more I/O than the chunks they cover. Ranges must lie within the attested data
+// Returns true if verification succeeds, false otherwise
+func (t *Terrapin) VerifyRanges(r io.ReaderAt, ranges []ByteRange) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+	if t.variable {
+		return false, errVariableChunks
+	}
+
+	// Validate every range before reading anything; ranges may end within the short final chunk, but not
+	// beyond the data when its length is known
+	limit := t.VerifiablePrefix()
+	if t.size > 0 {
+		limit = min(limit, t.size)
+	}
+	for _, rng := range ranges {
+		if rng.Offset < 0 || rng.Length <= 0 {
+			return false, errors.New("invalid range")
+		}
+		if rng.Length > limit-rng.Offset {
+			return false, fmt.Errorf("range of %d bytes at offset %d lies beyond the attested data", rng.Length, rng.Offset)
+		}
+	}
+
+	// Cache the outcome of each chunk so overlapping ranges do not read it again
+	r = t.limitReaderAt(context.Background(), r)
+	verified := map[int]bool{}
+	for _, rng := range ranges {
+		first := int(rng.Offset / int64(t.blockSize))
+		last := int((rng.Offset + rng.Length - 1) / int64(t.blockSize))
+		for index := first; index <= last; index++ {
+			if verified[index] {
+				continue
+			}
+			valid, err := t.verifyChunkAt(r, index)
+			if err != nil {
+				return false, err
+			}
+			if !valid {
+				return false, nil // Hash mismatch
+			}
+			verified[index] = true
+		}
+	}
+	return true, nil // All hashes match
+}
+
+// VerifyChunk verifies data, already in memory, against the attestation of the chunk at chunkIndex
+// Returns true if verification succeeds, false otherwise
+func (t *Terrapin) VerifyChunk(chunkIndex int, data []byte) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+
+	// Ensure the chunk is attested
+	if chunkIndex < 0 || chunkIndex >= len(t.attestations)/t.digestSize() {
+		return false, errors.New("chunk index out of range")
+	}
+	if len(data) == 0 || len(data) > t.chunkLength(chunkIndex) {
+		return false, nil
+	}
+
+	computedHash, err := t.hashChunk(data)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(computedHash, t.attestations[chunkIndex*t.digestSize():(chunkIndex+1)*t.digestSize()]), nil
+}
+
+// VerifyGitoid attests the data stream from the reader afresh with the instance's settings and reports whether
+// it reproduces the instance's root gitoid, without comparing individual chunk hashes
+// Only the root is compared, so this suits attestations whose root gitoid is trusted, such as one checked with
+// VerifyAttestationsRoot, and tells nothing about which chunk differs when it fails. Variable-size chunks
+// cannot be attested again, as their boundaries are not known from the data alone
+func (t *Terrapin) VerifyGitoid(reader io.Reader) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+	if t.variable {
+		return false, errVariableChunks
+	}
+
+	// Attest with the same settings, which determine the root gitoid
+	fresh := newDefaultTerrapin()
+	fresh.blockSize, fresh.chunkType, fresh.rootType = t.blockSize, t.chunkType, t.rootType
+	fresh.algorithm, fresh.rawChunks, fresh.digestLen = t.algorithm, t.rawChunks, t.digestLen
+	fresh.merkle, fresh.limiter = t.merkle, t.limiter
+	rootURI, _, err := fresh.attestPipelined(t.deframe(reader))
+	if err != nil {
+		return false, err
+	}
+	return rootURI == t.rootURI, nil
+}
+
+// VerifyCompressed verifies the gzip-compressed data stream from the reader against attestations of the
+// decompressed data, as VerifyBuffer verifies uncompressed data; any deframer strips framing before decompression
+// Errors from decompressing, including corrupt or truncated compressed data, are returned as a
+// *DecompressionError, while data that decompresses but does not match its attestations returns false
+func (t *Terrapin) VerifyCompressed(reader io.Reader) (bool, error) {
+	// Ensure the Terrapin instance is finalized
+	if !t.finalized {
+		return false, errors.New("terrapin not finalized")
+	}
+	decompressor, err := gzip.NewReader(t.deframe(reader))
+	if err != nil {
+		return false, &DecompressionError{Err: err}
+	}
+	defer decompressor.Close()
+	return verifyResult(t.verifyBuffer(context.Background(), &decompressingReader{reader: decompressor}, nil))
+}
+
+// decompressingReader reports the errors of a decompressor as DecompressionErrors
+type decompressingReader struct {
+	reader io.Reader // Decompressor reading the compressed data
+}
+
+// Read reads decompressed data into p, wrapping any error other than io.EOF
+func (r *decompressingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = &DecompressionError{Err: err}
+	}
+	return n, err
+}
+
+// DecompressionError is an error type for compressed data that could not be decompressed
+type DecompressionError struct {
+	Err error // Error returned by the decompressor
+}
+
+// Error implements the error interface for DecompressionError
+func (e *DecompressionError) Error() string {
+	return fmt.Sprintf("failed to decompress data: %v", e.Err)
+}
+
+// Unwrap returns the error returned by the decompressor
+func (e *DecompressionError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyFileGitoid streams the data from the reader, computes its whole-file gitoid, and compares it to expectedURI
+// This is independent of any chunk attestations and serves holders of a classic single gitoid
+// The object type and hash algorithm (sha1 or sha256) are taken from expectedURI
+// When the reader's length cannot be determined up front (it is not a bytes.Reader, strings.Reader,
+// or seekable file) the data is buffered in memory, as required by the gitoid header
+// Returns true if the gitoids match, false otherwise
+func VerifyFileGitoid(reader io.Reader, expectedURI string) (bool, error) {
+	expected, err := gitoid.FromURI(expectedURI)
+	if err != nil {
+		return false, err
+	}
+
+	// FromURI validated the shape, so the type and hash name can be taken from the URI directly
+	parts := strings.Split(expectedURI, ":")
+	opts := []gitoid.Option{gitoid.WithGitObjectType(gitoid.GitObjectType(parts[1]))}
+	switch parts[2] {
+	case "sha1":
+	case "sha256":
+		opts = append(opts, gitoid.WithSha256())
+	default:
+		return false, fmt.Errorf("unsupported gitoid hash algorithm %q", parts[2])
+	}
+
+	// Stream the data when its length is known, which the gitoid header requires
+	if length, ok := readerLength(reader); ok {
+		opts = append(opts, gitoid.WithContentLength(length))
+	}
+
+	computed, err := gitoid.New(reader, opts...)
+	if err != nil {
+		return false, err
+	}
+	return computed.Equal(expected), nil
+}
+
+// readerLength returns the number of bytes remaining in reader, if it can be determined without consuming it
+func readerLength(reader io.Reader) (int64, bool) {
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case io.Seeker:
+		current, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		if _, err := r.Seek(current, io.SeekStart); err != nil {
+			return 0, false
+		}
+		return end - current, true
+	}
+	return 0, false
+}
+
+// MatchChunk hashes data and returns the index of the first candidate attestation set holding a matching chunk
+// hash, or -1 and false if none does, allowing a chunk to be routed to the content it belongs to
+// Each candidate is parsed like NewTerrapinWithAttestations, so its header determines how data is hashed;
+// candidates that cannot be parsed never match
+func MatchChunk(data []byte, candidates [][]byte) (int, bool) {
+	for index, candidate := range candidates {
+		t := newDefaultTerrapin()
+		body, err := t.parseHeader(candidate)
+		if err != nil || len(body)%t.digestSize() != 0 {
+			continue
+		}
+		if !t.variable && len(data) > t.blockSize {
+			continue // Too large to be a chunk of this set
+		}
+
+		computedHash, err := t.hashChunk(data)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < len(body); i += t.digestSize() {
+			if bytes.Equal(computedHash, body[i:i+t.digestSize()]) {
+				return index, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// SameContent reports whether two attestation blobs attest byte-identical data, letting two parties confirm
+// their files are identical without exchanging the data itself
+// Both blobs must use the same block size, chunk type and hash algorithm, otherwise an error is returned;
+// the root type and Merkle mode do not affect the chunk hashes and may differ
+func SameContent(a, b []byte) (bool, error) {
+	first, err := NewTerrapinWithAttestations(a, WithEmptyAttestations())
+	if err != nil {
+		return false, err
+	}
+	second, err := NewTerrapinWithAttestations(b, WithEmptyAttestations())
+	if err != nil {
+		return false, err
+	}
+
+	if err := checkComparable(first, second); err != nil {
+		return false, err
+	}
+	if first.variable && !slices.Equal(first.chunkLengths, second.chunkLengths) {
+		return false, nil
+	}
+	return bytes.Equal(first.attestations, second.attestations), nil
+}
+
+// Diff returns the indices of the chunks whose hashes differ between two finalized instances, in ascending
+// order, so only the changed chunks need to be fetched again. When one instance attests more chunks than the
+// other, every chunk beyond the shorter one is reported as changed; variable-size chunks of different lengths
+// are reported as changed too
+// As with SameContent, both must use the same block size, chunk type and hash algorithm
+func Diff(a, b *Terrapin) ([]int, error) {
+	if !a.finalized || !b.finalized {
+		return nil, errors.New("terrapin not finalized")
+	}
+	if err := checkComparable(a, b); err != nil {
+		return nil, err
+	}
+
+	var changed []int
+	size := a.digestSize()
+	for index := 0; index < max(a.NumChunks(), b.NumChunks()); index++ {
+		if index >= a.NumChunks() || index >= b.NumChunks() ||
+			!bytes.Equal(a.attestations[index*size:(index+1)*size], b.attestations[index*size:(index+1)*size]) ||
+			(a.variable && a.chunkLengths[index] != b.chunkLengths[index]) {
+			changed = append(changed, index)
+		}
+	}
+	return changed, nil
+}
+
+// checkComparable returns an error unless the chunk hashes of a and b are computed the same way
+func checkComparable(a, b *Terrapin) error {
+	if a.blockSize != b.blockSize || a.chunkType != b.chunkType || a.algorithm != b.algorithm ||
+		a.variable != b.variable || a.rawChunks != b.rawChunks || a.digestSize() != b.digestSize() {
+		return errors.New("attestations use incompatible chunking or hashing settings")
+	}
+	return nil
+}
+
+// VerifyAttestationsRoot reports whether the attestations blob hashes to expectedRootURI, the root gitoid
+// Finalize returned when they were produced, so a blob from an untrusted source can be checked against a
+// trusted root, such as one from a signed manifest, before it is used. Metadata such as the epoch is not
+// covered by the root
+func VerifyAttestationsRoot(attestations []byte, expectedRootURI string) (bool, error) {
+	t, err := NewTerrapinWithAttestations(attestations, WithEmptyAttestations())
+	if err != nil {
+		return false, err
+	}
+	rootURI, _, err := t.Finalize()
+	if err != nil {
+		return false, err
+	}
+	return rootURI == expectedRootURI, nil
+}
+
+// verifyingReader passes data through from a reader once each chunk of it has been verified
+type verifyingReader struct {
+	t       *Terrapin // Finalized instance holding the attestations
+	reader  io.Reader // Deframed and throttled source of the data
+	chunk   []byte    // Storage for the chunk being read
+	pending []byte    // Verified data of the current chunk not yet returned
+	index   int       // Index of the next chunk to read
+	offset  int64     // Offset of the next chunk in the data
+	err     error     // Error returned once the pending data is exhausted
+}
+
+// VerifyingReader returns a reader that passes through the data stream from r while verifying it against the
+// attestations, so data can be verified and consumed in a single pass, such as when piping a download
+// Each chunk is read and verified before any of its bytes are returned, so only verified data is ever passed
+// through. Once a chunk fails, Read returns the error VerifyBufferStrict would, a *ChunkMismatchError or
+// *TruncatedDataError, after the data of every chunk before it. As with VerifyBuffer, data ending on a chunk
+// boundary before the last chunk is truncated
+func (t *Terrapin) VerifyingReader(r io.Reader) io.Reader {
+	v := &verifyingReader{t: t}
+	if !t.finalized {
+		v.err = errors.New("terrapin not finalized")
+		return v
+	}
+	v.reader = t.limitReader(context.Background(), t.deframe(r))
+	return v
+}
+
+// Read implements io.Reader, verifying the next chunk whenever the verified data is exhausted
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	for len(v.pending) == 0 {
+		if v.err != nil {
+			return 0, v.err
+		}
+		v.err = v.nextChunk()
+	}
+	n := copy(p, v.pending)
+	v.pending = v.pending[n:]
+	return n, nil
+}
+
+// nextChunk reads and verifies the next chunk, making its data pending if it matches
+func (v *verifyingReader) nextChunk() error {
+	t := v.t
+
+	// The data must end with the attested chunks
+	if v.index >= t.NumChunks() {
+		n, err := v.reader.Read(make([]byte, 1))
+		if n > 0 {
+			return &ChunkMismatchError{Index: v.index, Offset: v.offset} // More data than attested
+		}
+		if err == nil {
+			return nil // Nothing read yet, try again
+		}
+		return err
+	}
+
+	length := t.chunkLength(v.index)
+	if cap(v.chunk) < length {
+		v.chunk = make([]byte, length)
+	}
+	n, err := io.ReadFull(v.reader, v.chunk[:length])
+	if err == io.EOF {
+		return &TruncatedDataError{Chunk: v.index} // Data ending on a chunk boundary before the last chunk
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if err := t.checkShortChunk(v.index, n); err != nil {
+		return err
+	}
+
+	computedHash, err := t.hashChunk(v.chunk[:n])
+	if err != nil {
+		return err
+	}
+	expectedHash := t.attestations[v.index*t.digestSize() : (v.index+1)*t.digestSize()]
+	if !bytes.Equal(computedHash, expectedHash) {
+		return t.chunkMismatch(v.index, v.offset, computedHash)
+	}
+	v.pending = v.chunk[:n]
+	v.index++
+	v.offset += int64(n)
+	if t.progress != nil {
+		t.progress(v.offset)
+	}
+	return nil
+}